@@ -6,6 +6,9 @@
 package abi
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"math/big"
 	"strings"
 
@@ -23,34 +26,72 @@ type Log struct {
 // Matches event data in a log. Sets decoded data on e.
 // Use [Input]'s Item field to read decoded data.
 //
+// Indexed inputs with dynamic types (string, bytes, arrays
+// and tuples) are logged as the keccak hash of their value.
+// Use [Item.TopicHash] to read them.
+//
 // A false return value indicates the first log topic doesn't match
-// the event's [Event.SignatureHash].
+// the event's [Event.SignatureHash] or that l can't be decoded.
+// See [DecodeLog] for the reason.
 func Match(l Log, e Event) (Item, bool) {
+	it, err := DecodeLog(l, e)
+	return it, err == nil
+}
+
+// Like [Match] but decodes into dst, which is reused when
+// it has capacity for e's inputs. The returned Item references
+// dst and l. When the unindexed inputs of e all have static,
+// value types (eg ERC20 Transfer) no memory is allocated.
+//
+// The resolved types are cached on e so e must not be
+// modified or used concurrently with MatchInto.
+func MatchInto(l *Log, e *Event, dst []Item) (Item, bool) {
 	if e.SignatureHash() != l.Topics[0] {
 		return Item{}, false
 	}
-	var (
-		items     = make([]Item, len(e.Inputs))
-		unindexed []abit.Type
-	)
-	for i, inp := range e.Inputs {
-		if !inp.Indexed {
-			unindexed = append(unindexed, inp.ABIType())
-			continue
+	if e.types == nil {
+		e.types = make([]abit.Type, len(e.Inputs))
+		e.static = true
+		for i := range e.Inputs {
+			e.types[i] = e.Inputs[i].ABIType()
+			if !e.Inputs[i].Indexed && e.types[i].Kind != abit.S {
+				e.static = false
+			}
 		}
-		items[i] = Bytes(l.Topics[i+1][:])
+		e.tuple = abit.Tuple(e.types...)
 	}
-	item := Decode(l.Data, abit.Tuple(unindexed...))
-	for i, j := 0, 0; i < len(e.Inputs); i++ {
-		if e.Inputs[i].Indexed {
-			continue
+	if !e.static {
+		return Match(*l, *e)
+	}
+	if cap(dst) < len(e.Inputs) {
+		dst = make([]Item, len(e.Inputs))
+	}
+	dst = dst[:len(e.Inputs)]
+	var topic, head = 1, 0
+	for i := range e.Inputs {
+		t := e.types[i]
+		switch {
+		case e.Inputs[i].Indexed:
+			if topic >= len(l.Topics) {
+				return Item{}, false
+			}
+			dst[i] = Item{Type: t, d: l.Topics[topic][:], hashed: t.Kind != abit.S}
+			topic++
+		default:
+			if head+32 > len(l.Data) {
+				return Item{}, false
+			}
+			dst[i] = Item{Type: t, d: l.Data[head : head+32]}
+			head += 32
 		}
-		items[i] = item.At(j)
-		j++
 	}
-	return Tuple(items...), true
+	return Item{Type: e.tuple, l: dst}, true
 }
 
+// The keccak hash of an indexed input with a dynamic type.
+// The input's value can't be recovered from a log.
+type TopicHash [32]byte
+
 // Optionally contains a decoded Item. See: [Match].
 type Input struct {
 	Item *Item
@@ -79,6 +120,11 @@ type Event struct {
 	sig     string
 	sigHash [32]byte
 
+	// cached by MatchInto
+	types  []abit.Type
+	tuple  abit.Type
+	static bool // unindexed inputs are all abit.S
+
 	Name      string
 	Type      string //event
 	Anonymous bool
@@ -90,18 +136,22 @@ func (e *Event) Signature() string {
 	if e.sig != "" {
 		return e.sig
 	}
+	e.sig = signature(e.Name, e.Inputs)
+	return e.sig
+}
+
+func signature(name string, inputs []Input) string {
 	var s strings.Builder
-	s.WriteString(e.Name)
+	s.WriteString(name)
 	s.WriteString("(")
-	for i := range e.Inputs {
-		s.WriteString(e.Inputs[i].ABIType().Signature())
-		if i+1 < len(e.Inputs) {
+	for i := range inputs {
+		s.WriteString(inputs[i].ABIType().Signature())
+		if i+1 < len(inputs) {
 			s.WriteString(",")
 		}
 	}
 	s.WriteString(")")
-	e.sig = s.String()
-	return e.sig
+	return s.String()
 }
 
 // Computes keccak hash over [Event.Signature]. Caches result on e
@@ -113,19 +163,177 @@ func (e *Event) SignatureHash() [32]byte {
 	return e.sigHash
 }
 
+// The first topic of e's logs: [Event.SignatureHash].
+// Anonymous events don't log their signature and
+// the zero value is returned.
+func (e *Event) Topic0() [32]byte {
+	if e.Anonymous {
+		return [32]byte{}
+	}
+	return e.SignatureHash()
+}
+
+type Method struct {
+	sig string
+
+	Name            string
+	Type            string //function
+	StateMutability string
+	Inputs          []Input
+	Outputs         []Input
+}
+
+// Computes signature (eg name(type1,type2)). Caches result on m
+func (m *Method) Signature() string {
+	if m.sig != "" {
+		return m.sig
+	}
+	m.sig = signature(m.Name, m.Inputs)
+	return m.sig
+}
+
+// First 4 bytes of the keccak hash of [Method.Signature]
+func (m *Method) Selector() [4]byte {
+	var s [4]byte
+	copy(s[:], isxhash.Keccak([]byte(m.Signature())))
+	return s
+}
+
+// Returns the calldata for calling m with args:
+// the selector followed by the ABI encoded args.
+// Returns an error if args don't match m's inputs.
+func (m *Method) EncodeCall(args ...Item) ([]byte, error) {
+	if err := checkArgs(m.Signature(), m.Inputs, args); err != nil {
+		return nil, err
+	}
+	sel := m.Selector()
+	if len(args) == 0 {
+		return sel[:], nil
+	}
+	return append(sel[:], Encode(Tuple(args...))...), nil
+}
+
+// Decodes the args from calldata, which must begin
+// with m's selector. The inverse of [Method.EncodeCall].
+func (m *Method) DecodeArgs(calldata []byte) (Item, error) {
+	sel := m.Selector()
+	if !bytes.HasPrefix(calldata, sel[:]) {
+		return Item{}, fmt.Errorf("calldata does not begin with selector for %s", m.Signature())
+	}
+	return decodeInputs(calldata[4:], m.Inputs)
+}
+
+// Decodes the data returned by calling m (eg the result
+// of eth_call) into a tuple of m's outputs.
+// Returns an error if data can't be decoded according to m's outputs.
+// Empty data, which is returned when calling an address
+// without code, is an error unless m has no outputs.
+func (m *Method) DecodeReturn(data []byte) (Item, error) {
+	if len(m.Outputs) == 0 {
+		if len(data) != 0 {
+			return Item{}, fmt.Errorf("%s expected no return data. got %d bytes", m.Signature(), len(data))
+		}
+		return Tuple(), nil
+	}
+	if len(data) < 32*len(m.Outputs) || len(data)%32 != 0 {
+		return Item{}, fmt.Errorf("%s invalid return data length: %d", m.Signature(), len(data))
+	}
+	return decodeInputs(data, m.Outputs)
+}
+
+func checkArgs(name string, inputs []Input, args []Item) error {
+	if len(args) != len(inputs) {
+		return fmt.Errorf("%s requires %d args. got: %d", name, len(inputs), len(args))
+	}
+	for i := range args {
+		want := inputs[i].ABIType().Signature()
+		if got := args[i].Type.Signature(); got != want {
+			return fmt.Errorf("arg %d must be %s. got: %s", i, want, got)
+		}
+	}
+	return nil
+}
+
+type Constructor struct {
+	Type            string //constructor
+	StateMutability string
+	Inputs          []Input
+}
+
+// Computes signature (eg constructor(type1,type2))
+func (c *Constructor) Signature() string {
+	return signature("constructor", c.Inputs)
+}
+
+// Returns the input for a contract creation transaction:
+// the contract's creation code followed by the ABI encoded
+// args. Returns an error if args don't match c's inputs.
+// The inverse of [Constructor.DecodeArgs].
+func (c *Constructor) EncodeDeploy(code []byte, args ...Item) ([]byte, error) {
+	if err := checkArgs(c.Signature(), c.Inputs, args); err != nil {
+		return nil, err
+	}
+	res := make([]byte, len(code), len(code)+32*len(args))
+	copy(res, code)
+	if len(args) == 0 {
+		return res, nil
+	}
+	return append(res, Encode(Tuple(args...))...), nil
+}
+
+// Decodes the constructor arguments from the input of a
+// contract creation transaction. The input is the contract's
+// creation code followed by the ABI encoded arguments.
+func (c *Constructor) DecodeArgs(input, code []byte) (Item, error) {
+	if !bytes.HasPrefix(input, code) {
+		return Item{}, errors.New("input does not begin with creation code")
+	}
+	args := input[len(code):]
+	if len(c.Inputs) == 0 {
+		if len(args) != 0 {
+			return Item{}, fmt.Errorf("expected no args. got %d bytes", len(args))
+		}
+		return Tuple(), nil
+	}
+	if len(args) < 32*len(c.Inputs) || len(args)%32 != 0 {
+		return Item{}, fmt.Errorf("invalid args length: %d", len(args))
+	}
+	return decodeInputs(args, c.Inputs)
+}
+
 type Item struct {
 	abit.Type
 
 	// must be d XOR l
 	d []byte
 	l []Item
+
+	// d is the topic hash of an indexed dynamic value
+	hashed bool
+}
+
+// Returns false unless it is an indexed input with
+// a dynamic type. See [Match].
+func (it Item) TopicHash() (TopicHash, bool) {
+	if !it.hashed || len(it.d) != 32 {
+		return TopicHash{}, false
+	}
+	return *(*TopicHash)(it.d), true
 }
 
 func Bytes(d []byte) Item {
 	return Item{Type: abit.Bytes, d: d}
 }
 
+// Returns nil for topic hashes. See [Item.TopicHash].
+// For bytesN items the padding is removed.
 func (it Item) Bytes() []byte {
+	switch {
+	case it.hashed:
+		return nil
+	case it.Kind == abit.S && it.Length > 0 && it.Length <= len(it.d):
+		return it.d[:it.Length]
+	}
 	return it.d
 }
 
@@ -137,6 +345,12 @@ func (it Item) BytesSlice() [][]byte {
 	return res
 }
 
+func Address(a [20]byte) Item {
+	var d [32]byte
+	copy(d[12:], a[:])
+	return Item{Type: abit.Address, d: d[:]}
+}
+
 func (it Item) Address() [20]byte {
 	if len(it.d) < 32 {
 		return [20]byte{}
@@ -152,11 +366,28 @@ func (it Item) AddressSlice() [][20]byte {
 	return res
 }
 
+func Bytes32(b [32]byte) Item {
+	return Item{Type: abit.Bytes32, d: b[:]}
+}
+
+// Fixed size byte array (eg bytes4). b must
+// not be empty or longer than 32 bytes.
+func BytesN(b []byte) Item {
+	var d [32]byte
+	copy(d[:], b)
+	return Item{Type: abit.BytesN(len(b)), d: d[:]}
+}
+
 func String(s string) Item {
 	return Item{Type: abit.String, d: []byte(s)}
 }
 
+// Returns an empty string for topic
+// hashes. See [Item.TopicHash].
 func (it Item) String() string {
+	if it.hashed {
+		return ""
+	}
 	return string(it.d)
 }
 
@@ -191,9 +422,12 @@ func (it Item) BoolSlice() []bool {
 	return res
 }
 
+// A nil i is encoded as 0
 func BigInt(i *big.Int) Item {
 	var b [32]byte
-	i.FillBytes(b[:])
+	if i != nil {
+		i.FillBytes(b[:])
+	}
 	return Item{
 		Type: abit.Uint256,
 		d:    b[:],
@@ -214,6 +448,69 @@ func (it Item) BigIntSlice() []*big.Int {
 	return res
 }
 
+var two256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+func scale(decimals int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+}
+
+// Encodes i as t which must be an int type.
+// A nil i is encoded as 0
+func Int(t abit.Type, i *big.Int) Item {
+	var b [32]byte
+	if i != nil {
+		x := i
+		if x.Sign() < 0 {
+			x = new(big.Int).Add(x, two256)
+		}
+		x.FillBytes(b[:])
+	}
+	return Item{Type: t, d: b[:]}
+}
+
+// Decodes an int item using two's complement
+func (it Item) Int() *big.Int {
+	x := new(big.Int).SetBytes(it.d)
+	if len(it.d) == 32 && it.d[0]&0x80 != 0 {
+		x.Sub(x, two256)
+	}
+	return x
+}
+
+func (it Item) IntSlice() []*big.Int {
+	var res []*big.Int
+	for i := range it.l {
+		res = append(res, it.l[i].Int())
+	}
+	return res
+}
+
+// Encodes v as t which must be a fixed or ufixed type.
+// Digits beyond t's decimals are truncated towards zero.
+// A nil v is encoded as 0
+func Fixed(t abit.Type, v *big.Rat) Item {
+	var b [32]byte
+	if v != nil {
+		x := new(big.Int).Mul(v.Num(), scale(t.Decimals))
+		x.Quo(x, v.Denom())
+		if x.Sign() < 0 {
+			x.Add(x, two256)
+		}
+		x.FillBytes(b[:])
+	}
+	return Item{Type: t, d: b[:]}
+}
+
+// Decodes a fixed or ufixed item. Signed values
+// are decoded using two's complement.
+func (it Item) Rat() *big.Rat {
+	x := new(big.Int).SetBytes(it.d)
+	if it.Signed && len(it.d) == 32 && it.d[0]&0x80 != 0 {
+		x.Sub(x, two256)
+	}
+	return new(big.Rat).SetFrac(x, scale(it.Decimals))
+}
+
 func Uint64(i uint64) Item {
 	var b [32]byte
 	bint.Encode(b[:], i)
@@ -257,6 +554,24 @@ func List(items ...Item) Item {
 	}
 }
 
+// Like [List] but with an explicit element
+// type so that items may be empty.
+func ListOf(et abit.Type, items ...Item) Item {
+	return Item{
+		Type: abit.List(et),
+		l:    items,
+	}
+}
+
+// Fixed length array. items must not be empty
+// and must have the same type.
+func Array(items ...Item) Item {
+	return Item{
+		Type: abit.Array(items[0].Type, len(items)),
+		l:    items,
+	}
+}
+
 func (it Item) At(i int) Item {
 	if len(it.l) <= i {
 		return Item{}
@@ -304,29 +619,43 @@ func Encode(it Item) []byte {
 	case abit.L:
 		var c [32]byte
 		bint.Encode(c[:], uint64(len(it.l)))
-		return append(c[:], Encode(Tuple(it.l...))...)
-	case abit.T:
-		var head, tail []byte
-		for i := range it.l {
-			switch it.l[i].Kind {
-			case abit.S:
-				head = append(head, Encode(it.l[i])...)
-			default:
-				var offset [32]byte
-				bint.Encode(offset[:], uint64(len(it.l)*32+len(tail)))
-				head = append(head, offset[:]...)
-				tail = append(tail, Encode(it.l[i])...)
-			}
-		}
-		return append(head, tail...)
+		return append(c[:], encode(it.l)...)
+	case abit.A, abit.T:
+		return encode(it.l)
 	default:
 		panic("abi: encode: unkown type")
 	}
 }
 
+// Encodes items as the elements of a tuple. Static items
+// are encoded in the head and dynamic items are appended
+// to the tail and referenced by their offset from the
+// start of the head.
+func encode(items []Item) []byte {
+	var n int
+	for i := range items {
+		n += items[i].HeadSize()
+	}
+	var head, tail []byte
+	for i := range items {
+		if items[i].Static() {
+			head = append(head, Encode(items[i])...)
+			continue
+		}
+		var offset [32]byte
+		bint.Encode(offset[:], uint64(n+len(tail)))
+		head = append(head, offset[:]...)
+		tail = append(tail, Encode(items[i])...)
+	}
+	return append(head, tail...)
+}
+
 // Decodes ABI encoded bytes into an [Item] according to
 // the 'schema' defined by t. For example:
 //	Decode(b, abit.Tuple(abit.String, abit.Uint256))
+//
+// Panics when input can't be decoded. Use [DecodeChecked]
+// for input that comes from the chain.
 func Decode(input []byte, t abit.Type) Item {
 	switch t.Kind {
 	case abit.S:
@@ -336,32 +665,46 @@ func Decode(input []byte, t abit.Type) Item {
 		return Item{Type: t, d: input[32 : 32+count]}
 	case abit.L:
 		count := bint.Decode(input[:32])
-		items := make([]Item, count)
-		for i := uint64(0); i < count; i++ {
-			n := 32 + (32 * i) //skip count (head)
-			switch t.Elem.Kind {
-			case abit.S:
-				items[i] = Decode(input[n:], *t.Elem)
-			default:
-				offset := 32 + bint.Decode(input[n:n+32])
-				items[i] = Decode(input[offset:], *t.Elem)
-			}
+		// each element requires at least 32 bytes
+		if count > uint64(len(input)/32) {
+			panic("abi: decode: list length exceeds input")
 		}
-		return List(items...)
+		items := decode(input[32:], int(count), func(int) abit.Type {
+			return *t.Elem
+		})
+		return ListOf(*t.Elem, items...)
+	case abit.A:
+		items := decode(input, t.Length, func(int) abit.Type {
+			return *t.Elem
+		})
+		return Item{Type: t, l: items}
 	case abit.T:
-		items := make([]Item, len(t.Fields))
-		for i, f := range t.Fields {
-			n := 32 * i
-			switch f.Kind {
-			case abit.S:
-				items[i] = Decode(input[n:n+32], *f)
-			default:
-				offset := bint.Decode(input[n : n+32])
-				items[i] = Decode(input[offset:], *f)
-			}
-		}
+		items := decode(input, len(t.Fields), func(i int) abit.Type {
+			return *t.Fields[i]
+		})
 		return Tuple(items...)
 	default:
 		panic("abi: encode: unkown type")
 	}
 }
+
+// Decodes n tuple elements where typ(i) is the
+// type of the i'th element. See [encode].
+func decode(input []byte, n int, typ func(int) abit.Type) []Item {
+	var (
+		items = make([]Item, n)
+		head  int
+	)
+	for i := 0; i < n; i++ {
+		t := typ(i)
+		if t.Static() {
+			items[i] = Decode(input[head:], t)
+			head += t.HeadSize()
+			continue
+		}
+		offset := bint.Decode(input[head : head+32])
+		items[i] = Decode(input[offset:], t)
+		head += 32
+	}
+	return items
+}
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/indexsupply/x/abi/abit"
+	"github.com/indexsupply/x/isxhash"
 	"github.com/indexsupply/x/tc"
 )
 
@@ -133,13 +135,78 @@ func TestSolidityVectors(t *testing.T) {
 			),
 			want: `000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000001400000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a0000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000000000000000000000000000000000003000000000000000000000000000000000000000000000000000000000000006000000000000000000000000000000000000000000000000000000000000000a000000000000000000000000000000000000000000000000000000000000000e000000000000000000000000000000000000000000000000000000000000000036f6e650000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000374776f000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000057468726565000000000000000000000000000000000000000000000000000000`,
 		},
+		{
+			desc: "https://docs.soliditylang.org/en/latest/abi-spec.html#use-of-dynamic-types (uint32[] as uint256[])",
+			input: Tuple(
+				BigInt(big.NewInt(0x123)),
+				List(BigInt(big.NewInt(0x456)), BigInt(big.NewInt(0x789))),
+				BytesN([]byte("1234567890")),
+				Bytes([]byte("Hello, world!")),
+			),
+			want: `00000000000000000000000000000000000000000000000000000000000001230000000000000000000000000000000000000000000000000000000000000080313233343536373839300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000e0000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000004560000000000000000000000000000000000000000000000000000000000000789000000000000000000000000000000000000000000000000000000000000000d48656c6c6f2c20776f726c642100000000000000000000000000000000000000`,
+		},
+		{
+			desc: "bytes3[2]",
+			input: Tuple(
+				Array(BytesN([]byte("abc")), BytesN([]byte("def"))),
+			),
+			want: `61626300000000000000000000000000000000000000000000000000000000006465660000000000000000000000000000000000000000000000000000000000`,
+		},
+		{
+			desc: "uint64[2][3] is static",
+			input: Tuple(
+				Array(
+					Array(Uint64(1), Uint64(2)),
+					Array(Uint64(3), Uint64(4)),
+					Array(Uint64(5), Uint64(6)),
+				),
+				Bool(true),
+			),
+			want: `0000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000500000000000000000000000000000000000000000000000000000000000000060000000000000000000000000000000000000000000000000000000000000001`,
+		},
+		{
+			desc: "(uint8,string)[][2]",
+			input: Tuple(
+				Array(
+					List(
+						Tuple(Uint8(1), String("one")),
+						Tuple(Uint8(2), String("two")),
+					),
+					ListOf(abit.Tuple(abit.Uint8, abit.String), []Item{}...),
+				),
+			),
+			want: `0000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000001a00000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000c00000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000036f6e65000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000040000000000000000000000000000000000000000000000000000000000000000374776f00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000`,
+		},
+		{
+			desc: "static tuples",
+			input: Tuple(
+				String("x"),
+				List(
+					Tuple(Uint64(1), Bool(true)),
+					Tuple(Uint64(2), Bool(false)),
+				),
+				Tuple(Uint64(3), Bool(true)),
+			),
+			want: `000000000000000000000000000000000000000000000000000000000000008000000000000000000000000000000000000000000000000000000000000000c0000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000001780000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000`,
+		},
+		{
+			desc: "string[2]",
+			input: Tuple(
+				Array(String("foo"), String("bar")),
+				Uint8(7),
+			),
+			want: `00000000000000000000000000000000000000000000000000000000000000400000000000000000000000000000000000000000000000000000000000000007000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000800000000000000000000000000000000000000000000000000000000000000003666f6f000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000036261720000000000000000000000000000000000000000000000000000000000`,
+		},
 	}
 	for _, c := range cases {
 		want, err := hex.DecodeString(c.want)
 		tc.NoErr(t, err)
 		got := Encode(c.input)
 		if !bytes.Equal(want, got) {
-			t.Errorf("want: %x got: %x", want, got)
+			t.Errorf("%s want: %x got: %x", c.desc, want, got)
+		}
+		if it := Decode(want, c.input.Type); !reflect.DeepEqual(c.input, it) {
+			t.Errorf("decode %s want: %#v got: %#v", c.desc, c.input, it)
 		}
 	}
 }
@@ -211,6 +278,11 @@ func TestDecode(t *testing.T) {
 				abit.List(abit.Uint64),
 			),
 		},
+		{
+			desc: "empty list",
+			want: Tuple(ListOf(abit.Uint64, []Item{}...), Bytes32([32]byte{1})),
+			t:    abit.Tuple(abit.List(abit.Uint64), abit.Bytes32),
+		},
 	}
 	for _, c := range cases {
 		got := Decode(debug(t, Encode(c.want)), c.t)
@@ -229,3 +301,386 @@ func debug(t *testing.T, b []byte) []byte {
 	t.Logf("debug:\n%s\n", out)
 	return b
 }
+
+func TestEncodeCall(t *testing.T) {
+	m, err := ParseMethod("function transfer(address to, uint256 amount)")
+	tc.NoErr(t, err)
+	if s := m.Selector(); hex.EncodeToString(s[:]) != "a9059cbb" {
+		t.Errorf("want: a9059cbb got: %x", s)
+	}
+	got, err := m.EncodeCall(
+		Address([20]byte{19: 0x01}),
+		BigInt(big.NewInt(1000)),
+	)
+	tc.NoErr(t, err)
+	want := "a9059cbb" +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"00000000000000000000000000000000000000000000000000000000000003e8"
+	if hex.EncodeToString(got) != want {
+		t.Errorf("want: %s got: %x", want, got)
+	}
+
+	m, err = ParseMethod("function totalSupply() view returns (uint256)")
+	tc.NoErr(t, err)
+	got, err = m.EncodeCall()
+	tc.NoErr(t, err)
+	if hex.EncodeToString(got) != "18160ddd" {
+		t.Errorf("want: 18160ddd got: %x", got)
+	}
+}
+
+func TestDecodeArgs(t *testing.T) {
+	m, err := ParseMethod("function transfer(address to, uint256 amount)")
+	tc.NoErr(t, err)
+	calldata, err := m.EncodeCall(Address([20]byte{19: 0x01}), BigInt(big.NewInt(1000)))
+	tc.NoErr(t, err)
+	it, err := m.DecodeArgs(calldata)
+	tc.NoErr(t, err)
+	if it.At(0).Address() != [20]byte{19: 0x01} || it.At(1).BigInt().Int64() != 1000 {
+		t.Errorf("unexpected args: %x %s", it.At(0).Address(), it.At(1).BigInt())
+	}
+	if _, err := m.DecodeArgs(calldata[1:]); err == nil {
+		t.Error("expected error for missing selector")
+	}
+	if _, err := m.DecodeArgs(calldata[:36]); err == nil {
+		t.Error("expected error for truncated args")
+	}
+}
+
+func TestEncodeCall_Errors(t *testing.T) {
+	m, err := ParseMethod("function transfer(address to, uint256 amount)")
+	tc.NoErr(t, err)
+	if _, err := m.EncodeCall(Address([20]byte{})); err == nil {
+		t.Error("expected error for missing arg")
+	}
+	if _, err := m.EncodeCall(Address([20]byte{}), Uint64(1)); err == nil {
+		t.Error("expected error for mismatched type")
+	}
+}
+
+func TestDecodeReturn(t *testing.T) {
+	m, err := ParseMethod("function balances() view returns (uint256 total, string name)")
+	tc.NoErr(t, err)
+	want := Tuple(BigInt(big.NewInt(42)), String("hello"))
+	got, err := m.DecodeReturn(Encode(want))
+	tc.NoErr(t, err)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v want: %v", got, want)
+	}
+	if got.At(0).BigInt().Int64() != 42 || got.At(1).String() != "hello" {
+		t.Errorf("unexpected values: %s %s", got.At(0).BigInt(), got.At(1).String())
+	}
+
+	if _, err := m.DecodeReturn(nil); err == nil {
+		t.Error("expected error for empty return data")
+	}
+	if _, err := m.DecodeReturn(Encode(want)[:64]); err == nil {
+		t.Error("expected error for truncated return data")
+	}
+	bad := Encode(want)
+	bad[63] = 0xff
+	if _, err := m.DecodeReturn(bad); err == nil {
+		t.Error("expected error for bad offset")
+	}
+
+	m, err = ParseMethod("function poke()")
+	tc.NoErr(t, err)
+	got, err = m.DecodeReturn(nil)
+	tc.NoErr(t, err)
+	if len(got.l) != 0 {
+		t.Errorf("expected empty tuple. got %d items", len(got.l))
+	}
+	if _, err := m.DecodeReturn(make([]byte, 32)); err == nil {
+		t.Error("expected error for unexpected return data")
+	}
+}
+
+func TestTopic0(t *testing.T) {
+	cases := []struct {
+		desc string
+		sig  string
+		hash string
+	}{
+		{
+			"event Transfer(address indexed from, address indexed to, uint value)",
+			"Transfer(address,address,uint256)",
+			"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+		},
+		{
+			"event Order((address maker, uint256[2] amounts)[] orders, bytes32 id)",
+			"Order((address,uint256[2])[],bytes32)",
+			"",
+		},
+		{
+			"event Anon(uint8 a) anonymous",
+			"Anon(uint8)",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+		},
+	}
+	for _, c := range cases {
+		e, err := ParseEvent(c.desc)
+		tc.NoErr(t, err)
+		if got := e.Signature(); got != c.sig {
+			t.Errorf("signature got: %s want: %s", got, c.sig)
+		}
+		want := c.hash
+		if want == "" {
+			want = hex.EncodeToString(isxhash.Keccak([]byte(c.sig)))
+		}
+		if got := e.Topic0(); hex.EncodeToString(got[:]) != want {
+			t.Errorf("%s topic0 got: %x want: %s", c.sig, got, want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	e, err := ParseEvent("event E(uint64 a, address indexed b, string indexed c, string d)")
+	tc.NoErr(t, err)
+	var (
+		l     Log
+		chash = isxhash.Keccak32([]byte("hello"))
+	)
+	l.Topics[0] = e.SignatureHash()
+	l.Topics[1][31] = 0xbb
+	l.Topics[2] = chash
+	l.Data = Encode(Tuple(Uint64(42), String("world")))
+
+	it, ok := Match(l, e)
+	if !ok {
+		t.Fatal("expected match")
+	}
+	if n := it.At(0).Uint64(); n != 42 {
+		t.Errorf("a want: 42 got: %d", n)
+	}
+	if addr := it.At(1).Address(); addr != [20]byte{19: 0xbb} {
+		t.Errorf("b want: 00..bb got: %x", addr)
+	}
+	if _, ok := it.At(1).TopicHash(); ok {
+		t.Error("b is static and must not be a topic hash")
+	}
+	h, ok := it.At(2).TopicHash()
+	if !ok || h != TopicHash(chash) {
+		t.Errorf("c want: %x got: %x", chash, h)
+	}
+	if it.At(2).String() != "" || it.At(2).Bytes() != nil {
+		t.Error("c must not be readable as data")
+	}
+	if s := it.At(3).String(); s != "world" {
+		t.Errorf("d want: world got: %s", s)
+	}
+
+	l.Data = l.Data[:40]
+	if _, ok := Match(l, e); ok {
+		t.Error("expected no match for truncated data")
+	}
+}
+
+func transferLog(t testing.TB) (Event, Log) {
+	e, err := ParseEvent("event Transfer(address indexed from, address indexed to, uint256 value)")
+	tc.NoErr(t, err)
+	l := Log{
+		Topics: [4][32]byte{e.SignatureHash(), {31: 1}, {31: 2}},
+		Data:   Encode(Tuple(BigInt(big.NewInt(1000)))),
+	}
+	return e, l
+}
+
+func TestMatchInto(t *testing.T) {
+	e, l := transferLog(t)
+	want, ok := Match(l, e)
+	if !ok {
+		t.Fatal("expected match")
+	}
+	dst := make([]Item, 3)
+	got, ok := MatchInto(&l, &e, dst)
+	if !ok {
+		t.Fatal("expected match")
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %#v got: %#v", want, got)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		MatchInto(&l, &e, dst)
+	})
+	if allocs != 0 {
+		t.Errorf("want 0 allocs got: %f", allocs)
+	}
+
+	l.Data = l.Data[:31]
+	if _, ok := MatchInto(&l, &e, dst); ok {
+		t.Error("expected short data to not match")
+	}
+
+	// dynamic inputs use Match
+	e, err := ParseEvent("event E(uint64 a, string indexed b, string c)")
+	tc.NoErr(t, err)
+	l = Log{
+		Topics: [4][32]byte{e.SignatureHash(), {1}},
+		Data:   Encode(Tuple(Uint64(42), String("world"))),
+	}
+	want, _ = Match(l, e)
+	got, ok = MatchInto(&l, &e, nil)
+	if !ok || !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %#v got: %#v", want, got)
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	e, l := transferLog(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Match(l, e)
+	}
+}
+
+func BenchmarkMatchInto(b *testing.B) {
+	e, l := transferLog(b)
+	dst := make([]Item, len(e.Inputs))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		MatchInto(&l, &e, dst)
+	}
+}
+
+func TestFixed(t *testing.T) {
+	cases := []struct {
+		t    abit.Type
+		v    string
+		want string
+		hex  string
+	}{
+		{
+			t:    abit.Fixed(false, 128, 18),
+			v:    "1.5",
+			want: "3/2",
+			hex:  "00000000000000000000000000000000000000000000000014d1120d7b160000",
+		},
+		{
+			t:    abit.Fixed(true, 128, 18),
+			v:    "-1",
+			want: "-1/1",
+			hex:  "fffffffffffffffffffffffffffffffffffffffffffffffff21f494c589c0000",
+		},
+		{
+			t:    abit.Fixed(true, 8, 1),
+			v:    "0.25",
+			want: "1/5",
+			hex:  "0000000000000000000000000000000000000000000000000000000000000002",
+		},
+		{
+			t:    abit.Fixed(true, 8, 1),
+			v:    "-0.25",
+			want: "-1/5",
+			hex:  "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe",
+		},
+	}
+	for _, c := range cases {
+		v, ok := new(big.Rat).SetString(c.v)
+		if !ok {
+			t.Fatalf("bad rat %q", c.v)
+		}
+		it := Fixed(c.t, v)
+		if got := hex.EncodeToString(Encode(it)); got != c.hex {
+			t.Errorf("%s encode got: %s want: %s", c.v, got, c.hex)
+		}
+		got := Decode(Encode(it), c.t).Rat()
+		if got.String() != c.want {
+			t.Errorf("%s decode got: %s want: %s", c.v, got, c.want)
+		}
+	}
+}
+
+func TestInt(t *testing.T) {
+	cases := []struct {
+		v   int64
+		hex string
+	}{
+		{0, "0000000000000000000000000000000000000000000000000000000000000000"},
+		{1, "0000000000000000000000000000000000000000000000000000000000000001"},
+		{-1, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{-887272, "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffff27618"},
+	}
+	for _, c := range cases {
+		it := Int(abit.Int256, big.NewInt(c.v))
+		if got := hex.EncodeToString(Encode(it)); got != c.hex {
+			t.Errorf("%d encode got: %s want: %s", c.v, got, c.hex)
+		}
+		if got := Decode(Encode(it), abit.Int256).Int(); got.Int64() != c.v {
+			t.Errorf("%d decode got: %s", c.v, got)
+		}
+	}
+}
+
+func TestConstructor_EncodeDeploy(t *testing.T) {
+	c, err := ParseConstructor("constructor(string name, uint256 supply)")
+	tc.NoErr(t, err)
+	code := []byte{0x60, 0x80, 0x60, 0x40, 0x52}
+	got, err := c.EncodeDeploy(code, String("foo"), BigInt(big.NewInt(1000)))
+	tc.NoErr(t, err)
+	want := append(code, Encode(Tuple(String("foo"), BigInt(big.NewInt(1000))))...)
+	if !bytes.Equal(got, want) {
+		t.Errorf("want: %x got: %x", want, got)
+	}
+	it, err := c.DecodeArgs(got, code)
+	tc.NoErr(t, err)
+	if it.At(0).String() != "foo" || it.At(1).BigInt().Int64() != 1000 {
+		t.Errorf("round trip got: %s %s", it.At(0).String(), it.At(1).BigInt())
+	}
+	if _, err := c.EncodeDeploy(code, String("foo")); err == nil {
+		t.Error("expected error for missing arg")
+	}
+	if _, err := c.EncodeDeploy(code, String("foo"), Uint64(1)); err == nil {
+		t.Error("expected error for mismatched type")
+	}
+
+	c, err = ParseConstructor("constructor()")
+	tc.NoErr(t, err)
+	got, err = c.EncodeDeploy(code)
+	tc.NoErr(t, err)
+	if !bytes.Equal(got, code) {
+		t.Errorf("want: %x got: %x", code, got)
+	}
+}
+
+func TestConstructor_DecodeArgs(t *testing.T) {
+	c := Constructor{
+		Type: "constructor",
+		Inputs: []Input{
+			{Name: "name", Type: "string"},
+			{Name: "supply", Type: "uint256"},
+		},
+	}
+	code := []byte{0x60, 0x80, 0x60, 0x40, 0x52}
+	args := Encode(Tuple(String("foo"), BigInt(big.NewInt(1000))))
+	it, err := c.DecodeArgs(append(code, args...), code)
+	tc.NoErr(t, err)
+	if got := it.At(0).String(); got != "foo" {
+		t.Errorf("want: foo got: %s", got)
+	}
+	if got := it.At(1).BigInt().Int64(); got != 1000 {
+		t.Errorf("want: 1000 got: %d", got)
+	}
+
+	bad := append([]byte{}, args...)
+	bad[31] = 0xff
+	cases := []struct {
+		desc  string
+		input []byte
+	}{
+		{"missing code", args},
+		{"short args", append(code, args[:32]...)},
+		{"bad offset", append(code, bad...)},
+	}
+	for _, cs := range cases {
+		if _, err := c.DecodeArgs(cs.input, code); err == nil {
+			t.Errorf("%s: expected error", cs.desc)
+		}
+	}
+
+	c = Constructor{Type: "constructor"}
+	_, err = c.DecodeArgs(code, code)
+	tc.NoErr(t, err)
+	if _, err := c.DecodeArgs(append(code, 1), code); err == nil {
+		t.Error("expected error for unexpected args")
+	}
+}
@@ -1,7 +1,10 @@
 // Types for ABI encoding / decoding
 package abit
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
 
 type kind byte
 
@@ -10,11 +13,44 @@ const (
 	D             //dynamic
 	T             //tuple
 	L             //list
+	A             //array (fixed length)
 )
 
+// Returns the type described by desc. For example:
+// uint256, int24, bytes4, fixed128x18, (uint8,string)[2][] or tuple[3]
+// when fields are provided. Returns the zero Type
+// when desc is not supported.
 func Resolve(desc string, fields ...Type) Type {
-	if strings.HasSuffix(desc, "[]") {
-		return List(Resolve(strings.TrimSuffix(desc, "[]"), fields...))
+	// the outermost dimension is the last one:
+	// uint8[2][3] is 3 arrays of 2 uint8
+	if strings.HasSuffix(desc, "]") {
+		i := strings.LastIndexByte(desc, '[')
+		if i < 0 {
+			return Type{}
+		}
+		et := Resolve(desc[:i], fields...)
+		if et.Name == "" {
+			return Type{}
+		}
+		if desc[i+1:] == "]" {
+			return List(et)
+		}
+		d := desc[i+1 : len(desc)-1]
+		n, err := strconv.Atoi(d)
+		if err != nil || n <= 0 || d != strconv.Itoa(n) {
+			return Type{}
+		}
+		return Array(et, n)
+	}
+	if strings.HasPrefix(desc, "bytes") && desc != "bytes" {
+		n, err := strconv.Atoi(desc[len("bytes"):])
+		if err != nil || n < 1 || n > 32 || desc != "bytes"+strconv.Itoa(n) {
+			return Type{}
+		}
+		return BytesN(n)
+	}
+	if strings.HasPrefix(desc, "fixed") || strings.HasPrefix(desc, "ufixed") {
+		return resolveFixed(desc)
 	}
 	switch desc {
 	case "address":
@@ -23,8 +59,6 @@ func Resolve(desc string, fields ...Type) Type {
 		return Bool
 	case "bytes":
 		return Bytes
-	case "bytes32":
-		return Bytes32
 	case "string":
 		return String
 	case "tuple":
@@ -35,8 +69,40 @@ func Resolve(desc string, fields ...Type) Type {
 		return Uint64
 	case "uint256":
 		return Uint256
-	default:
-		return Type{}
+	}
+	if strings.HasPrefix(desc, "uint") {
+		n, err := strconv.Atoi(desc[len("uint"):])
+		if err != nil || n < 8 || n > 256 || n%8 != 0 || desc != "uint"+strconv.Itoa(n) {
+			return Type{}
+		}
+		return Uint(n)
+	}
+	if strings.HasPrefix(desc, "int") {
+		n, err := strconv.Atoi(desc[len("int"):])
+		if err != nil || n < 8 || n > 256 || n%8 != 0 || desc != "int"+strconv.Itoa(n) {
+			return Type{}
+		}
+		return Int(n)
+	}
+	return Type{}
+}
+
+// Unsigned integer of bits (8 to 256 in steps of 8).
+// Encoded as a 32 byte word like uint256.
+func Uint(bits int) Type {
+	return Type{
+		Name: "uint" + strconv.Itoa(bits),
+		Kind: S,
+	}
+}
+
+// Signed integer of bits (8 to 256 in steps of 8).
+// Encoded as a 32 byte two's complement word.
+func Int(bits int) Type {
+	return Type{
+		Name:   "int" + strconv.Itoa(bits),
+		Kind:   S,
+		Signed: true,
 	}
 }
 
@@ -45,17 +111,67 @@ type Type struct {
 	Name string
 
 	Fields []*Type //For Tuple
-	Elem   *Type   //For List
+	Elem   *Type   //For List and Array
+	Length int     //For Array and bytesN
+
+	Signed   bool //For int and fixed
+	Decimals int  //For fixed and ufixed
+}
+
+// Static types are encoded in place. Dynamic types
+// are encoded after the static values and referenced
+// by an offset. Tuples and arrays are static when
+// their fields or elements are static.
+func (t Type) Static() bool {
+	switch t.Kind {
+	case S:
+		return true
+	case T:
+		for _, f := range t.Fields {
+			if !f.Static() {
+				return false
+			}
+		}
+		return true
+	case A:
+		return t.Elem.Static()
+	default:
+		return false
+	}
+}
+
+// Number of bytes t occupies in the head of
+// its enclosing tuple. Dynamic types are
+// referenced by a 32 byte offset.
+func (t Type) HeadSize() int {
+	if !t.Static() {
+		return 32
+	}
+	switch t.Kind {
+	case T:
+		var n int
+		for _, f := range t.Fields {
+			n += f.HeadSize()
+		}
+		return n
+	case A:
+		return t.Length * t.Elem.HeadSize()
+	default:
+		return 32
+	}
 }
 
 // Returns signature of the type including it's Elem and Fields
 // For example:
 // 	tuple(uint8, address) becomes (uint8, address)
 // 	tuple(uint8, address[] becomes (uint8, address)[]
+// 	uint8[2][3] stays uint8[2][3]
 func (t Type) Signature() string {
 	switch t.Kind {
 	case L:
 		return t.Elem.Signature() + "[]"
+	case A:
+		return t.Elem.Signature() + "[" + strconv.Itoa(t.Length) + "]"
 	case T:
 		var s strings.Builder
 		s.WriteString("(")
@@ -85,10 +201,7 @@ var (
 		Name: "bytes",
 		Kind: D,
 	}
-	Bytes32 = Type{
-		Name: "bytes32",
-		Kind: S,
-	}
+	Bytes32 = BytesN(32)
 	String = Type{
 		Name: "string",
 		Kind: D,
@@ -105,6 +218,7 @@ var (
 		Name: "uint256",
 		Kind: S,
 	}
+	Int256 = Int(256)
 )
 
 func List(et Type) Type {
@@ -115,6 +229,64 @@ func List(et Type) Type {
 	}
 }
 
+// Fixed length array of n elements
+func Array(et Type, n int) Type {
+	return Type{
+		Name:   et.Signature(),
+		Kind:   A,
+		Elem:   &et,
+		Length: n,
+	}
+}
+
+// Fixed size byte array. n must be in [1, 32]
+func BytesN(n int) Type {
+	return Type{
+		Name:   "bytes" + strconv.Itoa(n),
+		Kind:   S,
+		Length: n,
+	}
+}
+
+// Fixed point number of bits (8 to 256 in steps of 8)
+// whose value is v / 10^decimals (0 to 80) where v is
+// a signed integer when signed is true.
+func Fixed(signed bool, bits, decimals int) Type {
+	name := "ufixed"
+	if signed {
+		name = "fixed"
+	}
+	return Type{
+		Name:     name + strconv.Itoa(bits) + "x" + strconv.Itoa(decimals),
+		Kind:     S,
+		Signed:   signed,
+		Decimals: decimals,
+	}
+}
+
+// fixed and ufixed are aliases for
+// fixed128x18 and ufixed128x18
+func resolveFixed(desc string) Type {
+	signed := strings.HasPrefix(desc, "fixed")
+	desc = strings.TrimPrefix(strings.TrimPrefix(desc, "u"), "fixed")
+	if desc == "" {
+		return Fixed(signed, 128, 18)
+	}
+	m, n, ok := strings.Cut(desc, "x")
+	if !ok {
+		return Type{}
+	}
+	bits, err := strconv.Atoi(m)
+	if err != nil || bits < 8 || bits > 256 || bits%8 != 0 || m != strconv.Itoa(bits) {
+		return Type{}
+	}
+	decimals, err := strconv.Atoi(n)
+	if err != nil || decimals < 0 || decimals > 80 || n != strconv.Itoa(decimals) {
+		return Type{}
+	}
+	return Fixed(signed, bits, decimals)
+}
+
 func Tuple(types ...Type) Type {
 	t := Type{Name: "tuple", Kind: T}
 	for i := range types {
@@ -30,6 +30,106 @@ func TestResolve(t *testing.T) {
 			desc: "tuple[]",
 			want: List(Tuple()),
 		},
+		{
+			desc: "bytes4",
+			want: BytesN(4),
+		},
+		{
+			desc: "bytes32",
+			want: Bytes32,
+		},
+		{
+			desc: "uint256[3]",
+			want: Array(Uint256, 3),
+		},
+		{
+			desc: "uint8[2][3][]",
+			want: List(Array(Array(Uint8, 2), 3)),
+		},
+		{
+			desc: "bytes0",
+			want: Type{},
+		},
+		{
+			desc: "bytes33",
+			want: Type{},
+		},
+		{
+			desc: "uint8[0]",
+			want: Type{},
+		},
+		{
+			desc: "int24",
+			want: Int(24),
+		},
+		{
+			desc: "int256[]",
+			want: List(Int256),
+		},
+		{
+			desc: "int7",
+			want: Type{},
+		},
+		{
+			desc: "int264",
+			want: Type{},
+		},
+		{
+			desc: "uint8[02]",
+			want: Type{},
+		},
+		{
+			desc: "foo[2]",
+			want: Type{},
+		},
+		{
+			desc: "uint160",
+			want: Uint(160),
+		},
+		{
+			desc: "uint48[]",
+			want: List(Uint(48)),
+		},
+		{
+			desc: "uint7",
+			want: Type{},
+		},
+		{
+			desc: "uint264",
+			want: Type{},
+		},
+		{
+			desc: "uint0160",
+			want: Type{},
+		},
+		{
+			desc: "fixed",
+			want: Fixed(true, 128, 18),
+		},
+		{
+			desc: "ufixed8x0",
+			want: Fixed(false, 8, 0),
+		},
+		{
+			desc: "fixed256x80[2]",
+			want: Array(Fixed(true, 256, 80), 2),
+		},
+		{
+			desc: "fixed7x2",
+			want: Type{},
+		},
+		{
+			desc: "ufixed128x81",
+			want: Type{},
+		},
+		{
+			desc: "fixed128x018",
+			want: Type{},
+		},
+		{
+			desc: "fixed128",
+			want: Type{},
+		},
 	}
 	for _, tc := range cases {
 		r := Resolve(tc.desc)
@@ -60,6 +160,10 @@ func TestName(t *testing.T) {
 			t:    List(Address),
 			want: "address[]",
 		},
+		{
+			t:    List(Array(Tuple(BytesN(4)), 2)),
+			want: "(bytes4)[2][]",
+		},
 	}
 	for _, tc := range cases {
 		if tc.t.Signature() != tc.want {
@@ -67,3 +171,29 @@ func TestName(t *testing.T) {
 		}
 	}
 }
+
+func TestHeadSize(t *testing.T) {
+	cases := []struct {
+		t      Type
+		static bool
+		size   int
+	}{
+		{Uint256, true, 32},
+		{String, false, 32},
+		{List(Uint8), false, 32},
+		{Tuple(Uint8, Address), true, 64},
+		{Tuple(Uint8, String), false, 32},
+		{Array(Uint8, 3), true, 96},
+		{Array(Array(Tuple(Uint8, Bool), 2), 3), true, 384},
+		{Array(List(Uint8), 2), false, 32},
+		{Array(Tuple(Uint8, Bytes), 2), false, 32},
+	}
+	for _, tc := range cases {
+		if got := tc.t.Static(); got != tc.static {
+			t.Errorf("%s static got: %t want: %t", tc.t.Signature(), got, tc.static)
+		}
+		if got := tc.t.HeadSize(); got != tc.size {
+			t.Errorf("%s head size got: %d want: %d", tc.t.Signature(), got, tc.size)
+		}
+	}
+}
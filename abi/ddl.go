@@ -0,0 +1,103 @@
+package abi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/indexsupply/x/abi/abit"
+)
+
+// Returns a Postgres create table statement with
+// a column for each of e's inputs. Columns are named like
+// [Flatten]: tuples and fixed size arrays are flattened
+// into a column per field or element. Dynamic arrays are
+// stored in a single array column or, when the elements
+// are tuples or arrays, as jsonb.
+//
+// Column types:
+//   - address, bytes, bytesN: bytea
+//   - bool: boolean
+//   - string: text
+//   - uint8: smallint
+//   - uint64, uint256: numeric
+//
+// Indexed inputs with dynamic types are stored as bytea.
+func CreateTable(table string, e Event) string {
+	var cols [][2]string
+	for i, inp := range e.Inputs {
+		name := column("", inp.Name, i)
+		if inp.Indexed && inp.ABIType().Kind != abit.S {
+			cols = append(cols, [2]string{name, "bytea"})
+			continue
+		}
+		cols = columns(cols, name, inp)
+	}
+	var s strings.Builder
+	fmt.Fprintf(&s, "create table %s (\n", quoteTable(table))
+	for i, c := range cols {
+		fmt.Fprintf(&s, "\t%s %s", quote(c[0]), c[1])
+		if i+1 < len(cols) {
+			s.WriteString(",")
+		}
+		s.WriteString("\n")
+	}
+	s.WriteString(");\n")
+	return s.String()
+}
+
+func columns(cols [][2]string, name string, inp Input) [][2]string {
+	switch {
+	case strings.HasSuffix(inp.Type, "[]"):
+		elem := strings.TrimSuffix(inp.Type, "[]")
+		if strings.HasPrefix(elem, "tuple") || strings.HasSuffix(elem, "]") {
+			return append(cols, [2]string{name, "jsonb"})
+		}
+		return append(cols, [2]string{name, pgType(elem) + "[]"})
+	case strings.HasSuffix(inp.Type, "]"):
+		i := strings.LastIndexByte(inp.Type, '[')
+		n, _ := strconv.Atoi(inp.Type[i+1 : len(inp.Type)-1])
+		elem := inp
+		elem.Type = inp.Type[:i]
+		for j := 0; j < n; j++ {
+			cols = columns(cols, column(name, "", j), elem)
+		}
+		return cols
+	case inp.Type == "tuple":
+		for i, c := range inp.Components {
+			cols = columns(cols, column(name, c.Name, i), c)
+		}
+		return cols
+	default:
+		return append(cols, [2]string{name, pgType(inp.Type)})
+	}
+}
+
+func pgType(typ string) string {
+	switch {
+	case typ == "bool":
+		return "boolean"
+	case typ == "string":
+		return "text"
+	case typ == "uint8":
+		return "smallint"
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"),
+		strings.HasPrefix(typ, "fixed"), strings.HasPrefix(typ, "ufixed"):
+		return "numeric"
+	default:
+		return "bytea"
+	}
+}
+
+func quote(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+// Quotes each part of a (optionally) schema qualified name
+func quoteTable(name string) string {
+	parts := strings.Split(name, ".")
+	for i := range parts {
+		parts[i] = quote(parts[i])
+	}
+	return strings.Join(parts, ".")
+}
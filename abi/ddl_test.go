@@ -0,0 +1,28 @@
+package abi
+
+import (
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestCreateTable(t *testing.T) {
+	e, err := ParseEvent("event E(address indexed from, string indexed memo, (uint8 a, bytes4 b) s, uint64[2] n, uint256[] amounts, (bool)[] flags, string, int24 tick)")
+	tc.NoErr(t, err)
+	const want = `create table "x"."e" (
+	"from" bytea,
+	"memo" bytea,
+	"s_a" smallint,
+	"s_b" bytea,
+	"n_0" numeric,
+	"n_1" numeric,
+	"amounts" numeric[],
+	"flags" jsonb,
+	"6" text,
+	"tick" numeric
+);
+`
+	if got := CreateTable("x.e", e); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
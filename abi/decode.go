@@ -0,0 +1,249 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/indexsupply/x/abi/abit"
+	"github.com/indexsupply/x/bint"
+)
+
+// Returned when ABI encoded input can't be decoded.
+// Param is the path to the parameter (eg orders[2].maker)
+// and Offset is the byte offset into the input where
+// the parameter's value, offset or length was expected.
+type DecodeError struct {
+	Param  string
+	Type   string
+	Offset int
+	Msg    string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding %s %s at byte %d: %s", e.Type, e.Param, e.Offset, e.Msg)
+}
+
+// Like [Decode] but validates input against inputs and
+// returns a *[DecodeError] rather than panicking. Used
+// for input that comes from the chain.
+func decodeInputs(input []byte, inputs []Input) (Item, error) {
+	d := decoder{input: input}
+	items, err := d.seq(0, len(inputs), func(i int) (Input, string) {
+		return inputs[i], column("", inputs[i].Name, i)
+	})
+	if err != nil {
+		return Item{}, err
+	}
+	return Tuple(items...), nil
+}
+
+// Like [Decode] but returns a *[DecodeError] rather than
+// panicking when input can't be decoded according to t.
+// Use this for input that comes from the chain.
+func DecodeChecked(input []byte, t abit.Type) (Item, error) {
+	if t.Kind == abit.T {
+		return decodeInputs(input, inputOf(t).Components)
+	}
+	d := decoder{input: input}
+	return d.decode(0, inputOf(t), "")
+}
+
+// Returns an unnamed Input whose ABIType is t
+func inputOf(t abit.Type) Input {
+	switch t.Kind {
+	case abit.T:
+		inp := Input{Type: "tuple", Components: make([]Input, len(t.Fields))}
+		for i, f := range t.Fields {
+			inp.Components[i] = inputOf(*f)
+		}
+		return inp
+	case abit.L:
+		inp := inputOf(*t.Elem)
+		inp.Type += "[]"
+		return inp
+	case abit.A:
+		inp := inputOf(*t.Elem)
+		inp.Type += "[" + strconv.Itoa(t.Length) + "]"
+		return inp
+	default:
+		return Input{Type: t.Name}
+	}
+}
+
+type decoder struct {
+	input []byte
+}
+
+// Returned when a log or calldata is for
+// a different event or method.
+var ErrNoMatch = errors.New("no match")
+
+// Decodes l according to e. See [Match]. Returns an
+// error when l doesn't match e and a *[DecodeError]
+// when l's data can't be decoded.
+func DecodeLog(l Log, e Event) (Item, error) {
+	if e.SignatureHash() != l.Topics[0] {
+		return Item{}, fmt.Errorf("%w: log isn't %s", ErrNoMatch, e.Signature())
+	}
+	var (
+		items     = make([]Item, len(e.Inputs))
+		unindexed []Input
+		topic     = 1
+	)
+	for i, inp := range e.Inputs {
+		if !inp.Indexed {
+			// name by position in e rather than in unindexed
+			inp.Name = column("", inp.Name, i)
+			unindexed = append(unindexed, inp)
+			continue
+		}
+		if topic >= len(l.Topics) {
+			return Item{}, fmt.Errorf("%s has too many indexed inputs", e.Signature())
+		}
+		t := inp.ABIType()
+		items[i] = Item{
+			Type:   t,
+			d:      l.Topics[topic][:],
+			hashed: t.Kind != abit.S,
+		}
+		topic++
+	}
+	item, err := decodeInputs(l.Data, unindexed)
+	if err != nil {
+		return Item{}, err
+	}
+	for i, j := 0, 0; i < len(e.Inputs); i++ {
+		if e.Inputs[i].Indexed {
+			continue
+		}
+		items[i] = item.At(j)
+		j++
+	}
+	return Tuple(items...), nil
+}
+
+func (d *decoder) errorf(off int, inp Input, path, format string, args ...any) error {
+	return &DecodeError{
+		Param:  path,
+		Type:   inp.ABIType().Signature(),
+		Offset: off,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// Reads the 32 byte word at off as an offset or length.
+// Values that can't index into the input are errors.
+func (d *decoder) uint(off int, inp Input, path, name string) (int, error) {
+	if off+32 > len(d.input) {
+		return 0, d.errorf(off, inp, path, "%s exceeds input length %d", name, len(d.input))
+	}
+	w := d.input[off : off+32]
+	for _, b := range w[:24] {
+		if b != 0 {
+			return 0, d.errorf(off, inp, path, "%s %x overflows", name, w)
+		}
+	}
+	n := bint.Decode(w[24:])
+	if n > uint64(len(d.input)) {
+		return 0, d.errorf(off, inp, path, "%s %d exceeds input length %d", name, n, len(d.input))
+	}
+	return int(n), nil
+}
+
+// Decodes n elements starting at base where at(i)
+// returns the i'th element's Input and path. See [decode].
+func (d *decoder) seq(base, n int, at func(int) (Input, string)) ([]Item, error) {
+	var (
+		items = make([]Item, n)
+		head  = base
+	)
+	for i := 0; i < n; i++ {
+		inp, path := at(i)
+		t := inp.ABIType()
+		if t.Static() {
+			it, err := d.decode(head, inp, path)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = it
+			head += t.HeadSize()
+			continue
+		}
+		offset, err := d.uint(head, inp, path, "offset")
+		if err != nil {
+			return nil, err
+		}
+		if base+offset >= len(d.input) {
+			return nil, d.errorf(head, inp, path, "offset %d exceeds input length %d", offset, len(d.input))
+		}
+		it, err := d.decode(base+offset, inp, path)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = it
+		head += 32
+	}
+	return items, nil
+}
+
+func (d *decoder) decode(off int, inp Input, path string) (Item, error) {
+	t := inp.ABIType()
+	if t.Name == "" {
+		return Item{}, d.errorf(off, inp, path, "unsupported type %q", inp.Type)
+	}
+	switch t.Kind {
+	case abit.S:
+		if off+32 > len(d.input) {
+			return Item{}, d.errorf(off, inp, path, "value exceeds input length %d", len(d.input))
+		}
+		return Item{Type: t, d: d.input[off : off+32]}, nil
+	case abit.D:
+		n, err := d.uint(off, inp, path, "length")
+		if err != nil {
+			return Item{}, err
+		}
+		if off+32+n > len(d.input) {
+			return Item{}, d.errorf(off, inp, path, "length %d exceeds input length %d", n, len(d.input))
+		}
+		return Item{Type: t, d: d.input[off+32 : off+32+n]}, nil
+	case abit.L:
+		n, err := d.uint(off, inp, path, "length")
+		if err != nil {
+			return Item{}, err
+		}
+		// each element requires at least 32 bytes
+		if n > (len(d.input)-off-32)/32 {
+			return Item{}, d.errorf(off, inp, path, "length %d exceeds input length %d", n, len(d.input))
+		}
+		items, err := d.seq(off+32, n, elem(inp, path))
+		if err != nil {
+			return Item{}, err
+		}
+		return ListOf(*t.Elem, items...), nil
+	case abit.A:
+		items, err := d.seq(off, t.Length, elem(inp, path))
+		if err != nil {
+			return Item{}, err
+		}
+		return Item{Type: t, l: items}, nil
+	default:
+		items, err := d.seq(off, len(inp.Components), func(i int) (Input, string) {
+			c := inp.Components[i]
+			return c, path + "." + column("", c.Name, i)
+		})
+		if err != nil {
+			return Item{}, err
+		}
+		return Tuple(items...), nil
+	}
+}
+
+func elem(inp Input, path string) func(int) (Input, string) {
+	e := inp
+	e.Type = inp.Type[:strings.LastIndexByte(inp.Type, '[')]
+	return func(i int) (Input, string) {
+		return e, path + "[" + strconv.Itoa(i) + "]"
+	}
+}
@@ -0,0 +1,163 @@
+package abi
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/indexsupply/x/abi/abit"
+	"github.com/indexsupply/x/tc"
+)
+
+func TestDecodeInputs(t *testing.T) {
+	m, err := ParseMethod("function f() returns (uint256 a, string s, (address maker, uint8[] ids)[] orders)")
+	tc.NoErr(t, err)
+	want := Tuple(
+		BigInt(big.NewInt(1)),
+		String("hi"),
+		List(Tuple(Address([20]byte{1}), List(Uint8(1), Uint8(2)))),
+	)
+	input := Encode(want)
+	got, err := decodeInputs(input, m.Outputs)
+	tc.NoErr(t, err)
+	if !reflect.DeepEqual(got, Decode(input, want.Type)) {
+		t.Errorf("decodeInputs and Decode disagree")
+	}
+	if s := got.At(2).At(0).At(1).At(1).Uint8(); s != 2 {
+		t.Errorf("want: 2 got: %d", s)
+	}
+
+	update := func(i int, b byte) []byte {
+		c := append([]byte{}, input...)
+		c[i] = b
+		return c
+	}
+	cases := []struct {
+		desc  string
+		input []byte
+		want  DecodeError
+	}{
+		{
+			desc:  "truncated head",
+			input: input[:32],
+			want:  DecodeError{Param: "s", Type: "string", Offset: 32},
+		},
+		{
+			desc:  "long string",
+			input: update(126, 0x01),
+			want:  DecodeError{Param: "s", Type: "string", Offset: 96},
+		},
+		{
+			desc:  "offset overflow",
+			input: update(64, 0x01),
+			want:  DecodeError{Param: "orders", Type: "(address,uint8[])[]", Offset: 64},
+		},
+		{
+			desc:  "truncated tuple",
+			input: input[:240],
+			want:  DecodeError{Param: "orders[0].maker", Type: "address", Offset: 224},
+		},
+		{
+			desc:  "truncated list",
+			input: input[:352],
+			want:  DecodeError{Param: "orders[0].ids", Type: "uint8[]", Offset: 288},
+		},
+	}
+	for _, c := range cases {
+		_, err := decodeInputs(c.input, m.Outputs)
+		var derr *DecodeError
+		if !errors.As(err, &derr) {
+			t.Errorf("%s: expected DecodeError got: %v", c.desc, err)
+			continue
+		}
+		if derr.Param != c.want.Param || derr.Type != c.want.Type || derr.Offset != c.want.Offset {
+			t.Errorf("%s: want: %s %s %d got: %s %s %d", c.desc,
+				c.want.Param, c.want.Type, c.want.Offset,
+				derr.Param, derr.Type, derr.Offset,
+			)
+		}
+	}
+}
+
+func TestDecodeLog(t *testing.T) {
+	e, err := ParseEvent("event E(address indexed a, uint256, string)")
+	tc.NoErr(t, err)
+	l := Log{
+		Topics: [4][32]byte{e.SignatureHash(), {31: 1}},
+		Data:   Encode(Tuple(BigInt(big.NewInt(7)), String("x"))),
+	}
+	got, err := DecodeLog(l, e)
+	tc.NoErr(t, err)
+	want, ok := Match(l, e)
+	if !ok {
+		t.Fatal("expected match")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeLog and Match disagree")
+	}
+
+	l.Data = l.Data[:64]
+	_, err = DecodeLog(l, e)
+	var derr *DecodeError
+	if !errors.As(err, &derr) {
+		t.Fatalf("expected DecodeError got: %v", err)
+	}
+	if derr.Param != "2" || derr.Offset != 32 {
+		t.Errorf("want: 2 at 32 got: %s at %d", derr.Param, derr.Offset)
+	}
+
+	l.Topics[0] = [32]byte{}
+	if _, err := DecodeLog(l, e); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("want ErrNoMatch got: %v", err)
+	}
+}
+
+func TestDecodeLog_Signed(t *testing.T) {
+	e, err := ParseEvent("event Swap(address indexed sender, address indexed recipient, int256 amount0, int256 amount1, uint160 sqrtPriceX96, uint128 liquidity, int24 tick)")
+	tc.NoErr(t, err)
+	l := Log{
+		Topics: [4][32]byte{e.SignatureHash(), {31: 1}, {31: 2}},
+		Data: Encode(Tuple(
+			Int(abit.Int256, big.NewInt(-1000)),
+			Int(abit.Int256, big.NewInt(500)),
+			BigInt(big.NewInt(1)),
+			BigInt(big.NewInt(2)),
+			Int(abit.Int(24), big.NewInt(-887272)),
+		)),
+	}
+	got, err := DecodeToMap(e, l)
+	tc.NoErr(t, err)
+	for name, want := range map[string]int64{"amount0": -1000, "amount1": 500, "tick": -887272} {
+		if v := got[name].(*big.Int); v.Int64() != want {
+			t.Errorf("%s want: %d got: %s", name, want, v)
+		}
+	}
+}
+
+func TestDecodeInputs_Unsupported(t *testing.T) {
+	inputs := []Input{{Name: "x", Type: "uint7"}}
+	if _, err := decodeInputs(make([]byte, 32), inputs); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
+
+func TestDecodeChecked(t *testing.T) {
+	cases := []Item{
+		Tuple(Uint64(1), String("hi"), List(Tuple(Address([20]byte{1}), Array(Uint8(1), Uint8(2))))),
+		String("hi"),
+		List(BigInt(big.NewInt(1)), BigInt(big.NewInt(2))),
+	}
+	for _, c := range cases {
+		input := Encode(c)
+		got, err := DecodeChecked(input, c.Type)
+		tc.NoErr(t, err)
+		if !reflect.DeepEqual(got, Decode(input, c.Type)) {
+			t.Errorf("DecodeChecked and Decode disagree for %s", c.Type.Signature())
+		}
+		var derr *DecodeError
+		if _, err := DecodeChecked(input[:len(input)-32], c.Type); !errors.As(err, &derr) {
+			t.Errorf("%s expected DecodeError got: %v", c.Type.Signature(), err)
+		}
+	}
+}
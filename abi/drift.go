@@ -0,0 +1,113 @@
+package abi
+
+// Drift detects a configured event that never matches logs
+// emitted by its contract. This usually indicates a typo
+// in the event's signature (eg uint vs uint256).
+//
+// Call [Drift.Observe] with each log from Address and
+// [Drift.Check] once per block.
+type Drift struct {
+	Event   *Event
+	Address [20]byte
+
+	// Number of blocks without a match
+	// before Check returns a warning.
+	Blocks uint64
+
+	// Known signatures keyed by their hash.
+	// Used to suggest a correction. May be nil.
+	Signatures map[[32]byte]string
+
+	start   uint64
+	matched bool
+	seen    map[[32]byte]int
+}
+
+// A structured warning returned by [Drift.Check]
+type DriftWarning struct {
+	Address   [20]byte
+	Event     string   // configured signature
+	Blocks    uint64   // blocks observed without a match
+	Topic     [32]byte // the suggested topic0
+	Signature string   // signature of Topic. empty when unknown
+	Count     int      // number of logs observed with Topic
+}
+
+// Records l if it was emitted by d.Address. num is
+// the number of the block that contains l.
+func (d *Drift) Observe(num uint64, l Log) {
+	if l.Address != d.Address || d.matched {
+		return
+	}
+	if d.seen == nil {
+		d.seen = map[[32]byte]int{}
+		d.start = num
+	}
+	if l.Topics[0] == d.Event.SignatureHash() {
+		d.matched = true
+		d.seen = nil
+		return
+	}
+	d.seen[l.Topics[0]]++
+}
+
+// Returns a warning when logs from d.Address have been
+// observed for d.Blocks blocks and none matched d.Event.
+// The warning suggests the observed topic whose known
+// signature is closest to d.Event's signature or,
+// when no signatures are known, the most frequent topic.
+func (d *Drift) Check(num uint64) (DriftWarning, bool) {
+	if d.matched || len(d.seen) == 0 || num-d.start < d.Blocks {
+		return DriftWarning{}, false
+	}
+	w := DriftWarning{
+		Address: d.Address,
+		Event:   d.Event.Signature(),
+		Blocks:  num - d.start,
+	}
+	best := -1
+	for topic, count := range d.seen {
+		sig, known := d.Signatures[topic]
+		switch {
+		case known:
+			dist := distance(w.Event, sig)
+			if w.Signature == "" || dist < best || (dist == best && count > w.Count) {
+				w.Topic, w.Signature, w.Count, best = topic, sig, count, dist
+			}
+		case w.Signature == "" && count > w.Count:
+			w.Topic, w.Count = topic, count
+		}
+	}
+	return w, true
+}
+
+// Levenshtein distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(n ...int) int {
+	m := n[0]
+	for _, v := range n[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,61 @@
+package abi
+
+import (
+	"testing"
+
+	"github.com/indexsupply/x/isxhash"
+)
+
+func TestDrift(t *testing.T) {
+	var (
+		addr     = [20]byte{1}
+		transfer = isxhash.Keccak32([]byte("Transfer(address,address,uint256)"))
+		approval = isxhash.Keccak32([]byte("Approval(address,address,uint256)"))
+		unknown  = [32]byte{0xff}
+	)
+	d := Drift{
+		Event: &Event{
+			Name: "Transfer",
+			Inputs: []Input{
+				{Name: "from", Type: "address", Indexed: true},
+				{Name: "to", Type: "address", Indexed: true},
+				{Name: "value", Type: "uint64"},
+			},
+		},
+		Address: addr,
+		Blocks:  100,
+		Signatures: map[[32]byte]string{
+			transfer: "Transfer(address,address,uint256)",
+			approval: "Approval(address,address,uint256)",
+		},
+	}
+	for i := uint64(0); i < 100; i++ {
+		d.Observe(i, Log{Address: addr, Topics: [4][32]byte{unknown}})
+		d.Observe(i, Log{Address: addr, Topics: [4][32]byte{unknown}})
+		d.Observe(i, Log{Address: addr, Topics: [4][32]byte{approval}})
+		d.Observe(i, Log{Address: [20]byte{2}, Topics: [4][32]byte{transfer}})
+		if i%10 == 0 {
+			d.Observe(i, Log{Address: addr, Topics: [4][32]byte{transfer}})
+		}
+		if _, ok := d.Check(i); ok {
+			t.Fatalf("unexpected warning at block %d", i)
+		}
+	}
+	w, ok := d.Check(100)
+	if !ok {
+		t.Fatal("expected warning")
+	}
+	if w.Topic != transfer || w.Signature != "Transfer(address,address,uint256)" {
+		t.Errorf("want suggestion: Transfer got: %x %q", w.Topic, w.Signature)
+	}
+	if w.Event != "Transfer(address,address,uint64)" || w.Blocks != 100 || w.Count != 10 {
+		t.Errorf("unexpected warning: %+v", w)
+	}
+
+	d.Event.Inputs[2].Type = "uint256"
+	d.Event.sig, d.Event.sigHash = "", [32]byte{}
+	d.Observe(101, Log{Address: addr, Topics: [4][32]byte{transfer}})
+	if _, ok := d.Check(200); ok {
+		t.Error("expected no warning after match")
+	}
+}
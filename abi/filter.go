@@ -0,0 +1,108 @@
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/indexsupply/x/abi/abit"
+	"github.com/indexsupply/x/isxhash"
+)
+
+// Selects logs of an event by the values of its
+// indexed inputs. See [Filter.Topics].
+type Filter struct {
+	Event Event
+
+	// Values for indexed inputs keyed by input name (or
+	// position for unnamed inputs). A log matches when
+	// the input equals any of the values. Inputs
+	// without values match any value.
+	Values map[string][]Item
+}
+
+// Topics in the format expected by eth_getLogs. Each
+// position is a list of OR'd topics. An empty position
+// matches any topic. Trailing empty positions are removed.
+type Topics [][][32]byte
+
+func (t Topics) MarshalJSON() ([]byte, error) {
+	res := make([]any, len(t))
+	for i := range t {
+		switch len(t[i]) {
+		case 0:
+			res[i] = nil
+		case 1:
+			res[i] = "0x" + hex.EncodeToString(t[i][0][:])
+		default:
+			l := make([]string, len(t[i]))
+			for j := range t[i] {
+				l[j] = "0x" + hex.EncodeToString(t[i][j][:])
+			}
+			res[i] = l
+		}
+	}
+	return json.Marshal(res)
+}
+
+// Compiles f into topics. The first position is the
+// event's signature hash unless the event is anonymous.
+// Values of dynamic inputs (eg string) are hashed.
+//
+// Returns an error when a value's type doesn't match its
+// input, when a key in f.Values isn't an indexed input,
+// or when an input is an indexed tuple or array.
+func (f *Filter) Topics() (Topics, error) {
+	var (
+		res  Topics
+		used int
+	)
+	if !f.Event.Anonymous {
+		res = append(res, [][32]byte{f.Event.SignatureHash()})
+	}
+	for i, inp := range f.Event.Inputs {
+		if !inp.Indexed {
+			continue
+		}
+		var (
+			name   = column("", inp.Name, i)
+			values = f.Values[name]
+			topics [][32]byte
+		)
+		if _, ok := f.Values[name]; ok {
+			used++
+		}
+		t := inp.ABIType()
+		for _, v := range values {
+			if got := v.Type.Signature(); got != t.Signature() {
+				return nil, fmt.Errorf("%s must be %s. got: %s", name, t.Signature(), got)
+			}
+			topic, err := topic(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			topics = append(topics, topic)
+		}
+		res = append(res, topics)
+	}
+	if used != len(f.Values) {
+		return nil, fmt.Errorf("values must be for indexed inputs of %s", f.Event.Signature())
+	}
+	for len(res) > 0 && len(res[len(res)-1]) == 0 {
+		res = res[:len(res)-1]
+	}
+	return res, nil
+}
+
+func topic(it Item) ([32]byte, error) {
+	switch it.Kind {
+	case abit.S:
+		var t [32]byte
+		copy(t[:], it.d)
+		return t, nil
+	case abit.D:
+		return isxhash.Keccak32(it.d), nil
+	default:
+		return [32]byte{}, fmt.Errorf("indexed %s isn't supported", it.Type.Signature())
+	}
+}
@@ -0,0 +1,75 @@
+package abi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/indexsupply/x/isxhash"
+	"github.com/indexsupply/x/tc"
+)
+
+func TestFilter(t *testing.T) {
+	e, err := ParseEvent("event Transfer(address indexed from, address indexed to, uint256 value)")
+	tc.NoErr(t, err)
+	f := Filter{
+		Event: e,
+		Values: map[string][]Item{
+			"to": {Address([20]byte{19: 1}), Address([20]byte{19: 2})},
+		},
+	}
+	got, err := f.Topics()
+	tc.NoErr(t, err)
+	want := Topics{
+		{e.SignatureHash()},
+		nil,
+		{{31: 1}, {31: 2}},
+	}
+	if len(got) != len(want) || len(got[1]) != 0 || got[2][0] != want[2][0] || got[2][1] != want[2][1] {
+		t.Errorf("want: %x got: %x", want, got)
+	}
+	b, err := json.Marshal(got)
+	tc.NoErr(t, err)
+	const wantJSON = `["0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",null,["0x0000000000000000000000000000000000000000000000000000000000000001","0x0000000000000000000000000000000000000000000000000000000000000002"]]`
+	if string(b) != wantJSON {
+		t.Errorf("want: %s got: %s", wantJSON, b)
+	}
+
+	// trailing wildcards are removed
+	f.Values = map[string][]Item{"from": {Address([20]byte{})}}
+	got, err = f.Topics()
+	tc.NoErr(t, err)
+	if len(got) != 2 {
+		t.Errorf("want 2 topics got: %d", len(got))
+	}
+}
+
+func TestFilter_Dynamic(t *testing.T) {
+	e, err := ParseEvent("event E(string indexed, uint8 indexed b) anonymous")
+	tc.NoErr(t, err)
+	f := Filter{
+		Event:  e,
+		Values: map[string][]Item{"0": {String("foo")}},
+	}
+	got, err := f.Topics()
+	tc.NoErr(t, err)
+	if len(got) != 1 || got[0][0] != isxhash.Keccak32([]byte("foo")) {
+		t.Errorf("want: keccak(foo) got: %x", got)
+	}
+}
+
+func TestFilter_Errors(t *testing.T) {
+	e, err := ParseEvent("event E(address indexed a, uint256 b, (uint8) indexed c)")
+	tc.NoErr(t, err)
+	cases := []map[string][]Item{
+		{"a": {Uint64(1)}},
+		{"b": {BigInt(nil)}},
+		{"x": {Address([20]byte{})}},
+		{"c": {Tuple(Uint8(1))}},
+	}
+	for _, values := range cases {
+		f := Filter{Event: e, Values: values}
+		if _, err := f.Topics(); err == nil {
+			t.Errorf("expected error for %v", values)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package abi
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/indexsupply/x/abi/abit"
+)
+
+// Flattens it, which must be decoded according to inputs
+// (eg the result of [Match]), into a map of column names to
+// primitive values. Column names are the path to the value
+// joined with underscores. For example s.c[2].x becomes s_c_2_x.
+// Unnamed inputs are named by their position.
+//
+// Values have the following Go types:
+//   - address: [20]byte
+//   - bool: bool
+//   - bytes: []byte
+//   - bytes32: [32]byte
+//   - bytes1 to bytes31: []byte
+//   - string: string
+//   - uint8, uint64: uint8, uint64
+//   - uint256 and other uintN: *big.Int
+//   - intN: *big.Int
+//   - fixed, ufixed: *big.Rat
+//
+// Indexed inputs with dynamic types (eg string) are
+// stored in the log as a hash and are returned as [32]byte.
+func Flatten(inputs []Input, it Item) map[string]any {
+	res := map[string]any{}
+	for i, inp := range inputs {
+		name := column("", inp.Name, i)
+		if inp.Indexed && inp.ABIType().Kind != abit.S {
+			var h [32]byte
+			copy(h[:], it.At(i).d)
+			res[name] = h
+			continue
+		}
+		flatten(res, name, inp, it.At(i))
+	}
+	return res
+}
+
+// Decodes l according to e into a map of input names to
+// values. Unlike [Flatten], tuples are nested as map[string]any
+// and arrays as []any so that the result mirrors the event's
+// structure (eg for storing as JSON). Values have the same
+// Go types as [Flatten]. Unnamed inputs are named by their
+// position.
+//
+// Returns an error when l doesn't match e or
+// when l's data can't be decoded.
+func DecodeToMap(e Event, l Log) (map[string]any, error) {
+	it, err := DecodeLog(l, e)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]any{}
+	for i, inp := range e.Inputs {
+		name := column("", inp.Name, i)
+		if inp.Indexed && inp.ABIType().Kind != abit.S {
+			var h [32]byte
+			copy(h[:], it.At(i).d)
+			res[name] = h
+			continue
+		}
+		res[name] = nest(inp, it.At(i))
+	}
+	return res, nil
+}
+
+func nest(inp Input, it Item) any {
+	switch {
+	case strings.HasSuffix(inp.Type, "]"):
+		elem := inp
+		elem.Type = inp.Type[:strings.LastIndexByte(inp.Type, '[')]
+		res := make([]any, len(it.l))
+		for i := range it.l {
+			res[i] = nest(elem, it.l[i])
+		}
+		return res
+	case inp.Type == "tuple":
+		res := map[string]any{}
+		for i, c := range inp.Components {
+			res[column("", c.Name, i)] = nest(c, it.At(i))
+		}
+		return res
+	default:
+		return value(inp.Type, it)
+	}
+}
+
+func column(prefix, name string, i int) string {
+	if name == "" {
+		name = strconv.Itoa(i)
+	}
+	if prefix == "" {
+		return name
+	}
+	return prefix + "_" + name
+}
+
+func flatten(res map[string]any, name string, inp Input, it Item) {
+	switch {
+	case strings.HasSuffix(inp.Type, "]"):
+		elem := inp
+		elem.Type = inp.Type[:strings.LastIndexByte(inp.Type, '[')]
+		for i := range it.l {
+			flatten(res, column(name, "", i), elem, it.l[i])
+		}
+	case inp.Type == "tuple":
+		for i, c := range inp.Components {
+			flatten(res, column(name, c.Name, i), c, it.At(i))
+		}
+	default:
+		res[name] = value(inp.Type, it)
+	}
+}
+
+func value(typ string, it Item) any {
+	switch typ {
+	case "address":
+		return it.Address()
+	case "bool":
+		return it.Bool()
+	case "bytes32":
+		var b [32]byte
+		copy(b[:], it.Bytes())
+		return b
+	case "string":
+		return it.String()
+	case "uint8":
+		return it.Uint8()
+	case "uint64":
+		return it.Uint64()
+	case "uint256":
+		return it.BigInt()
+	default:
+		if strings.HasPrefix(typ, "fixed") || strings.HasPrefix(typ, "ufixed") {
+			return it.Rat()
+		}
+		if strings.HasPrefix(typ, "uint") {
+			return it.BigInt()
+		}
+		if strings.HasPrefix(typ, "int") {
+			return it.Int()
+		}
+		return it.Bytes()
+	}
+}
@@ -0,0 +1,110 @@
+package abi
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/indexsupply/x/abi/abit"
+	"github.com/indexsupply/x/tc"
+)
+
+func TestFlatten(t *testing.T) {
+	inputs := []Input{
+		{Name: "from", Type: "address", Indexed: true},
+		{Name: "memo", Type: "string", Indexed: true},
+		{
+			Name: "s",
+			Type: "tuple",
+			Components: []Input{
+				{Name: "a", Type: "uint8"},
+				{
+					Name: "c",
+					Type: "tuple[]",
+					Components: []Input{
+						{Name: "x", Type: "uint256"},
+						{Type: "bool"},
+					},
+				},
+			},
+		},
+		{Type: "string[]"},
+	}
+	topic := make([]byte, 32)
+	topic[12] = 1
+	it := Tuple(
+		Bytes(topic),
+		Item{Type: abit.String, d: make([]byte, 32), hashed: true},
+		Tuple(
+			Uint8(7),
+			List(
+				Tuple(BigInt(big.NewInt(1)), Bool(true)),
+				Tuple(BigInt(big.NewInt(2)), Bool(false)),
+			),
+		),
+		List(String("foo"), String("bar")),
+	)
+	want := map[string]any{
+		"from":    [20]byte{1},
+		"memo":    [32]byte{},
+		"s_a":     uint8(7),
+		"s_c_0_x": big.NewInt(1),
+		"s_c_0_1": true,
+		"s_c_1_x": big.NewInt(2),
+		"s_c_1_1": false,
+		"3_0":     "foo",
+		"3_1":     "bar",
+	}
+	got := Flatten(inputs, it)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%v\ngot:\n%v", want, got)
+	}
+}
+
+func TestDecodeToMap(t *testing.T) {
+	e, err := ParseEvent("event E(address indexed from, string indexed memo, (uint8 a, (uint256 x, bool)[] c) s, string[] names)")
+	tc.NoErr(t, err)
+	l := Log{
+		Topics: [4][32]byte{
+			e.SignatureHash(),
+			{31: 1},
+			{0: 0xaa},
+		},
+		Data: Encode(Tuple(
+			Tuple(
+				Uint8(7),
+				List(
+					Tuple(BigInt(big.NewInt(1)), Bool(true)),
+					Tuple(BigInt(big.NewInt(2)), Bool(false)),
+				),
+			),
+			List(String("foo"), String("bar")),
+		)),
+	}
+	want := map[string]any{
+		"from": [20]byte{19: 1},
+		"memo": [32]byte{0: 0xaa},
+		"s": map[string]any{
+			"a": uint8(7),
+			"c": []any{
+				map[string]any{"x": big.NewInt(1), "1": true},
+				map[string]any{"x": big.NewInt(2), "1": false},
+			},
+		},
+		"names": []any{"foo", "bar"},
+	}
+	got, err := DecodeToMap(e, l)
+	tc.NoErr(t, err)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%v\ngot:\n%v", want, got)
+	}
+
+	l.Data = l.Data[:64]
+	if _, err := DecodeToMap(e, l); err == nil {
+		t.Error("expected error for truncated data")
+	}
+	l.Topics[0] = [32]byte{}
+	if _, err := DecodeToMap(e, l); err == nil {
+		t.Error("expected error for mismatched topic")
+	}
+}
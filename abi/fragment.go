@@ -0,0 +1,258 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/indexsupply/x/abi/abit"
+)
+
+// Parses a human-readable event fragment. For example:
+//
+//	event Transfer(address indexed from, address indexed to, uint256 value)
+//
+// Parameter names are optional and tuples may be written
+// as tuple(...) or (...).
+func ParseEvent(s string) (Event, error) {
+	p := newFragment(s)
+	if err := p.expect("event"); err != nil {
+		return Event{}, fmt.Errorf("parsing %q: %w", s, err)
+	}
+	e := Event{Type: "event"}
+	e.Name = p.next()
+	if !isIdent(e.Name) {
+		return Event{}, fmt.Errorf("parsing %q: invalid name %q", s, e.Name)
+	}
+	inputs, err := p.params(true)
+	if err != nil {
+		return Event{}, fmt.Errorf("parsing %q: %w", s, err)
+	}
+	e.Inputs = inputs
+	if p.peek() == "anonymous" {
+		p.next()
+		e.Anonymous = true
+	}
+	if !p.done() {
+		return Event{}, fmt.Errorf("parsing %q: unexpected %q", s, p.peek())
+	}
+	return e, nil
+}
+
+// Parses a human-readable function fragment. For example:
+//
+//	function balanceOf(address owner) view returns (uint256)
+func ParseMethod(s string) (Method, error) {
+	p := newFragment(s)
+	if err := p.expect("function"); err != nil {
+		return Method{}, fmt.Errorf("parsing %q: %w", s, err)
+	}
+	m := Method{Type: "function", StateMutability: "nonpayable"}
+	m.Name = p.next()
+	if !isIdent(m.Name) {
+		return Method{}, fmt.Errorf("parsing %q: invalid name %q", s, m.Name)
+	}
+	inputs, err := p.params(false)
+	if err != nil {
+		return Method{}, fmt.Errorf("parsing %q: %w", s, err)
+	}
+	m.Inputs = inputs
+	for !p.done() {
+		switch t := p.next(); t {
+		case "view", "pure", "payable", "nonpayable":
+			m.StateMutability = t
+		case "external", "public":
+		case "returns":
+			m.Outputs, err = p.params(false)
+			if err != nil {
+				return Method{}, fmt.Errorf("parsing %q outputs: %w", s, err)
+			}
+		default:
+			return Method{}, fmt.Errorf("parsing %q: unexpected %q", s, t)
+		}
+	}
+	return m, nil
+}
+
+// Parses a human-readable constructor fragment. For example:
+//
+//	constructor(string name, uint8 decimals) payable
+func ParseConstructor(s string) (Constructor, error) {
+	p := newFragment(s)
+	if err := p.expect("constructor"); err != nil {
+		return Constructor{}, fmt.Errorf("parsing %q: %w", s, err)
+	}
+	c := Constructor{Type: "constructor", StateMutability: "nonpayable"}
+	inputs, err := p.params(false)
+	if err != nil {
+		return Constructor{}, fmt.Errorf("parsing %q: %w", s, err)
+	}
+	c.Inputs = inputs
+	for !p.done() {
+		switch t := p.next(); t {
+		case "payable", "nonpayable":
+			c.StateMutability = t
+		case "public", "internal":
+		default:
+			return Constructor{}, fmt.Errorf("parsing %q: unexpected %q", s, t)
+		}
+	}
+	return c, nil
+}
+
+type fragment struct {
+	toks []string
+	pos  int
+}
+
+func newFragment(s string) *fragment {
+	var (
+		f     = &fragment{}
+		ident strings.Builder
+	)
+	flush := func() {
+		if ident.Len() > 0 {
+			f.toks = append(f.toks, ident.String())
+			ident.Reset()
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case ' ', '\t', '\n':
+			flush()
+		case '(', ')', ',':
+			flush()
+			f.toks = append(f.toks, string(c))
+		case '[':
+			flush()
+			j := strings.IndexByte(s[i:], ']')
+			if j < 0 {
+				f.toks = append(f.toks, s[i:])
+				return f
+			}
+			f.toks = append(f.toks, s[i:i+j+1])
+			i += j
+		default:
+			ident.WriteByte(c)
+		}
+	}
+	flush()
+	return f
+}
+
+func (f *fragment) done() bool {
+	return f.pos >= len(f.toks)
+}
+
+func (f *fragment) peek() string {
+	if f.done() {
+		return ""
+	}
+	return f.toks[f.pos]
+}
+
+func (f *fragment) next() string {
+	t := f.peek()
+	f.pos++
+	return t
+}
+
+func (f *fragment) expect(want string) error {
+	if got := f.next(); got != want {
+		return fmt.Errorf("expected %q got %q", want, got)
+	}
+	return nil
+}
+
+func isIdent(s string) bool {
+	return s != "" && strings.IndexAny(s, "()[],") < 0
+}
+
+var errUnterminated = errors.New("unterminated parameter list")
+
+// Parses a parenthesized, comma separated list of parameters.
+// The indexed keyword is only permitted when indexed is true.
+func (f *fragment) params(indexed bool) ([]Input, error) {
+	if err := f.expect("("); err != nil {
+		return nil, err
+	}
+	var inputs []Input
+	if f.peek() == ")" {
+		f.next()
+		return inputs, nil
+	}
+	for {
+		inp, err := f.param(indexed)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, inp)
+		switch f.next() {
+		case ",":
+		case ")":
+			return inputs, nil
+		default:
+			return nil, errUnterminated
+		}
+	}
+}
+
+func (f *fragment) param(indexed bool) (Input, error) {
+	var inp Input
+	switch t := f.peek(); t {
+	case "tuple", "(":
+		if t == "tuple" {
+			f.next()
+		}
+		c, err := f.params(false)
+		if err != nil {
+			return inp, err
+		}
+		inp.Type = "tuple"
+		inp.Components = c
+	case "", ",", ")":
+		return inp, fmt.Errorf("expected type got %q", t)
+	default:
+		f.next()
+		switch t {
+		case "uint":
+			t = "uint256"
+		case "int":
+			t = "int256"
+		case "fixed":
+			t = "fixed128x18"
+		case "ufixed":
+			t = "ufixed128x18"
+		}
+		if abit.Resolve(t).Name == "" {
+			return inp, fmt.Errorf("unsupported type %q", t)
+		}
+		inp.Type = t
+	}
+	for strings.HasPrefix(f.peek(), "[") {
+		t := f.next()
+		if abit.Resolve("uint8"+t).Name == "" {
+			return inp, fmt.Errorf("unsupported array %q", t)
+		}
+		inp.Type += t
+	}
+	for {
+		switch t := f.peek(); t {
+		case "indexed":
+			if !indexed {
+				return inp, errors.New("indexed is only valid for event parameters")
+			}
+			f.next()
+			inp.Indexed = true
+		case "memory", "calldata", "storage":
+			f.next()
+		case "", ",", ")":
+			return inp, nil
+		default:
+			if inp.Name != "" {
+				return inp, fmt.Errorf("unexpected %q", t)
+			}
+			inp.Name = f.next()
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package abi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestParseEvent(t *testing.T) {
+	cases := []struct {
+		input string
+		want  Event
+		sig   string
+	}{
+		{
+			"event Transfer(address indexed from, address indexed to, uint256 value)",
+			Event{
+				Name: "Transfer",
+				Type: "event",
+				Inputs: []Input{
+					{Name: "from", Type: "address", Indexed: true},
+					{Name: "to", Type: "address", Indexed: true},
+					{Name: "value", Type: "uint256"},
+				},
+			},
+			"Transfer(address,address,uint256)",
+		},
+		{
+			"event Foo(uint, (uint8 a, bytes[] b)[] indexed c) anonymous",
+			Event{
+				Name:      "Foo",
+				Type:      "event",
+				Anonymous: true,
+				Inputs: []Input{
+					{Type: "uint256"},
+					{
+						Name:    "c",
+						Type:    "tuple[]",
+						Indexed: true,
+						Components: []Input{
+							{Name: "a", Type: "uint8"},
+							{Name: "b", Type: "bytes[]"},
+						},
+					},
+				},
+			},
+			"Foo(uint256,(uint8,bytes[])[])",
+		},
+		{
+			"event Fixed(bytes4 a, uint256[2][] b, (address, uint8)[3] indexed c)",
+			Event{
+				Name: "Fixed",
+				Type: "event",
+				Inputs: []Input{
+					{Name: "a", Type: "bytes4"},
+					{Name: "b", Type: "uint256[2][]"},
+					{
+						Name:    "c",
+						Type:    "tuple[3]",
+						Indexed: true,
+						Components: []Input{
+							{Type: "address"},
+							{Type: "uint8"},
+						},
+					},
+				},
+			},
+			"Fixed(bytes4,uint256[2][],(address,uint8)[3])",
+		},
+		{
+			"event Swap(address indexed sender, address indexed recipient, int256 amount0, int amount1, uint160 sqrtPriceX96, uint128 liquidity, int24 tick)",
+			Event{
+				Name: "Swap",
+				Type: "event",
+				Inputs: []Input{
+					{Name: "sender", Type: "address", Indexed: true},
+					{Name: "recipient", Type: "address", Indexed: true},
+					{Name: "amount0", Type: "int256"},
+					{Name: "amount1", Type: "int256"},
+					{Name: "sqrtPriceX96", Type: "uint160"},
+					{Name: "liquidity", Type: "uint128"},
+					{Name: "tick", Type: "int24"},
+				},
+			},
+			"Swap(address,address,int256,int256,uint160,uint128,int24)",
+		},
+		{
+			"event Empty()",
+			Event{Name: "Empty", Type: "event"},
+			"Empty()",
+		},
+	}
+	for _, c := range cases {
+		got, err := ParseEvent(c.input)
+		tc.NoErr(t, err)
+		if !reflect.DeepEqual(c.want, got) {
+			t.Errorf("want:\n%#v\ngot:\n%#v", c.want, got)
+		}
+		if got.Signature() != c.sig {
+			t.Errorf("want: %s got: %s", c.sig, got.Signature())
+		}
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	got, err := ParseMethod("function balanceOf(address) view returns (uint256)")
+	tc.NoErr(t, err)
+	want := Method{
+		Name:            "balanceOf",
+		Type:            "function",
+		StateMutability: "view",
+		Inputs:          []Input{{Type: "address"}},
+		Outputs:         []Input{{Type: "uint256"}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%#v\ngot:\n%#v", want, got)
+	}
+
+	got, err = ParseMethod("function set(string memory s, tuple(bool, bytes32) t) external")
+	tc.NoErr(t, err)
+	if got.Signature() != "set(string,(bool,bytes32))" {
+		t.Errorf("want: set(string,(bool,bytes32)) got: %s", got.Signature())
+	}
+	if got.StateMutability != "nonpayable" {
+		t.Errorf("want: nonpayable got: %s", got.StateMutability)
+	}
+}
+
+func TestParseConstructor(t *testing.T) {
+	got, err := ParseConstructor("constructor(string memory name, uint8 decimals) payable")
+	tc.NoErr(t, err)
+	want := Constructor{
+		Type:            "constructor",
+		StateMutability: "payable",
+		Inputs:          []Input{{Name: "name", Type: "string"}, {Name: "decimals", Type: "uint8"}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%#v\ngot:\n%#v", want, got)
+	}
+	if got.Signature() != "constructor(string,uint8)" {
+		t.Errorf("want: constructor(string,uint8) got: %s", got.Signature())
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	events := []string{
+		"",
+		"function Transfer(address)",
+		"event (address)",
+		"event Transfer(address",
+		"event Transfer(address,)",
+		"event Transfer(uint7)",
+		"event Transfer(uint256[0])",
+		"event Transfer(uint256[x])",
+		"event Transfer(address a b)",
+		"event Transfer(address) view",
+	}
+	for _, s := range events {
+		if _, err := ParseEvent(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+	methods := []string{
+		"event balanceOf(address)",
+		"function balanceOf(address indexed a)",
+		"function balanceOf(address) returns",
+		"function balanceOf(address) returns (uint256",
+		"function balanceOf(address) constant",
+	}
+	for _, s := range methods {
+		if _, err := ParseMethod(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+	constructors := []string{
+		"function constructor(address)",
+		"constructor(address indexed a)",
+		"constructor(address) view",
+		"constructor(address) returns (uint256)",
+	}
+	for _, s := range constructors {
+		if _, err := ParseConstructor(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
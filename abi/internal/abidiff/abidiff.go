@@ -0,0 +1,203 @@
+// Differential testing of package abi against go-ethereum's abi.
+package abidiff
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/indexsupply/x/abi"
+	"github.com/indexsupply/x/abi/abit"
+
+	gethabi "github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Builds a random type and value from seed and encodes
+// the value with abi.Encode and go-ethereum's Arguments.Pack.
+// Returns an error if the encodings differ or if
+// abi.Decode doesn't round trip go-ethereum's encoding.
+func Compare(seed []byte) error {
+	g := &gen{b: seed}
+	t := g.typ(0)
+	gt, err := gethType(t)
+	if err != nil {
+		return fmt.Errorf("geth type %s: %w", t.Signature(), err)
+	}
+	it, v := g.value(t, gt.GetType())
+	want, err := gethabi.Arguments{{Type: gt}}.Pack(v.Interface())
+	if err != nil {
+		return fmt.Errorf("geth pack %s: %w", t.Signature(), err)
+	}
+	got := abi.Encode(abi.Tuple(it))
+	if !bytes.Equal(want, got) {
+		return fmt.Errorf("encoding %s\nwant: %x\ngot:  %x", t.Signature(), want, got)
+	}
+	dec := abi.Decode(want, abit.Tuple(t))
+	if got := abi.Encode(dec); !bytes.Equal(want, got) {
+		return fmt.Errorf("decoding %s\nwant: %x\ngot:  %x", t.Signature(), want, got)
+	}
+	return nil
+}
+
+// geth requires tuple types to be named tuple
+// and described by their components
+func gethType(t abit.Type) (gethabi.Type, error) {
+	m := marshaling("", t)
+	return gethabi.NewType(m.Type, "", m.Components)
+}
+
+func marshaling(name string, t abit.Type) gethabi.ArgumentMarshaling {
+	var suffix string
+	for ; t.Kind == abit.L || t.Kind == abit.A; t = *t.Elem {
+		if t.Kind == abit.L {
+			suffix = "[]" + suffix
+		} else {
+			suffix = "[" + strconv.Itoa(t.Length) + "]" + suffix
+		}
+	}
+	if t.Kind != abit.T {
+		return gethabi.ArgumentMarshaling{Name: name, Type: t.Name + suffix}
+	}
+	m := gethabi.ArgumentMarshaling{Name: name, Type: "tuple" + suffix}
+	for i := range t.Fields {
+		m.Components = append(m.Components, marshaling("f"+strconv.Itoa(i), *t.Fields[i]))
+	}
+	return m
+}
+
+// Deterministically reads choices from b.
+// Returns zeros once b is exhausted.
+type gen struct {
+	b []byte
+}
+
+func (g *gen) byte() byte {
+	if len(g.b) == 0 {
+		return 0
+	}
+	c := g.b[0]
+	g.b = g.b[1:]
+	return c
+}
+
+func (g *gen) bytes(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = g.byte()
+	}
+	return b
+}
+
+const maxDepth = 3
+
+func (g *gen) typ(depth int) abit.Type {
+	n := 8
+	if depth < maxDepth {
+		n = 11
+	}
+	switch g.byte() % byte(n) {
+	case 0:
+		return abit.Uint8
+	case 1:
+		return abit.Uint64
+	case 2:
+		return abit.Uint256
+	case 3:
+		return abit.Address
+	case 4:
+		return abit.Bool
+	case 5:
+		return abit.String
+	case 6:
+		return abit.Bytes
+	case 7:
+		return abit.BytesN(int(g.byte()%32) + 1)
+	case 8:
+		fields := make([]abit.Type, int(g.byte()%3)+1)
+		for i := range fields {
+			fields[i] = g.typ(depth + 1)
+		}
+		return abit.Tuple(fields...)
+	case 9:
+		return abit.List(g.typ(depth + 1))
+	default:
+		return abit.Array(g.typ(depth+1), int(g.byte()%3)+1)
+	}
+}
+
+// Returns t's value as an abi.Item and as a
+// value of rt, the Go type geth uses for t.
+func (g *gen) value(t abit.Type, rt reflect.Type) (abi.Item, reflect.Value) {
+	switch t.Kind {
+	case abit.T:
+		var (
+			items = make([]abi.Item, len(t.Fields))
+			rv    = reflect.New(rt).Elem()
+		)
+		for i := range t.Fields {
+			var fv reflect.Value
+			items[i], fv = g.value(*t.Fields[i], rt.Field(i).Type)
+			rv.Field(i).Set(fv)
+		}
+		return abi.Tuple(items...), rv
+	case abit.L:
+		var (
+			n     = int(g.byte() % 4)
+			items = make([]abi.Item, n)
+			rv    = reflect.MakeSlice(rt, n, n)
+		)
+		for i := range items {
+			var ev reflect.Value
+			items[i], ev = g.value(*t.Elem, rt.Elem())
+			rv.Index(i).Set(ev)
+		}
+		return abi.ListOf(*t.Elem, items...), rv
+	case abit.A:
+		var (
+			items = make([]abi.Item, t.Length)
+			rv    = reflect.New(rt).Elem()
+		)
+		for i := range items {
+			var ev reflect.Value
+			items[i], ev = g.value(*t.Elem, rt.Elem())
+			rv.Index(i).Set(ev)
+		}
+		return abi.Array(items...), rv
+	}
+	switch {
+	case t.Name == "uint8":
+		v := g.byte()
+		return abi.Uint8(v), reflect.ValueOf(v)
+	case t.Name == "uint64":
+		v := new(big.Int).SetBytes(g.bytes(8)).Uint64()
+		return abi.Uint64(v), reflect.ValueOf(v)
+	case t.Name == "uint256":
+		v := new(big.Int).SetBytes(g.bytes(32))
+		return abi.BigInt(v), reflect.ValueOf(v)
+	case t.Name == "address":
+		var a [20]byte
+		copy(a[:], g.bytes(20))
+		rv := reflect.New(rt).Elem()
+		reflect.Copy(rv, reflect.ValueOf(a[:]))
+		return abi.Address(a), rv
+	case t.Name == "bool":
+		v := g.byte()%2 == 1
+		return abi.Bool(v), reflect.ValueOf(v)
+	case t.Name == "string":
+		v := string(g.bytes(int(g.byte() % 70)))
+		return abi.String(v), reflect.ValueOf(v)
+	case t.Name == "bytes":
+		v := g.bytes(int(g.byte() % 70))
+		return abi.Bytes(v), reflect.ValueOf(v)
+	case strings.HasPrefix(t.Name, "bytes"):
+		v := g.bytes(t.Length)
+		rv := reflect.New(rt).Elem()
+		reflect.Copy(rv, reflect.ValueOf(v))
+		return abi.BytesN(v), rv
+	default:
+		panic("abidiff: unsupported type " + t.Name)
+	}
+}
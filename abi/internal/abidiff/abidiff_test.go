@@ -0,0 +1,37 @@
+package abidiff
+
+import "testing"
+
+func seeds() [][]byte {
+	return [][]byte{
+		{},
+		{0},
+		{2, 0xff},
+		{5, 33, 'h', 'e', 'l', 'l', 'o'},
+		{7, 3, 1, 2, 3, 4},
+		{8, 2, 0, 0x7f, 5, 3, 'a', 'b', 'c', 3, 9},
+		{9, 8, 1, 6, 2, 4, 4, 1, 3, 0xaa},
+		{10, 2, 9, 0, 3, 1, 2, 3},
+		{10, 1, 8, 1, 5, 2, 'a', 'b', 2},
+		{9, 9, 10, 1, 2, 2, 3, 0, 1, 7, 1},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	for _, b := range seeds() {
+		if err := Compare(b); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func FuzzCompare(f *testing.F) {
+	for _, b := range seeds() {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		if err := Compare(b); err != nil {
+			t.Error(err)
+		}
+	})
+}
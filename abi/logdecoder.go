@@ -0,0 +1,127 @@
+package abi
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Decodes batches of logs with a pool of workers.
+// Logs are matched against the registered events by their
+// first topic and passed to the event's handler. Logs
+// that don't match a registered event are skipped.
+//
+// Handlers must be registered before calling [LogDecoder.Decode].
+type LogDecoder struct {
+	// Number of goroutines decoding each batch.
+	// Defaults to 1
+	Workers int
+
+	handlers map[[32]byte]handler
+}
+
+type handler struct {
+	e *Event
+	f func(Log, Item) (any, error)
+}
+
+// The output of a handler for a single log.
+// Err is set when the log's data doesn't decode
+// (see [DecodeError]) or when the handler fails.
+type Decoded struct {
+	Log   Log
+	Event *Event
+	Value any
+	Err   error
+}
+
+// Registers f to convert logs matching e into values
+// (eg a generated struct). f is called concurrently.
+// Anonymous events can't be matched by topic and
+// are an error.
+func (d *LogDecoder) Handle(e Event, f func(Log, Item) (any, error)) error {
+	if e.Anonymous {
+		return errors.New("anonymous events don't have a topic")
+	}
+	if d.handlers == nil {
+		d.handlers = map[[32]byte]handler{}
+	}
+	d.handlers[e.SignatureHash()] = handler{e: &e, f: f}
+	return nil
+}
+
+// Decodes each batch received on batches and sends the
+// results, in log order, on the returned channel. The
+// channel is closed once batches is closed or ctx is done.
+func (d *LogDecoder) Decode(ctx context.Context, batches <-chan []Log) <-chan []Decoded {
+	out := make(chan []Decoded)
+	go func() {
+		defer close(out)
+		for {
+			var (
+				batch []Log
+				ok    bool
+			)
+			select {
+			case <-ctx.Done():
+				return
+			case batch, ok = <-batches:
+				if !ok {
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- d.DecodeBatch(batch):
+			}
+		}
+	}()
+	return out
+}
+
+// Decodes logs using d's workers. Results are
+// in the same order as logs.
+func (d *LogDecoder) DecodeBatch(logs []Log) []Decoded {
+	var (
+		res     = make([]Decoded, len(logs))
+		matched = make([]bool, len(logs))
+		workers = d.Workers
+		wg      sync.WaitGroup
+	)
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(logs) {
+		workers = len(logs)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(logs); i += workers {
+				h, ok := d.handlers[logs[i].Topics[0]]
+				if !ok {
+					continue
+				}
+				matched[i] = true
+				res[i] = Decoded{Log: logs[i], Event: h.e}
+				it, err := DecodeLog(logs[i], *h.e)
+				if err != nil {
+					res[i].Err = err
+					continue
+				}
+				res[i].Value, res[i].Err = h.f(logs[i], it)
+			}
+		}(w)
+	}
+	wg.Wait()
+	var n int
+	for i := range res {
+		if matched[i] {
+			res[n] = res[i]
+			n++
+		}
+	}
+	return res[:n]
+}
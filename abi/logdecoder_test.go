@@ -0,0 +1,92 @@
+package abi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+type transfer struct {
+	from, to [20]byte
+	value    *big.Int
+}
+
+func TestLogDecoder(t *testing.T) {
+	e, l := transferLog(t)
+	d := LogDecoder{Workers: 4}
+	tc.NoErr(t, d.Handle(e, func(l Log, it Item) (any, error) {
+		if it.At(2).BigInt().Sign() == 0 {
+			return nil, errors.New("zero value")
+		}
+		return transfer{
+			from:  it.At(0).Address(),
+			to:    it.At(1).Address(),
+			value: it.At(2).BigInt(),
+		}, nil
+	}))
+	anon, err := ParseEvent("event Anon(uint8 a) anonymous")
+	tc.NoErr(t, err)
+	if err := d.Handle(anon, nil); err == nil {
+		t.Error("expected error for anonymous event")
+	}
+
+	var logs []Log
+	for i := 0; i < 10; i++ {
+		l := l
+		l.Data = Encode(Tuple(BigInt(big.NewInt(int64(i)))))
+		logs = append(logs, l)
+		// not registered
+		logs = append(logs, Log{Topics: [4][32]byte{{1}}})
+	}
+	logs[4].Data = logs[4].Data[:16]
+
+	res := d.DecodeBatch(logs)
+	if len(res) != 10 {
+		t.Fatalf("want 10 results got: %d", len(res))
+	}
+	for i, r := range res {
+		switch i {
+		case 0:
+			if r.Err == nil || r.Err.Error() != "zero value" {
+				t.Errorf("0: want handler error got: %v", r.Err)
+			}
+		case 2:
+			var derr *DecodeError
+			if !errors.As(r.Err, &derr) {
+				t.Errorf("2: want DecodeError got: %v", r.Err)
+			}
+		default:
+			if r.Err != nil {
+				t.Errorf("%d: unexpected error: %v", i, r.Err)
+				continue
+			}
+			tr := r.Value.(transfer)
+			if tr.value.Int64() != int64(i) || tr.to != [20]byte{19: 2} {
+				t.Errorf("%d: unexpected transfer: %v", i, tr)
+			}
+		}
+		if r.Event.Name != "Transfer" {
+			t.Errorf("%d: want Transfer got: %s", i, r.Event.Name)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	batches := make(chan []Log)
+	out := d.Decode(ctx, batches)
+	go func() {
+		batches <- logs[:2]
+		batches <- logs[2:]
+		close(batches)
+	}()
+	var n int
+	for res := range out {
+		n += len(res)
+	}
+	if n != 10 {
+		t.Errorf("want 10 results got: %d", n)
+	}
+}
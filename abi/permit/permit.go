@@ -0,0 +1,151 @@
+// EIP-2612 permits and Uniswap's Permit2 signed approvals
+//
+// Permits are EIP-712 typed data signed by a token owner.
+// This package computes the digests that are signed,
+// recovers signers and encodes/decodes the calls that
+// submit permits on chain.
+package permit
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/indexsupply/x/abi"
+	"github.com/indexsupply/x/isxhash"
+	"github.com/indexsupply/x/isxsecp256k1"
+)
+
+// EIP-712 domain. Empty fields (eg Permit2
+// doesn't have a version) are omitted.
+type Domain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract [20]byte
+}
+
+func (d Domain) Separator() [32]byte {
+	var (
+		typ    = "EIP712Domain(string name"
+		fields = []abi.Item{{}, hashString(d.Name)}
+	)
+	if d.Version != "" {
+		typ += ",string version"
+		fields = append(fields, hashString(d.Version))
+	}
+	typ += ",uint256 chainId,address verifyingContract)"
+	fields = append(fields, abi.BigInt(d.ChainID), abi.Address(d.VerifyingContract))
+	fields[0] = hashString(typ)
+	return isxhash.Keccak32(abi.Encode(abi.Tuple(fields...)))
+}
+
+// Returns the EIP-712 digest of structHash
+// in domain d. The digest is what's signed.
+func Digest(d Domain, structHash [32]byte) [32]byte {
+	sep := d.Separator()
+	b := make([]byte, 0, 66)
+	b = append(b, 0x19, 0x01)
+	b = append(b, sep[:]...)
+	b = append(b, structHash[:]...)
+	return isxhash.Keccak32(b)
+}
+
+func hashString(s string) abi.Item {
+	return abi.BytesN(isxhash.Keccak([]byte(s)))
+}
+
+func hashStruct(typ string, fields ...abi.Item) [32]byte {
+	fields = append([]abi.Item{hashString(typ)}, fields...)
+	return isxhash.Keccak32(abi.Encode(abi.Tuple(fields...)))
+}
+
+// Recovers the address that signed digest.
+// sig is r || s || recovery id (0 or 1)
+// as returned by [isxsecp256k1.Sign].
+func Signer(digest [32]byte, sig [65]byte) ([20]byte, error) {
+	pub, err := isxsecp256k1.Recover(sig, digest)
+	if err != nil {
+		return [20]byte{}, err
+	}
+	b := isxsecp256k1.Encode(pub)
+	return *(*[20]byte)(isxhash.Keccak(b[:])[12:]), nil
+}
+
+// EIP-2612 Permit
+type Permit struct {
+	Owner    [20]byte
+	Spender  [20]byte
+	Value    *big.Int
+	Nonce    *big.Int
+	Deadline *big.Int
+}
+
+func (p Permit) Hash() [32]byte {
+	return hashStruct(
+		"Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)",
+		abi.Address(p.Owner),
+		abi.Address(p.Spender),
+		abi.BigInt(p.Value),
+		abi.BigInt(p.Nonce),
+		abi.BigInt(p.Deadline),
+	)
+}
+
+// d is the token's domain (see the token's DOMAIN_SEPARATOR)
+func (p Permit) Digest(d Domain) [32]byte {
+	return Digest(d, p.Hash())
+}
+
+var permitMethod = mustParse("function permit(address owner, address spender, uint256 value, uint256 deadline, uint8 v, bytes32 r, bytes32 s)")
+
+func mustParse(s string) abi.Method {
+	m, err := abi.ParseMethod(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Returns the calldata for the token's permit function.
+// p's nonce isn't included since the token uses
+// the owner's current nonce.
+func EncodePermit(p Permit, sig [65]byte) ([]byte, error) {
+	if sig[64] > 1 {
+		return nil, errors.New("invalid signature recovery id")
+	}
+	return permitMethod.EncodeCall(
+		abi.Address(p.Owner),
+		abi.Address(p.Spender),
+		abi.BigInt(p.Value),
+		abi.BigInt(p.Deadline),
+		abi.Uint8(sig[64]+27),
+		abi.BytesN(sig[:32]),
+		abi.BytesN(sig[32:64]),
+	)
+}
+
+// Decodes calldata for a token's permit function.
+// The Permit's Nonce is nil since it isn't
+// included in calldata.
+func DecodePermit(calldata []byte) (Permit, [65]byte, error) {
+	var sig [65]byte
+	it, err := permitMethod.DecodeArgs(calldata)
+	if err != nil {
+		return Permit{}, sig, err
+	}
+	v := it.At(4).Uint8()
+	if v != 27 && v != 28 {
+		return Permit{}, sig, fmt.Errorf("invalid signature v: %d", v)
+	}
+	copy(sig[:32], it.At(5).Bytes())
+	copy(sig[32:64], it.At(6).Bytes())
+	sig[64] = v - 27
+	p := Permit{
+		Owner:    it.At(0).Address(),
+		Spender:  it.At(1).Address(),
+		Value:    it.At(2).BigInt(),
+		Deadline: it.At(3).BigInt(),
+	}
+	return p, sig, nil
+}
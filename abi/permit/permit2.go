@@ -0,0 +1,175 @@
+package permit
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/indexsupply/x/abi"
+	"github.com/indexsupply/x/abi/abit"
+)
+
+// Permit2's address on all chains
+var Permit2 = [20]byte{
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x22, 0xd4, 0x73, 0x03, 0x0f,
+	0x11, 0x6d, 0xde, 0xe9, 0xf6, 0xb4, 0x3a, 0xc7, 0x8b, 0xa3,
+}
+
+// Permit2's domain on the chain with id chainID
+func Permit2Domain(chainID *big.Int) Domain {
+	return Domain{
+		Name:              "Permit2",
+		ChainID:           chainID,
+		VerifyingContract: Permit2,
+	}
+}
+
+const (
+	permitDetailsType      = "PermitDetails(address token,uint160 amount,uint48 expiration,uint48 nonce)"
+	permitSingleType       = "PermitSingle(PermitDetails details,address spender,uint256 sigDeadline)" + permitDetailsType
+	tokenPermissionsType   = "TokenPermissions(address token,uint256 amount)"
+	permitTransferFromType = "PermitTransferFrom(TokenPermissions permitted,address spender,uint256 nonce,uint256 deadline)" + tokenPermissionsType
+)
+
+// Permit2 (AllowanceTransfer) allowance for a single token
+type PermitDetails struct {
+	Token      [20]byte
+	Amount     *big.Int //uint160
+	Expiration uint64   //uint48
+	Nonce      uint64   //uint48
+}
+
+func (p PermitDetails) Hash() [32]byte {
+	return hashStruct(
+		permitDetailsType,
+		abi.Address(p.Token),
+		abi.BigInt(p.Amount),
+		abi.Uint64(p.Expiration),
+		abi.Uint64(p.Nonce),
+	)
+}
+
+// Permit2 (AllowanceTransfer) PermitSingle
+type PermitSingle struct {
+	Details     PermitDetails
+	Spender     [20]byte
+	SigDeadline *big.Int
+}
+
+func (p PermitSingle) Hash() [32]byte {
+	h := p.Details.Hash()
+	return hashStruct(
+		permitSingleType,
+		abi.BytesN(h[:]),
+		abi.Address(p.Spender),
+		abi.BigInt(p.SigDeadline),
+	)
+}
+
+// d is usually [Permit2Domain]
+func (p PermitSingle) Digest(d Domain) [32]byte {
+	return Digest(d, p.Hash())
+}
+
+// Permit2 (SignatureTransfer) PermitTransferFrom.
+// Spender isn't part of the permit's calldata;
+// it's the address that calls Permit2.
+type PermitTransferFrom struct {
+	Token    [20]byte
+	Amount   *big.Int
+	Spender  [20]byte
+	Nonce    *big.Int
+	Deadline *big.Int
+}
+
+func (p PermitTransferFrom) Hash() [32]byte {
+	h := hashStruct(tokenPermissionsType, abi.Address(p.Token), abi.BigInt(p.Amount))
+	return hashStruct(
+		permitTransferFromType,
+		abi.BytesN(h[:]),
+		abi.Address(p.Spender),
+		abi.BigInt(p.Nonce),
+		abi.BigInt(p.Deadline),
+	)
+}
+
+// d is usually [Permit2Domain]
+func (p PermitTransferFrom) Digest(d Domain) [32]byte {
+	return Digest(d, p.Hash())
+}
+
+var permit2Method = mustParse("function permit(address owner, ((address token, uint160 amount, uint48 expiration, uint48 nonce) details, address spender, uint256 sigDeadline) permitSingle, bytes signature)")
+
+func (p PermitSingle) item() abi.Item {
+	d := p.Details
+	return abi.Tuple(
+		abi.Tuple(
+			abi.Address(d.Token),
+			uintN(160, abi.BigInt(d.Amount)),
+			uintN(48, abi.Uint64(d.Expiration)),
+			uintN(48, abi.Uint64(d.Nonce)),
+		),
+		abi.Address(p.Spender),
+		abi.BigInt(p.SigDeadline),
+	)
+}
+
+// abi doesn't have constructors for uint160 or uint48.
+// Their encoding is the same as uint256.
+func uintN(bits int, it abi.Item) abi.Item {
+	it.Type = abit.Uint(bits)
+	return it
+}
+
+// Returns the calldata for Permit2's permit(address,PermitSingle,bytes).
+// sig is the owner's signature over p's digest as
+// returned by [isxsecp256k1.Sign].
+func EncodePermit2(owner [20]byte, p PermitSingle, sig [65]byte) ([]byte, error) {
+	if sig[64] > 1 {
+		return nil, errors.New("invalid signature recovery id")
+	}
+	b := sig
+	b[64] += 27
+	return permit2Method.EncodeCall(abi.Address(owner), p.item(), abi.Bytes(b[:]))
+}
+
+// Decodes calldata for Permit2's permit(address,PermitSingle,bytes).
+// The signature may be 65 bytes (r || s || v) or 64 bytes
+// (EIP-2098) and is returned in the format used by [Signer].
+func DecodePermit2(calldata []byte) ([20]byte, PermitSingle, [65]byte, error) {
+	var sig [65]byte
+	it, err := permit2Method.DecodeArgs(calldata)
+	if err != nil {
+		return [20]byte{}, PermitSingle{}, sig, err
+	}
+	switch b := it.At(2).Bytes(); len(b) {
+	case 65:
+		if b[64] != 27 && b[64] != 28 {
+			return [20]byte{}, PermitSingle{}, sig, fmt.Errorf("invalid signature v: %d", b[64])
+		}
+		copy(sig[:], b)
+		sig[64] -= 27
+	case 64:
+		// the high bit of s is the recovery id
+		copy(sig[:], b)
+		sig[64] = sig[32] >> 7
+		sig[32] &= 0x7f
+	default:
+		return [20]byte{}, PermitSingle{}, sig, fmt.Errorf("invalid signature length: %d", len(b))
+	}
+	var (
+		ps = it.At(1)
+		d  = ps.At(0)
+	)
+	p := PermitSingle{
+		Details: PermitDetails{
+			Token:      d.At(0).Address(),
+			Amount:     d.At(1).BigInt(),
+			Expiration: d.At(2).Uint64(),
+			Nonce:      d.At(3).Uint64(),
+		},
+		Spender:     ps.At(1).Address(),
+		SigDeadline: ps.At(2).BigInt(),
+	}
+	return it.At(0).Address(), p, sig, nil
+}
@@ -0,0 +1,240 @@
+package permit
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/indexsupply/x/isxhash"
+	"github.com/indexsupply/x/isxsecp256k1"
+	"github.com/indexsupply/x/tc"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+func gethDigest(t *testing.T, js string) [32]byte {
+	var td apitypes.TypedData
+	tc.NoErr(t, json.Unmarshal([]byte(js), &td))
+	h, _, err := apitypes.TypedDataAndHash(td)
+	tc.NoErr(t, err)
+	return *(*[32]byte)(h)
+}
+
+var (
+	owner   = [20]byte{19: 0xaa}
+	spender = [20]byte{19: 0xbb}
+	token   = [20]byte{19: 0xcc}
+)
+
+func TestPermit_Digest(t *testing.T) {
+	d := Domain{
+		Name:              "USD Coin",
+		Version:           "2",
+		ChainID:           big.NewInt(1),
+		VerifyingContract: token,
+	}
+	p := Permit{
+		Owner:    owner,
+		Spender:  spender,
+		Value:    big.NewInt(1000),
+		Nonce:    big.NewInt(3),
+		Deadline: big.NewInt(1700000000),
+	}
+	want := gethDigest(t, `{
+		"types": {
+			"EIP712Domain": [
+				{"name": "name", "type": "string"},
+				{"name": "version", "type": "string"},
+				{"name": "chainId", "type": "uint256"},
+				{"name": "verifyingContract", "type": "address"}
+			],
+			"Permit": [
+				{"name": "owner", "type": "address"},
+				{"name": "spender", "type": "address"},
+				{"name": "value", "type": "uint256"},
+				{"name": "nonce", "type": "uint256"},
+				{"name": "deadline", "type": "uint256"}
+			]
+		},
+		"primaryType": "Permit",
+		"domain": {
+			"name": "USD Coin",
+			"version": "2",
+			"chainId": "1",
+			"verifyingContract": "0x00000000000000000000000000000000000000cc"
+		},
+		"message": {
+			"owner": "0x00000000000000000000000000000000000000aa",
+			"spender": "0x00000000000000000000000000000000000000bb",
+			"value": "1000",
+			"nonce": "3",
+			"deadline": "1700000000"
+		}
+	}`)
+	if got := p.Digest(d); got != want {
+		t.Errorf("want: %x got: %x", want, got)
+	}
+}
+
+func TestPermit2_Digest(t *testing.T) {
+	d := Permit2Domain(big.NewInt(10))
+	types := `
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],`
+	domain := `"domain": {
+		"name": "Permit2",
+		"chainId": "10",
+		"verifyingContract": "0x000000000022D473030F116dDEE9F6B43aC78BA3"
+	},`
+	// geth's typed data doesn't support uint160 and uint48
+	// so check the type hashes against Permit2's constants
+	typeHashes := map[string]string{
+		permitDetailsType:      "65626cad6cb96493bf6f5ebea28756c966f023ab9e8a83a7101849d5573b3678",
+		permitSingleType:       "f3841cd1ff0085026a6327b620b67997ce40f282c88a8e905a7a5626e310f3d0",
+		tokenPermissionsType:   "618358ac3db8dc274f0cd8829da7e234bd48cd73c4a740aede1adec9846d06a1",
+		permitTransferFromType: "939c21a48a8dbe3a9a2404a1d46691e4d39f6583d6ec6b35714604c986d80106",
+	}
+	for typ, want := range typeHashes {
+		if got := hex.EncodeToString(isxhash.Keccak([]byte(typ))); got != want {
+			t.Errorf("%s want: %s got: %s", typ, want, got)
+		}
+	}
+
+	transfer := PermitTransferFrom{
+		Token:    token,
+		Amount:   big.NewInt(500),
+		Spender:  spender,
+		Nonce:    big.NewInt(9),
+		Deadline: big.NewInt(1700000000),
+	}
+	want := gethDigest(t, `{
+		"types": {`+types+`
+			"PermitTransferFrom": [
+				{"name": "permitted", "type": "TokenPermissions"},
+				{"name": "spender", "type": "address"},
+				{"name": "nonce", "type": "uint256"},
+				{"name": "deadline", "type": "uint256"}
+			],
+			"TokenPermissions": [
+				{"name": "token", "type": "address"},
+				{"name": "amount", "type": "uint256"}
+			]
+		},
+		"primaryType": "PermitTransferFrom",`+domain+`
+		"message": {
+			"permitted": {
+				"token": "0x00000000000000000000000000000000000000cc",
+				"amount": "500"
+			},
+			"spender": "0x00000000000000000000000000000000000000bb",
+			"nonce": "9",
+			"deadline": "1700000000"
+		}
+	}`)
+	if got := transfer.Digest(d); got != want {
+		t.Errorf("PermitTransferFrom want: %x got: %x", want, got)
+	}
+}
+
+func TestPermit_Call(t *testing.T) {
+	k, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	pub := isxsecp256k1.Encode(k.PubKey())
+	d := Domain{Name: "Token", Version: "1", ChainID: big.NewInt(1), VerifyingContract: token}
+	p := Permit{
+		Owner:    owner,
+		Spender:  spender,
+		Value:    big.NewInt(1000),
+		Nonce:    big.NewInt(0),
+		Deadline: big.NewInt(1700000000),
+	}
+	sig, err := isxsecp256k1.Sign(k, p.Digest(d))
+	tc.NoErr(t, err)
+	calldata, err := EncodePermit(p, sig)
+	tc.NoErr(t, err)
+	if got := hex.EncodeToString(calldata[:4]); got != "d505accf" {
+		t.Errorf("selector want: d505accf got: %s", got)
+	}
+
+	got, gotSig, err := DecodePermit(calldata)
+	tc.NoErr(t, err)
+	if gotSig != sig {
+		t.Errorf("sig want: %x got: %x", sig, gotSig)
+	}
+	if got.Owner != p.Owner || got.Spender != p.Spender || got.Value.Cmp(p.Value) != 0 || got.Deadline.Cmp(p.Deadline) != 0 {
+		t.Errorf("want: %v got: %v", p, got)
+	}
+	// the nonce is read from the token
+	got.Nonce = big.NewInt(0)
+	signer, err := Signer(got.Digest(d), gotSig)
+	tc.NoErr(t, err)
+	if want := addr(pub); signer != want {
+		t.Errorf("signer want: %x got: %x", want, signer)
+	}
+
+	calldata[4+4*32+31] = 29
+	if _, _, err := DecodePermit(calldata); err == nil {
+		t.Error("expected error for invalid v")
+	}
+}
+
+func TestPermit2_Call(t *testing.T) {
+	k, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	d := Permit2Domain(big.NewInt(1))
+	p := PermitSingle{
+		Details:     PermitDetails{Token: token, Amount: big.NewInt(500), Expiration: 1800000000, Nonce: 7},
+		Spender:     spender,
+		SigDeadline: big.NewInt(1700000000),
+	}
+	sig, err := isxsecp256k1.Sign(k, p.Digest(d))
+	tc.NoErr(t, err)
+	calldata, err := EncodePermit2(owner, p, sig)
+	tc.NoErr(t, err)
+	if got := hex.EncodeToString(calldata[:4]); got != "2b67b570" {
+		t.Errorf("selector want: 2b67b570 got: %s", got)
+	}
+	gotOwner, got, gotSig, err := DecodePermit2(calldata)
+	tc.NoErr(t, err)
+	if gotOwner != owner || gotSig != sig || got.Digest(d) != p.Digest(d) {
+		t.Errorf("round trip mismatch: %x %x %v", gotOwner, gotSig, got)
+	}
+	signer, err := Signer(got.Digest(d), gotSig)
+	tc.NoErr(t, err)
+	if want := addr(isxsecp256k1.Encode(k.PubKey())); signer != want {
+		t.Errorf("signer want: %x got: %x", want, signer)
+	}
+}
+
+func TestDecodePermit2_Compact(t *testing.T) {
+	p := PermitSingle{
+		Details:     PermitDetails{Token: token, Amount: big.NewInt(1)},
+		Spender:     spender,
+		SigDeadline: big.NewInt(1),
+	}
+	var sig [65]byte
+	sig[0], sig[32], sig[64] = 1, 2, 1
+	calldata, err := EncodePermit2(owner, p, sig)
+	tc.NoErr(t, err)
+	// replace the 65 byte signature with the EIP-2098 encoding
+	compact := append([]byte{}, calldata[:len(calldata)-96]...)
+	compact[len(compact)-1] = 64
+	rs := make([]byte, 64)
+	copy(rs, sig[:64])
+	rs[32] |= 0x80
+	compact = append(compact, rs...)
+	_, _, got, err := DecodePermit2(compact)
+	tc.NoErr(t, err)
+	if got != sig {
+		t.Errorf("want: %x got: %x", sig, got)
+	}
+}
+
+func addr(pub [64]byte) [20]byte {
+	return *(*[20]byte)(isxhash.Keccak(pub[:])[12:])
+}
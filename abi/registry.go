@@ -0,0 +1,141 @@
+package abi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/indexsupply/x/abi/abit"
+)
+
+// Maps function selectors and event topics to the
+// methods and events of the loaded ABIs. When two
+// entries share a selector or topic the first one
+// loaded is kept.
+//
+// The zero value is an empty registry.
+type Registry struct {
+	methods map[[4]byte]*Method
+	events  map[[32]byte]*Event
+}
+
+// A JSON ABI entry. See:
+// https://docs.soliditylang.org/en/latest/abi-spec.html#json
+type entry struct {
+	Type            string
+	Name            string
+	Inputs          []Input
+	Outputs         []Input
+	Anonymous       bool
+	StateMutability string
+}
+
+// Adds the functions and events of a JSON ABI.
+// Entries with types that aren't supported
+// by abit are skipped.
+func (r *Registry) Load(js []byte) error {
+	var entries []entry
+	if err := json.Unmarshal(js, &entries); err != nil {
+		return fmt.Errorf("decoding abi json: %w", err)
+	}
+	for _, e := range entries {
+		switch e.Type {
+		case "function":
+			r.AddMethod(Method{
+				Name:            e.Name,
+				Type:            e.Type,
+				StateMutability: e.StateMutability,
+				Inputs:          e.Inputs,
+				Outputs:         e.Outputs,
+			})
+		case "event":
+			r.AddEvent(Event{
+				Name:      e.Name,
+				Type:      e.Type,
+				Anonymous: e.Anonymous,
+				Inputs:    e.Inputs,
+			})
+		}
+	}
+	return nil
+}
+
+// Skips m when it has types that aren't supported by abit
+func (r *Registry) AddMethod(m Method) {
+	if !resolved(m.Inputs) || !resolved(m.Outputs) {
+		return
+	}
+	if r.methods == nil {
+		r.methods = map[[4]byte]*Method{}
+	}
+	if _, ok := r.methods[m.Selector()]; !ok {
+		r.methods[m.Selector()] = &m
+	}
+}
+
+// Skips e when it has types that aren't supported by
+// abit. Anonymous events don't have a topic and are skipped.
+func (r *Registry) AddEvent(e Event) {
+	if e.Anonymous || !resolved(e.Inputs) {
+		return
+	}
+	if r.events == nil {
+		r.events = map[[32]byte]*Event{}
+	}
+	if _, ok := r.events[e.SignatureHash()]; !ok {
+		r.events[e.SignatureHash()] = &e
+	}
+}
+
+func resolved(inputs []Input) bool {
+	for _, inp := range inputs {
+		if !resolvedType(inp.ABIType()) {
+			return false
+		}
+	}
+	return true
+}
+
+func resolvedType(t abit.Type) bool {
+	switch {
+	case t.Name == "":
+		return false
+	case t.Elem != nil:
+		return resolvedType(*t.Elem)
+	}
+	for _, f := range t.Fields {
+		if !resolvedType(*f) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Registry) Method(selector [4]byte) (*Method, bool) {
+	m, ok := r.methods[selector]
+	return m, ok
+}
+
+func (r *Registry) Event(topic [32]byte) (*Event, bool) {
+	e, ok := r.events[topic]
+	return e, ok
+}
+
+// Identifies the function called by calldata using its
+// selector and decodes the arguments. Returns an error
+// when the selector isn't known or when the arguments
+// don't decode according to the function's inputs.
+func (r *Registry) DecodeUnknown(calldata []byte) (*Method, Item, error) {
+	if len(calldata) < 4 {
+		return nil, Item{}, errors.New("calldata is shorter than a selector")
+	}
+	m, ok := r.Method(*(*[4]byte)(calldata))
+	if !ok {
+		return nil, Item{}, fmt.Errorf("unknown selector: %x", calldata[:4])
+	}
+	it, err := m.DecodeArgs(calldata)
+	if err != nil {
+		return m, Item{}, fmt.Errorf("%s: %w", m.Signature(), err)
+	}
+	return m, it, nil
+}
@@ -0,0 +1,70 @@
+package abi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+const erc20 = `[
+	{"type":"function","name":"transfer","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"type":"bool"}]},
+	{"type":"function","name":"totalSupply","stateMutability":"view","inputs":[],"outputs":[{"type":"uint256"}]},
+	{"type":"function","name":"unsupported","inputs":[{"name":"x","type":"int7"}]},
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]},
+	{"type":"event","name":"Anon","anonymous":true,"inputs":[]}
+]`
+
+func TestRegistry(t *testing.T) {
+	var r Registry
+	tc.NoErr(t, r.Load([]byte(erc20)))
+
+	want, err := ParseMethod("function transfer(address to, uint256 amount)")
+	tc.NoErr(t, err)
+	calldata, err := want.EncodeCall(Address([20]byte{19: 1}), BigInt(big.NewInt(1000)))
+	tc.NoErr(t, err)
+	m, it, err := r.DecodeUnknown(calldata)
+	tc.NoErr(t, err)
+	if m.Signature() != want.Signature() {
+		t.Errorf("want: %s got: %s", want.Signature(), m.Signature())
+	}
+	if got := it.At(0).Address(); got != [20]byte{19: 1} {
+		t.Errorf("want: %x got: %x", [20]byte{19: 1}, got)
+	}
+	if got := it.At(1).BigInt().Int64(); got != 1000 {
+		t.Errorf("want: 1000 got: %d", got)
+	}
+
+	m, it, err = r.DecodeUnknown([]byte{0x18, 0x16, 0x0d, 0xdd})
+	tc.NoErr(t, err)
+	if m.Name != "totalSupply" || it.Len() != 0 {
+		t.Errorf("want: totalSupply() got: %s %d", m.Signature(), it.Len())
+	}
+
+	e, err := ParseEvent("event Transfer(address indexed from, address indexed to, uint256 value)")
+	tc.NoErr(t, err)
+	if _, ok := r.Event(e.SignatureHash()); !ok {
+		t.Error("expected Transfer event")
+	}
+	if len(r.methods) != 2 || len(r.events) != 1 {
+		t.Errorf("want 2 methods and 1 event got: %d %d", len(r.methods), len(r.events))
+	}
+}
+
+func TestRegistry_Errors(t *testing.T) {
+	var r Registry
+	tc.NoErr(t, r.Load([]byte(erc20)))
+	cases := []struct {
+		desc     string
+		calldata []byte
+	}{
+		{"short", []byte{0xa9, 0x05}},
+		{"unknown selector", []byte{1, 2, 3, 4}},
+		{"truncated args", append([]byte{0xa9, 0x05, 0x9c, 0xbb}, make([]byte, 40)...)},
+	}
+	for _, c := range cases {
+		if _, _, err := r.DecodeUnknown(c.calldata); err == nil {
+			t.Errorf("%s: expected error", c.desc)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package abi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Returns an error when b can't be stored in a
+// Postgres text column: invalid UTF-8 or a NUL byte.
+// On-chain strings and bytes are arbitrary data.
+func ValidText(b []byte) error {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return fmt.Errorf("nul byte at %d", i)
+	}
+	if !utf8.Valid(b) {
+		return errors.New("invalid utf-8")
+	}
+	return nil
+}
+
+// Converts b into text that can be stored in a Postgres
+// text column. Invalid UTF-8 is replaced with U+FFFD
+// and NUL bytes are removed. When max > 0 the result is
+// truncated to at most max bytes on a rune boundary.
+func SafeText(b []byte, max int) string {
+	s := strings.ToValidUTF8(string(b), "\uFFFD")
+	s = strings.ReplaceAll(s, "\x00", "")
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
+
+// Like [Item.String] but safe for Postgres text
+// columns. See [SafeText].
+func (it Item) SafeText(max int) string {
+	if it.hashed {
+		return ""
+	}
+	return SafeText(it.d, max)
+}
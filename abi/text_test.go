@@ -0,0 +1,47 @@
+package abi
+
+import "testing"
+
+func TestValidText(t *testing.T) {
+	cases := []struct {
+		input string
+		ok    bool
+	}{
+		{"hello", true},
+		{"héllo", true},
+		{"", true},
+		{"a\x00b", false},
+		{"a\xffb", false},
+	}
+	for _, c := range cases {
+		if err := ValidText([]byte(c.input)); (err == nil) != c.ok {
+			t.Errorf("%q want ok: %t got: %v", c.input, c.ok, err)
+		}
+	}
+}
+
+func TestSafeText(t *testing.T) {
+	cases := []struct {
+		input string
+		max   int
+		want  string
+	}{
+		{"hello", 0, "hello"},
+		{"a\x00b", 0, "ab"},
+		{"a\xffb", 0, "a�b"},
+		{"a\xff\xfeb", 0, "a�b"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "h"},
+		{"héllo", 3, "hé"},
+		{"\x00\x00", 1, ""},
+	}
+	for _, c := range cases {
+		if got := SafeText([]byte(c.input), c.max); got != c.want {
+			t.Errorf("SafeText(%q, %d) want: %q got: %q", c.input, c.max, c.want, got)
+		}
+	}
+	it := String("a\x00b")
+	if got := it.SafeText(0); got != "ab" {
+		t.Errorf("want: ab got: %q", got)
+	}
+}
@@ -0,0 +1,81 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Formats v, an amount in a token's smallest unit, as an
+// exact decimal string with the given number of decimals.
+// For example FormatUnits(1500000, 6) is "1.5". Trailing
+// zeros are removed and exponents are never used so the
+// result can be used as a Postgres numeric literal.
+func FormatUnits(v *big.Int, decimals int) string {
+	if v == nil {
+		return "0"
+	}
+	s := new(big.Int).Abs(v).String()
+	if decimals > 0 {
+		if len(s) <= decimals {
+			s = strings.Repeat("0", decimals-len(s)+1) + s
+		}
+		i := len(s) - decimals
+		frac := strings.TrimRight(s[i:], "0")
+		s = s[:i]
+		if frac != "" {
+			s += "." + frac
+		}
+	}
+	if v.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// The inverse of [FormatUnits]. Returns an error when s
+// isn't a decimal number or has more than decimals
+// fractional digits (it would be rounded).
+func ParseUnits(s string, decimals int) (*big.Int, error) {
+	var neg bool
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return nil, errors.New("empty number")
+	}
+	if !digits(whole) || !digits(frac) {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > decimals {
+		return nil, fmt.Errorf("%q has more than %d decimals", s, decimals)
+	}
+	x, ok := new(big.Int).SetString(whole+frac+strings.Repeat("0", decimals-len(frac)), 10)
+	if !ok {
+		x = new(big.Int)
+	}
+	if neg {
+		x.Neg(x)
+	}
+	return x, nil
+}
+
+func digits(s string) bool {
+	for i := range s {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Formats the item's uint256 value using [FormatUnits]
+func (it Item) Units(decimals int) string {
+	return FormatUnits(it.BigInt(), decimals)
+}
@@ -0,0 +1,75 @@
+package abi
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFormatUnits(t *testing.T) {
+	cases := []struct {
+		v        string
+		decimals int
+		want     string
+	}{
+		{"0", 18, "0"},
+		{"1", 0, "1"},
+		{"1500000", 6, "1.5"},
+		{"1", 18, "0.000000000000000001"},
+		{"-1", 2, "-0.01"},
+		{"1000000000000000000", 18, "1"},
+		{"115792089237316195423570985008687907853269984665640564039457584007913129639935", 18, "115792089237316195423570985008687907853269984665640564039457.584007913129639935"},
+	}
+	for _, c := range cases {
+		v, _ := new(big.Int).SetString(c.v, 10)
+		got := FormatUnits(v, c.decimals)
+		if got != c.want {
+			t.Errorf("FormatUnits(%s, %d) got: %s want: %s", c.v, c.decimals, got, c.want)
+		}
+		back, err := ParseUnits(got, c.decimals)
+		if err != nil {
+			t.Errorf("ParseUnits(%s) error: %v", got, err)
+			continue
+		}
+		if back.Cmp(v) != 0 {
+			t.Errorf("ParseUnits(%s, %d) got: %s want: %s", got, c.decimals, back, c.v)
+		}
+	}
+	if got := BigInt(big.NewInt(25)).Units(1); got != "2.5" {
+		t.Errorf("Units got: %s want: 2.5", got)
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	cases := []struct {
+		s        string
+		decimals int
+		want     string
+		err      bool
+	}{
+		{s: "1.50", decimals: 2, want: "150"},
+		{s: ".5", decimals: 1, want: "5"},
+		{s: "+2", decimals: 3, want: "2000"},
+		{s: "0.000", decimals: 0, want: "0"},
+		{s: "1.005", decimals: 2, err: true},
+		{s: "1e18", decimals: 18, err: true},
+		{s: "1.2.3", decimals: 18, err: true},
+		{s: ".", decimals: 18, err: true},
+		{s: "", decimals: 18, err: true},
+	}
+	for _, c := range cases {
+		got, err := ParseUnits(c.s, c.decimals)
+		if c.err {
+			if err == nil {
+				t.Errorf("ParseUnits(%q) expected error", c.s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseUnits(%q) error: %v", c.s, err)
+			continue
+		}
+		if got.String() != c.want {
+			t.Errorf("ParseUnits(%q, %d) got: %s want: %s", c.s, c.decimals, got, c.want)
+		}
+	}
+}
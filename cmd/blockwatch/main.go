@@ -0,0 +1,273 @@
+// Follows the head of one or more JSON-RPC endpoints
+// and reports reorgs. Metrics are served at /debug/vars
+// when -listen is set.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+type head struct {
+	Number uint64
+	Hash   string
+	Parent string
+}
+
+func (h *head) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Number     string `json:"number"`
+		Hash       string `json:"hash"`
+		ParentHash string `json:"parentHash"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(raw.Number, "0x"), 16, 64)
+	if err != nil {
+		return fmt.Errorf("decoding number %q: %w", raw.Number, err)
+	}
+	h.Number, h.Hash, h.Parent = n, raw.Hash, raw.ParentHash
+	return nil
+}
+
+type reorg struct {
+	Ancestor uint64   // number of the common ancestor
+	Removed  []string // hashes no longer in the chain
+	Added    []string // hashes of the new branch
+}
+
+func (r reorg) Depth() int {
+	return len(r.Removed)
+}
+
+// Tracks the most recent blocks of a chain
+// so that reorgs can be detected and measured.
+type chain struct {
+	max    int // number of blocks kept
+	blocks map[uint64]head
+	tip    uint64
+}
+
+func newChain(max int) *chain {
+	return &chain{max: max, blocks: map[uint64]head{}}
+}
+
+var errTooDeep = errors.New("reorg is deeper than tracked history")
+
+// Adds h to the chain. Parents that aren't known are
+// requested with fetch. Returns a reorg when h is not a
+// descendant of the previous tip. When the reorg is deeper
+// than the tracked history the chain is reset to h and
+// errTooDeep is returned.
+func (c *chain) add(h head, fetch func(hash string) (head, error)) (*reorg, error) {
+	if b, ok := c.blocks[h.Number]; ok && b.Hash == h.Hash {
+		return nil, nil
+	}
+	if len(c.blocks) == 0 || h.Number > c.tip+uint64(c.max) {
+		c.reset(h)
+		return nil, nil
+	}
+	// Walk back from h until reaching a known block.
+	// Known blocks are contiguous so a missing block
+	// below the tip is older than the tracked history.
+	var (
+		branch = []head{h}
+		cur    = h
+	)
+	for {
+		if cur.Number == 0 {
+			c.reset(h)
+			return nil, errTooDeep
+		}
+		p, ok := c.blocks[cur.Number-1]
+		switch {
+		case ok && p.Hash == cur.Parent:
+		case !ok && cur.Number-1 <= c.tip, len(branch) > c.max:
+			c.reset(h)
+			return nil, errTooDeep
+		default:
+			parent, err := fetch(cur.Parent)
+			if err != nil {
+				return nil, fmt.Errorf("fetching parent %s: %w", cur.Parent, err)
+			}
+			branch = append(branch, parent)
+			cur = parent
+			continue
+		}
+		break
+	}
+	r := &reorg{Ancestor: cur.Number - 1}
+	for n := cur.Number; n <= c.tip; n++ {
+		if b, ok := c.blocks[n]; ok {
+			r.Removed = append(r.Removed, b.Hash)
+			delete(c.blocks, n)
+		}
+	}
+	for i := len(branch) - 1; i >= 0; i-- {
+		r.Added = append(r.Added, branch[i].Hash)
+		c.set(branch[i])
+	}
+	if len(r.Removed) == 0 {
+		return nil, nil
+	}
+	return r, nil
+}
+
+func (c *chain) reset(h head) {
+	c.blocks = map[uint64]head{}
+	c.set(h)
+}
+
+func (c *chain) set(h head) {
+	c.blocks[h.Number] = h
+	c.tip = h.Number
+	if h.Number >= uint64(c.max) {
+		delete(c.blocks, h.Number-uint64(c.max))
+	}
+}
+
+type client struct {
+	url  string
+	http *http.Client
+	id   int
+}
+
+func (c *client) call(method string, res any, params ...any) error {
+	c.id++
+	req, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      c.id,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Post(c.url, "application/json", bytes.NewReader(req))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: http status %d", method, resp.StatusCode)
+	}
+	var body struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("%s: decoding response: %w", method, err)
+	}
+	switch {
+	case body.Error != nil:
+		return fmt.Errorf("%s: %d %s", method, body.Error.Code, body.Error.Message)
+	case len(body.Result) == 0 || string(body.Result) == "null":
+		return fmt.Errorf("%s: empty result", method)
+	}
+	return json.Unmarshal(body.Result, res)
+}
+
+func (c *client) latest() (head, error) {
+	var h head
+	return h, c.call("eth_getBlockByNumber", &h, "latest", false)
+}
+
+func (c *client) byHash(hash string) (head, error) {
+	var h head
+	return h, c.call("eth_getBlockByHash", &h, hash, false)
+}
+
+var (
+	heads    = expvar.NewMap("heads")
+	reorgs   = expvar.NewMap("reorgs")
+	maxDepth = expvar.NewMap("max_reorg_depth")
+	deep     = expvar.NewMap("deep_reorgs")
+	errs     = expvar.NewMap("errors")
+)
+
+func watch(url string, interval time.Duration, history int) {
+	var (
+		c  = &client{url: url, http: &http.Client{Timeout: 10 * time.Second}}
+		ch = newChain(history)
+	)
+	for ; ; time.Sleep(interval) {
+		h, err := c.latest()
+		if err != nil {
+			errs.Add(url, 1)
+			fmt.Printf("error: %s: %s\n", url, err)
+			continue
+		}
+		prev := ch.tip
+		r, err := ch.add(h, c.byHash)
+		switch {
+		case errors.Is(err, errTooDeep):
+			deep.Add(url, 1)
+			fmt.Printf("reorg: %s deeper than %d blocks. reset to %d %s\n",
+				url, history, h.Number, h.Hash)
+		case err != nil:
+			errs.Add(url, 1)
+			fmt.Printf("error: %s: %s\n", url, err)
+			continue
+		}
+		if r != nil {
+			reorgs.Add(url, 1)
+			if v, ok := maxDepth.Get(url).(*expvar.Int); !ok || v.Value() < int64(r.Depth()) {
+				d := new(expvar.Int)
+				d.Set(int64(r.Depth()))
+				maxDepth.Set(url, d)
+			}
+			fmt.Printf("reorg: %s depth=%d ancestor=%d removed=%v added=%v\n",
+				url, r.Depth(), r.Ancestor, r.Removed, r.Added)
+		}
+		if h.Number != prev || r != nil || err != nil {
+			n := new(expvar.Int)
+			n.Set(int64(h.Number))
+			heads.Set(url, n)
+			fmt.Printf("head: %s %d %s\n", url, h.Number, h.Hash)
+		}
+	}
+}
+
+func main() {
+	var (
+		urls, listen string
+		interval     time.Duration
+		history      int
+	)
+	flag.StringVar(&urls, "rpc", "", "comma separated list of json-rpc urls")
+	flag.StringVar(&listen, "listen", "", "address for serving metrics at /debug/vars")
+	flag.DurationVar(&interval, "interval", 2*time.Second, "time between head requests")
+	flag.IntVar(&history, "history", 128, "number of blocks to keep for detecting reorgs")
+	flag.Parse()
+
+	if urls == "" {
+		fmt.Println("missing -rpc")
+		flag.Usage()
+		return
+	}
+	if listen != "" {
+		go func() { check(http.ListenAndServe(listen, nil)) }()
+	}
+	for _, u := range strings.Split(urls, ",") {
+		go watch(strings.TrimSpace(u), interval, history)
+	}
+	select {}
+}
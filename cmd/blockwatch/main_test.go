@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+// test chains are identified by a fork name
+// so that hashes are unique per branch
+func testHead(fork string, n uint64, parentFork string) head {
+	return head{
+		Number: n,
+		Hash:   fmt.Sprintf("%s%d", fork, n),
+		Parent: fmt.Sprintf("%s%d", parentFork, n-1),
+	}
+}
+
+type testNode map[string]head
+
+func (tn testNode) add(hs ...head) {
+	for _, h := range hs {
+		tn[h.Hash] = h
+	}
+}
+
+func (tn testNode) fetch(hash string) (head, error) {
+	h, ok := tn[hash]
+	if !ok {
+		return head{}, errors.New("not found")
+	}
+	return h, nil
+}
+
+func TestChain(t *testing.T) {
+	var (
+		c    = newChain(8)
+		node = testNode{}
+	)
+	for n := uint64(1); n <= 5; n++ {
+		h := testHead("a", n, "a")
+		node.add(h)
+		r, err := c.add(h, node.fetch)
+		tc.NoErr(t, err)
+		if r != nil {
+			t.Fatalf("unexpected reorg at %d", n)
+		}
+	}
+	// b forks from a3 and skips ahead to 6
+	node.add(
+		testHead("b", 4, "a"),
+		testHead("b", 5, "b"),
+		testHead("b", 6, "b"),
+	)
+	r, err := c.add(testHead("b", 6, "b"), node.fetch)
+	tc.NoErr(t, err)
+	want := &reorg{
+		Ancestor: 3,
+		Removed:  []string{"a4", "a5"},
+		Added:    []string{"b4", "b5", "b6"},
+	}
+	if !reflect.DeepEqual(want, r) {
+		t.Errorf("want: %+v got: %+v", want, r)
+	}
+	if r.Depth() != 2 {
+		t.Errorf("want depth 2 got: %d", r.Depth())
+	}
+	// repeated head and a gap that is
+	// filled from the parent are not reorgs
+	r, err = c.add(testHead("b", 6, "b"), node.fetch)
+	tc.NoErr(t, err)
+	node.add(testHead("b", 7, "b"), testHead("b", 8, "b"))
+	r2, err := c.add(testHead("b", 8, "b"), node.fetch)
+	tc.NoErr(t, err)
+	if r != nil || r2 != nil {
+		t.Errorf("unexpected reorg: %+v %+v", r, r2)
+	}
+	if c.tip != 8 || c.blocks[7].Hash != "b7" {
+		t.Errorf("expected b7 to be filled in")
+	}
+	// a shorter branch replaces the tip
+	node.add(testHead("c", 7, "b"))
+	r, err = c.add(testHead("c", 7, "b"), node.fetch)
+	tc.NoErr(t, err)
+	want = &reorg{Ancestor: 6, Removed: []string{"b7", "b8"}, Added: []string{"c7"}}
+	if !reflect.DeepEqual(want, r) {
+		t.Errorf("want: %+v got: %+v", want, r)
+	}
+}
+
+func TestChain_TooDeep(t *testing.T) {
+	var (
+		c    = newChain(4)
+		node = testNode{}
+	)
+	for n := uint64(1); n <= 10; n++ {
+		h := testHead("a", n, "a")
+		node.add(h)
+		_, err := c.add(h, node.fetch)
+		tc.NoErr(t, err)
+	}
+	for n := uint64(3); n <= 10; n++ {
+		parent := "b"
+		if n == 3 {
+			parent = "a"
+		}
+		node.add(testHead("b", n, parent))
+	}
+	if _, err := c.add(testHead("b", 10, "b"), node.fetch); !errors.Is(err, errTooDeep) {
+		t.Errorf("want: %s got: %v", errTooDeep, err)
+	}
+	// the chain follows the new branch after a deep reorg
+	h := testHead("b", 11, "b")
+	node.add(h)
+	r, err := c.add(h, node.fetch)
+	tc.NoErr(t, err)
+	if r != nil || c.tip != 11 || c.blocks[10].Hash != "b10" {
+		t.Errorf("expected chain to be reset to b10. got: %v %d %v", r, c.tip, c.blocks)
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		tc.NoErr(t, json.NewDecoder(r.Body).Decode(&req))
+		switch req.Method {
+		case "eth_getBlockByNumber":
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{"number":"0x10","hash":"0xaa","parentHash":"0xbb"}}`)
+		default:
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}`)
+		}
+	}))
+	defer srv.Close()
+
+	c := &client{url: srv.URL, http: srv.Client()}
+	h, err := c.latest()
+	tc.NoErr(t, err)
+	if want := (head{Number: 16, Hash: "0xaa", Parent: "0xbb"}); h != want {
+		t.Errorf("want: %+v got: %+v", want, h)
+	}
+	if _, err := c.byHash("0xaa"); err == nil {
+		t.Error("expected rpc error")
+	}
+}
@@ -0,0 +1,81 @@
+// Prints Postgres create table statements for the
+// events in an ABI JSON file or for a single event
+// given as a human-readable fragment:
+//
+//	eventddl -schema erc20 erc20.json
+//	eventddl -event 'event Transfer(address indexed from, address indexed to, uint256 value)'
+//
+// Tables are named after the lowercased event name.
+// See [abi.CreateTable] for the column types.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/indexsupply/x/abi"
+)
+
+func check(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	var schema, event string
+	flag.StringVar(&schema, "schema", "", "optional schema for the tables")
+	flag.StringVar(&event, "event", "", "event fragment. used instead of an abi file")
+	flag.Parse()
+
+	var events []abi.Event
+	switch {
+	case event != "":
+		e, err := abi.ParseEvent(event)
+		check(err)
+		events = append(events, e)
+	case flag.NArg() == 1:
+		js, err := os.ReadFile(flag.Arg(0))
+		check(err)
+		events, err = parse(js)
+		check(err)
+	default:
+		check(fmt.Errorf("usage: eventddl [-schema name] [-event fragment | abi.json]"))
+	}
+	fmt.Print(ddl(schema, events))
+}
+
+// Returns the events in a JSON ABI
+func parse(js []byte) ([]abi.Event, error) {
+	var entries []abi.Event
+	if err := json.Unmarshal(js, &entries); err != nil {
+		return nil, fmt.Errorf("decoding abi json: %w", err)
+	}
+	var events []abi.Event
+	for _, e := range entries {
+		if e.Type == "event" {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func ddl(schema string, events []abi.Event) string {
+	var s strings.Builder
+	for i, e := range events {
+		if i > 0 {
+			s.WriteString("\n")
+		}
+		table := strings.ToLower(e.Name)
+		if schema != "" {
+			table = schema + "." + table
+		}
+		fmt.Fprintf(&s, "-- %s\n", e.Signature())
+		s.WriteString(abi.CreateTable(table, e))
+	}
+	return s.String()
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestDDL(t *testing.T) {
+	events, err := parse([]byte(`[
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"}]},
+		{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"value","type":"uint256"}]}
+	]`))
+	tc.NoErr(t, err)
+	const want = `-- Approval(address,uint256)
+create table "erc20"."approval" (
+	"owner" bytea,
+	"value" numeric
+);
+`
+	if got := ddl("erc20", events); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
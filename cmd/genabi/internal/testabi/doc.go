@@ -0,0 +1,4 @@
+// Bindings used to test the code generated by genabi
+package testabi
+
+//go:generate go run github.com/indexsupply/x/cmd/genabi -pkg testabi test.json
@@ -0,0 +1,348 @@
+// Code generated by genabi. DO NOT EDIT.
+
+package testabi
+
+import (
+	"math/big"
+
+	"github.com/indexsupply/x/abi"
+	"github.com/indexsupply/x/abi/abit"
+)
+
+// Transfer(address,address,uint256)
+var transferEventSignatureHash = [32]byte{0xdd, 0xf2, 0x52, 0xad, 0x1b, 0xe2, 0xc8, 0x9b, 0x69, 0xc2, 0xb0, 0x68, 0xfc, 0x37, 0x8d, 0xaa, 0x95, 0x2b, 0xa7, 0xf1, 0x63, 0xc4, 0xa1, 0x16, 0x28, 0xf5, 0x5a, 0x4d, 0xf5, 0x23, 0xb3, 0xef}
+
+type TransferEvent struct {
+	From  [20]byte
+	To    [20]byte
+	Value *big.Int
+}
+
+// Returns abi.ErrNoMatch if l's first topic isn't the signature hash of Transfer(address,address,uint256)
+func MatchTransferEvent(l abi.Log) (TransferEvent, error) {
+	var x TransferEvent
+	if l.Topics[0] != transferEventSignatureHash {
+		return x, abi.ErrNoMatch
+	}
+	it, err := abi.DecodeChecked(l.Data, abit.Tuple(abit.Uint256))
+	if err != nil {
+		return x, err
+	}
+	x.From = abi.Bytes(l.Topics[1][:]).Address()
+	x.To = abi.Bytes(l.Topics[2][:]).Address()
+	x.Value = it.At(0).BigInt()
+	return x, nil
+}
+
+// Order(bytes32,string,(address,uint256[],(string,bool))[],uint8)
+var orderEventSignatureHash = [32]byte{0x7f, 0x9f, 0xe6, 0xd4, 0x7b, 0x5b, 0xa3, 0x02, 0x92, 0x64, 0x2f, 0xb2, 0x12, 0x6a, 0x81, 0xc8, 0xe5, 0xec, 0x1f, 0x33, 0x51, 0xe6, 0xe9, 0xd5, 0x2e, 0x0a, 0x8c, 0x89, 0xf1, 0xa9, 0x84, 0x58}
+
+// Indexed inputs with dynamic types are the keccak hash of their value.
+type OrderEvent struct {
+	Id    [32]byte
+	Memo  abi.TopicHash
+	Fills []OrderEventFills
+	Arg3  uint8
+}
+
+// Returns abi.ErrNoMatch if l's first topic isn't the signature hash of Order(bytes32,string,(address,uint256[],(string,bool))[],uint8)
+func MatchOrderEvent(l abi.Log) (OrderEvent, error) {
+	var x OrderEvent
+	if l.Topics[0] != orderEventSignatureHash {
+		return x, abi.ErrNoMatch
+	}
+	it, err := abi.DecodeChecked(l.Data, abit.Tuple(abit.List(abit.Tuple(abit.Address, abit.List(abit.Uint256), abit.Tuple(abit.String, abit.Bool))), abit.Uint8))
+	if err != nil {
+		return x, err
+	}
+	copy(x.Id[:], abi.Bytes(l.Topics[1][:]).Bytes())
+	x.Memo = abi.TopicHash(l.Topics[2])
+	l1 := it.At(0)
+	x.Fills = make([]OrderEventFills, l1.Len())
+	for i2 := range x.Fills {
+		x.Fills[i2] = decodeOrderEventFills(l1.At(i2))
+	}
+	x.Arg3 = it.At(1).Uint8()
+	return x, nil
+}
+
+// skipped Hidden(): anonymous
+
+// Signed(int256)
+var signedEventSignatureHash = [32]byte{0x11, 0xb8, 0x14, 0xb6, 0x8d, 0xb1, 0xb0, 0x9e, 0x3e, 0xfa, 0xa8, 0xbe, 0x01, 0x9f, 0xad, 0x3f, 0x10, 0x4f, 0xa3, 0xe3, 0x43, 0xa0, 0x5c, 0x23, 0x1a, 0x07, 0x71, 0xd2, 0xfa, 0xea, 0x5b, 0x7e}
+
+type SignedEvent struct {
+	Delta *big.Int
+}
+
+// Returns abi.ErrNoMatch if l's first topic isn't the signature hash of Signed(int256)
+func MatchSignedEvent(l abi.Log) (SignedEvent, error) {
+	var x SignedEvent
+	if l.Topics[0] != signedEventSignatureHash {
+		return x, abi.ErrNoMatch
+	}
+	it, err := abi.DecodeChecked(l.Data, abit.Tuple(abit.Int256))
+	if err != nil {
+		return x, err
+	}
+	x.Delta = it.At(0).Int()
+	return x, nil
+}
+
+// skipped Wide(uint128): unsupported type uint128
+
+// transfer(address,uint256)
+var transferSelector = [4]byte{0xa9, 0x05, 0x9c, 0xbb}
+
+type TransferCall struct {
+	To     [20]byte
+	Amount *big.Int
+}
+
+// Returns the calldata for transfer(address,uint256)
+func EncodeTransferCall(x TransferCall) []byte {
+	it := abi.Tuple(
+		abi.Address(x.To),
+		abi.BigInt(x.Amount),
+	)
+	return append(transferSelector[:], abi.Encode(it)...)
+}
+
+// Returns abi.ErrNoMatch if b doesn't start with the selector for transfer(address,uint256)
+func DecodeTransferCall(b []byte) (TransferCall, error) {
+	var x TransferCall
+	if len(b) < 4 || *(*[4]byte)(b[:4]) != transferSelector {
+		return x, abi.ErrNoMatch
+	}
+	it3, err := abi.DecodeChecked(b[4:], abit.Tuple(abit.Address, abit.Uint256))
+	if err != nil {
+		return x, err
+	}
+	x.To = it3.At(0).Address()
+	x.Amount = it3.At(1).BigInt()
+	return x, nil
+}
+
+type TransferResult struct {
+	Out0 bool
+}
+
+// Decodes the data returned by transfer(address,uint256)
+func DecodeTransferResult(b []byte) (TransferResult, error) {
+	var x TransferResult
+	it4, err := abi.DecodeChecked(b, abit.Tuple(abit.Bool))
+	if err != nil {
+		return x, err
+	}
+	x.Out0 = it4.At(0).Bool()
+	return x, nil
+}
+
+// totalSupply()
+var totalSupplySelector = [4]byte{0x18, 0x16, 0x0d, 0xdd}
+
+type TotalSupplyCall struct {
+}
+
+// Returns the calldata for totalSupply()
+func EncodeTotalSupplyCall(x TotalSupplyCall) []byte {
+	it := abi.Tuple()
+	return append(totalSupplySelector[:], abi.Encode(it)...)
+}
+
+// Returns abi.ErrNoMatch if b doesn't start with the selector for totalSupply()
+func DecodeTotalSupplyCall(b []byte) (TotalSupplyCall, error) {
+	var x TotalSupplyCall
+	if len(b) < 4 || *(*[4]byte)(b[:4]) != totalSupplySelector {
+		return x, abi.ErrNoMatch
+	}
+	return x, nil
+}
+
+type TotalSupplyResult struct {
+	Out0 *big.Int
+}
+
+// Decodes the data returned by totalSupply()
+func DecodeTotalSupplyResult(b []byte) (TotalSupplyResult, error) {
+	var x TotalSupplyResult
+	it5, err := abi.DecodeChecked(b, abit.Tuple(abit.Uint256))
+	if err != nil {
+		return x, err
+	}
+	x.Out0 = it5.At(0).BigInt()
+	return x, nil
+}
+
+// batch(uint64[],bytes,(bytes32,string[])[])
+var batchSelector = [4]byte{0xf7, 0x45, 0xe2, 0x85}
+
+type BatchCall struct {
+	Ids   []uint64
+	Data  []byte
+	Pairs []BatchCallPairs
+}
+
+// Returns the calldata for batch(uint64[],bytes,(bytes32,string[])[])
+func EncodeBatchCall(x BatchCall) []byte {
+	it := abi.Tuple(
+		func() abi.Item {
+			l7 := make([]abi.Item, len(x.Ids))
+			for i6 := range x.Ids {
+				l7[i6] = abi.Uint64(x.Ids[i6])
+			}
+			return abi.ListOf(abit.Uint64, l7...)
+		}(),
+		abi.Bytes(x.Data),
+		func() abi.Item {
+			l9 := make([]abi.Item, len(x.Pairs))
+			for i8 := range x.Pairs {
+				l9[i8] = x.Pairs[i8].abiItem()
+			}
+			return abi.ListOf(abit.Tuple(abit.Bytes32, abit.List(abit.String)), l9...)
+		}(),
+	)
+	return append(batchSelector[:], abi.Encode(it)...)
+}
+
+// Returns abi.ErrNoMatch if b doesn't start with the selector for batch(uint64[],bytes,(bytes32,string[])[])
+func DecodeBatchCall(b []byte) (BatchCall, error) {
+	var x BatchCall
+	if len(b) < 4 || *(*[4]byte)(b[:4]) != batchSelector {
+		return x, abi.ErrNoMatch
+	}
+	it10, err := abi.DecodeChecked(b[4:], abit.Tuple(abit.List(abit.Uint64), abit.Bytes, abit.List(abit.Tuple(abit.Bytes32, abit.List(abit.String)))))
+	if err != nil {
+		return x, err
+	}
+	l11 := it10.At(0)
+	x.Ids = make([]uint64, l11.Len())
+	for i12 := range x.Ids {
+		x.Ids[i12] = l11.At(i12).Uint64()
+	}
+	x.Data = it10.At(1).Bytes()
+	l13 := it10.At(2)
+	x.Pairs = make([]BatchCallPairs, l13.Len())
+	for i14 := range x.Pairs {
+		x.Pairs[i14] = decodeBatchCallPairs(l13.At(i14))
+	}
+	return x, nil
+}
+
+// transfer(address,uint256,bytes)
+var transfer2Selector = [4]byte{0xbe, 0x45, 0xfd, 0x62}
+
+type Transfer2Call struct {
+	To     [20]byte
+	Amount *big.Int
+	Data   []byte
+}
+
+// Returns the calldata for transfer(address,uint256,bytes)
+func EncodeTransfer2Call(x Transfer2Call) []byte {
+	it := abi.Tuple(
+		abi.Address(x.To),
+		abi.BigInt(x.Amount),
+		abi.Bytes(x.Data),
+	)
+	return append(transfer2Selector[:], abi.Encode(it)...)
+}
+
+// Returns abi.ErrNoMatch if b doesn't start with the selector for transfer(address,uint256,bytes)
+func DecodeTransfer2Call(b []byte) (Transfer2Call, error) {
+	var x Transfer2Call
+	if len(b) < 4 || *(*[4]byte)(b[:4]) != transfer2Selector {
+		return x, abi.ErrNoMatch
+	}
+	it15, err := abi.DecodeChecked(b[4:], abit.Tuple(abit.Address, abit.Uint256, abit.Bytes))
+	if err != nil {
+		return x, err
+	}
+	x.To = it15.At(0).Address()
+	x.Amount = it15.At(1).BigInt()
+	x.Data = it15.At(2).Bytes()
+	return x, nil
+}
+
+type OrderEventFills struct {
+	Maker   [20]byte
+	Amounts []*big.Int
+	Meta    OrderEventFillsMeta
+}
+
+func (x OrderEventFills) abiItem() abi.Item {
+	it := abi.Tuple(
+		abi.Address(x.Maker),
+		func() abi.Item {
+			l17 := make([]abi.Item, len(x.Amounts))
+			for i16 := range x.Amounts {
+				l17[i16] = abi.BigInt(x.Amounts[i16])
+			}
+			return abi.ListOf(abit.Uint256, l17...)
+		}(),
+		x.Meta.abiItem(),
+	)
+	return it
+}
+
+func decodeOrderEventFills(it abi.Item) OrderEventFills {
+	var x OrderEventFills
+	x.Maker = it.At(0).Address()
+	l18 := it.At(1)
+	x.Amounts = make([]*big.Int, l18.Len())
+	for i19 := range x.Amounts {
+		x.Amounts[i19] = l18.At(i19).BigInt()
+	}
+	x.Meta = decodeOrderEventFillsMeta(it.At(2))
+	return x
+}
+
+type BatchCallPairs struct {
+	A [32]byte
+	B []string
+}
+
+func (x BatchCallPairs) abiItem() abi.Item {
+	it := abi.Tuple(
+		abi.Bytes32(x.A),
+		func() abi.Item {
+			l21 := make([]abi.Item, len(x.B))
+			for i20 := range x.B {
+				l21[i20] = abi.String(x.B[i20])
+			}
+			return abi.ListOf(abit.String, l21...)
+		}(),
+	)
+	return it
+}
+
+func decodeBatchCallPairs(it abi.Item) BatchCallPairs {
+	var x BatchCallPairs
+	copy(x.A[:], it.At(0).Bytes())
+	l22 := it.At(1)
+	x.B = make([]string, l22.Len())
+	for i23 := range x.B {
+		x.B[i23] = l22.At(i23).String()
+	}
+	return x
+}
+
+type OrderEventFillsMeta struct {
+	Note string
+	Ok   bool
+}
+
+func (x OrderEventFillsMeta) abiItem() abi.Item {
+	it := abi.Tuple(
+		abi.String(x.Note),
+		abi.Bool(x.Ok),
+	)
+	return it
+}
+
+func decodeOrderEventFillsMeta(it abi.Item) OrderEventFillsMeta {
+	var x OrderEventFillsMeta
+	x.Note = it.At(0).String()
+	x.Ok = it.At(1).Bool()
+	return x
+}
@@ -0,0 +1,148 @@
+package testabi
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/indexsupply/x/abi"
+	"github.com/indexsupply/x/abi/abit"
+	"github.com/indexsupply/x/isxhash"
+	"github.com/indexsupply/x/tc"
+)
+
+func TestMatchTransferEvent(t *testing.T) {
+	var l abi.Log
+	l.Topics[0] = isxhash.Keccak32([]byte("Transfer(address,address,uint256)"))
+	l.Topics[1][31] = 0x01
+	l.Topics[2][31] = 0x02
+	l.Data = abi.Encode(abi.Tuple(abi.BigInt(big.NewInt(42))))
+
+	got, err := MatchTransferEvent(l)
+	tc.NoErr(t, err)
+	want := TransferEvent{
+		From:  [20]byte{19: 0x01},
+		To:    [20]byte{19: 0x02},
+		Value: big.NewInt(42),
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %+v got: %+v", want, got)
+	}
+	l.Data = l.Data[:31]
+	var derr *abi.DecodeError
+	if _, err := MatchTransferEvent(l); !errors.As(err, &derr) {
+		t.Errorf("expected DecodeError for truncated data. got: %v", err)
+	}
+	l.Topics[0][0]++
+	if _, err := MatchTransferEvent(l); !errors.Is(err, abi.ErrNoMatch) {
+		t.Errorf("want ErrNoMatch got: %v", err)
+	}
+}
+
+func TestMatchSignedEvent(t *testing.T) {
+	var l abi.Log
+	l.Topics[0] = isxhash.Keccak32([]byte("Signed(int256)"))
+	l.Data = abi.Encode(abi.Tuple(abi.Int(abit.Int256, big.NewInt(-42))))
+	got, err := MatchSignedEvent(l)
+	tc.NoErr(t, err)
+	if got.Delta.Int64() != -42 {
+		t.Errorf("want: -42 got: %s", got.Delta)
+	}
+}
+
+func TestMatchOrderEvent(t *testing.T) {
+	want := OrderEvent{
+		Id:   [32]byte{0xaa},
+		Memo: abi.TopicHash(isxhash.Keccak32([]byte("memo"))),
+		Fills: []OrderEventFills{
+			{
+				Maker:   [20]byte{0x01},
+				Amounts: []*big.Int{big.NewInt(1), big.NewInt(2)},
+				Meta:    OrderEventFillsMeta{Note: "first", Ok: true},
+			},
+			{
+				Maker:   [20]byte{0x02},
+				Amounts: []*big.Int{},
+				Meta:    OrderEventFillsMeta{Note: "second"},
+			},
+		},
+		Arg3: 7,
+	}
+	var fills []abi.Item
+	for _, f := range want.Fills {
+		fills = append(fills, f.abiItem())
+	}
+	var l abi.Log
+	l.Topics[0] = orderEventSignatureHash
+	l.Topics[1] = want.Id
+	l.Topics[2] = want.Memo
+	l.Data = abi.Encode(abi.Tuple(abi.List(fills...), abi.Uint8(want.Arg3)))
+
+	got, err := MatchOrderEvent(l)
+	tc.NoErr(t, err)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %+v got: %+v", want, got)
+	}
+	l.Data = l.Data[:len(l.Data)-32]
+	if _, err := MatchOrderEvent(l); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
+
+func TestTransferCall(t *testing.T) {
+	m, err := abi.ParseMethod("function transfer(address to, uint256 amount) returns (bool)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := TransferCall{To: [20]byte{0x01}, Amount: big.NewInt(1000)}
+	want, err := m.EncodeCall(abi.Address(x.To), abi.BigInt(x.Amount))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := EncodeTransferCall(x)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %x got: %x", want, got)
+	}
+	dec, err := DecodeTransferCall(got)
+	tc.NoErr(t, err)
+	if !reflect.DeepEqual(x, dec) {
+		t.Errorf("want: %+v got: %+v", x, dec)
+	}
+	if _, err := DecodeTransferCall(got[:40]); err == nil {
+		t.Error("expected error for truncated calldata")
+	}
+	if _, err := DecodeTransfer2Call(got); !errors.Is(err, abi.ErrNoMatch) {
+		t.Errorf("expected overload selector mismatch. got: %v", err)
+	}
+	res, err := DecodeTransferResult(abi.Encode(abi.Tuple(abi.Bool(true))))
+	tc.NoErr(t, err)
+	if !res.Out0 {
+		t.Error("expected true result")
+	}
+	if _, err := DecodeTransferResult(nil); err == nil {
+		t.Error("expected error for empty result")
+	}
+}
+
+func TestBatchCall(t *testing.T) {
+	want := BatchCall{
+		Ids:  []uint64{1, 2, 3},
+		Data: []byte("data"),
+		Pairs: []BatchCallPairs{
+			{A: [32]byte{0x01}, B: []string{"a", "b"}},
+			{A: [32]byte{0x02}, B: []string{}},
+		},
+	}
+	b := EncodeBatchCall(want)
+	got, err := DecodeBatchCall(b)
+	tc.NoErr(t, err)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %+v got: %+v", want, got)
+	}
+	// an offset past the end of the calldata
+	b[4+30] = 0xff
+	if _, err := DecodeBatchCall(b); err == nil {
+		t.Error("expected error for invalid offset")
+	}
+}
@@ -0,0 +1,580 @@
+// Generates Go bindings from an ABI JSON file. For each
+// event a struct and a Match function are generated. For
+// each function, structs for the call and result are
+// generated along with Encode and Decode functions.
+// The generated code uses the abi package's typed
+// accessors and does not use reflection.
+// Use it with go generate:
+//
+//	//go:generate go run github.com/indexsupply/x/cmd/genabi -pkg erc20 erc20.json
+//
+// Entries with types that aren't supported by abit
+// and anonymous events are skipped and noted in
+// the generated file.
+//
+// Logs, calldata and return data are untrusted so the
+// generated Match and Decode functions return an error
+// rather than panicking when they can't be decoded.
+// abi.ErrNoMatch is returned for a different event or method.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/indexsupply/x/abi"
+)
+
+func check(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "genabi: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	var pkg, out string
+	flag.StringVar(&pkg, "pkg", "", "package name of generated file. defaults to the directory name")
+	flag.StringVar(&out, "out", "", "name of generated file. defaults to <input>_abi.go")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		check(fmt.Errorf("usage: genabi [-pkg name] [-out file] abi.json"))
+	}
+	in := flag.Arg(0)
+	if pkg == "" {
+		abs, err := filepath.Abs(filepath.Dir(in))
+		check(err)
+		pkg = filepath.Base(abs)
+	}
+	if out == "" {
+		out = strings.TrimSuffix(in, filepath.Ext(in)) + "_abi.go"
+	}
+	js, err := os.ReadFile(in)
+	check(err)
+	src, err := generate(pkg, js)
+	check(err)
+	check(os.WriteFile(out, src, 0644))
+}
+
+// A JSON ABI entry. See:
+// https://docs.soliditylang.org/en/latest/abi-spec.html#json
+type entry struct {
+	Type            string
+	Name            string
+	Inputs          []abi.Input
+	Outputs         []abi.Input
+	Anonymous       bool
+	StateMutability string
+}
+
+type gen struct {
+	buf   bytes.Buffer
+	tmp   int
+	names map[string]int
+
+	// structs to generate for tuples
+	tuples []tuple
+}
+
+type tuple struct {
+	name   string
+	inputs []abi.Input
+}
+
+func (g *gen) p(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *gen) v(prefix string) string {
+	g.tmp++
+	return fmt.Sprintf("%s%d", prefix, g.tmp)
+}
+
+// Returns a unique, exported go name for s within
+// kind (event or function). Overloaded names get a
+// numeric suffix.
+func (g *gen) unique(kind, s string) string {
+	n := exported(s)
+	g.names[kind+n]++
+	if c := g.names[kind+n]; c > 1 {
+		return fmt.Sprintf("%s%d", n, c)
+	}
+	return n
+}
+
+func exported(s string) string {
+	s = strings.TrimLeft(s, "_")
+	if s == "" {
+		return ""
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// Returns the go name of each input. Unnamed
+// and duplicate inputs are given positional names.
+func fieldNames(inputs []abi.Input, prefix string) []string {
+	var (
+		names = make([]string, len(inputs))
+		seen  = map[string]bool{}
+	)
+	for i, inp := range inputs {
+		n := exported(inp.Name)
+		if n == "" || seen[n] {
+			n = fmt.Sprintf("%s%d", prefix, i)
+		}
+		seen[n] = true
+		names[i] = n
+	}
+	return names
+}
+
+// Returns an error if inp or any of its
+// components is not supported by the generator.
+func supported(inp abi.Input) error {
+	base := strings.TrimRight(inp.Type, "[]")
+	if base == "tuple" {
+		for _, c := range inp.Components {
+			if err := supported(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	switch base {
+	case "address", "bool", "bytes", "bytes32", "string", "uint8", "uint64", "uint256", "int256":
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %s", inp.Type)
+	}
+}
+
+func supportedAll(inputs ...[]abi.Input) error {
+	for _, l := range inputs {
+		for _, inp := range l {
+			if err := supported(inp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func generate(pkg string, js []byte) ([]byte, error) {
+	var entries []entry
+	if err := json.Unmarshal(js, &entries); err != nil {
+		return nil, fmt.Errorf("decoding abi json: %w", err)
+	}
+	g := &gen{names: map[string]int{}}
+	for _, e := range entries {
+		switch e.Type {
+		case "event":
+			ev := abi.Event{Name: e.Name, Type: e.Type, Inputs: e.Inputs}
+			if e.Anonymous {
+				g.p("")
+				g.p("// skipped %s: anonymous", ev.Signature())
+				continue
+			}
+			if err := supportedAll(e.Inputs); err != nil {
+				g.p("")
+				g.p("// skipped %s: %s", ev.Signature(), err)
+				continue
+			}
+			g.event(g.unique(e.Type, e.Name), ev)
+		case "function":
+			m := abi.Method{Name: e.Name, Type: e.Type, Inputs: e.Inputs, Outputs: e.Outputs}
+			if err := supportedAll(e.Inputs, e.Outputs); err != nil {
+				g.p("")
+				g.p("// skipped %s: %s", m.Signature(), err)
+				continue
+			}
+			g.method(g.unique(e.Type, e.Name), m)
+		}
+	}
+	// generating a tuple may add nested tuples
+	for i := 0; i < len(g.tuples); i++ {
+		g.tuple(g.tuples[i])
+	}
+	body := g.buf.String()
+	g.buf.Reset()
+	g.p("// Code generated by genabi. DO NOT EDIT.")
+	g.p("")
+	g.p("package %s", pkg)
+	g.p("")
+	g.p("import (")
+	if strings.Contains(body, "big.") {
+		g.p(`"math/big"`)
+		g.p("")
+	}
+	g.p(`"github.com/indexsupply/x/abi"`)
+	if strings.Contains(body, "abit.") {
+		g.p(`"github.com/indexsupply/x/abi/abit"`)
+	}
+	g.p(")")
+	g.buf.WriteString(body)
+	return format.Source(g.buf.Bytes())
+}
+
+// Returns the go type of inp. Tuples are added to g.tuples
+// and named by appending the field name to parent.
+func (g *gen) goType(inp abi.Input, parent, field string) string {
+	var (
+		base = strings.TrimRight(inp.Type, "[]")
+		dims = strings.Count(inp.Type[len(base):], "[]")
+		t    string
+	)
+	switch base {
+	case "tuple":
+		t = parent + field
+		g.tuples = append(g.tuples, tuple{name: t, inputs: inp.Components})
+	case "address":
+		t = "[20]byte"
+	case "bool":
+		t = "bool"
+	case "bytes":
+		t = "[]byte"
+	case "bytes32":
+		t = "[32]byte"
+	case "string":
+		t = "string"
+	case "uint8":
+		t = "uint8"
+	case "uint64":
+		t = "uint64"
+	case "uint256", "int256":
+		t = "*big.Int"
+	}
+	return strings.Repeat("[]", dims) + t
+}
+
+// Returns an expression for inp's abit.Type
+func abitType(inp abi.Input) string {
+	var (
+		base = strings.TrimRight(inp.Type, "[]")
+		dims = strings.Count(inp.Type[len(base):], "[]")
+		t    string
+	)
+	switch base {
+	case "tuple":
+		var fields []string
+		for _, c := range inp.Components {
+			fields = append(fields, abitType(c))
+		}
+		t = "abit.Tuple(" + strings.Join(fields, ", ") + ")"
+	default:
+		t = "abit." + map[string]string{
+			"address": "Address",
+			"bool":    "Bool",
+			"bytes":   "Bytes",
+			"bytes32": "Bytes32",
+			"string":  "String",
+			"uint8":   "Uint8",
+			"uint64":  "Uint64",
+			"uint256": "Uint256",
+			"int256":  "Int256",
+		}[base]
+	}
+	for i := 0; i < dims; i++ {
+		t = "abit.List(" + t + ")"
+	}
+	return t
+}
+
+func abitTuple(inputs []abi.Input) string {
+	var types []string
+	for _, inp := range inputs {
+		types = append(types, abitType(inp))
+	}
+	return "abit.Tuple(" + strings.Join(types, ", ") + ")"
+}
+
+func (g *gen) fields(name string, inputs []abi.Input, prefix string) {
+	names := fieldNames(inputs, prefix)
+	g.p("")
+	g.p("type %s struct {", name)
+	for i, inp := range inputs {
+		g.p("%s %s", names[i], g.goType(inp, name, names[i]))
+	}
+	g.p("}")
+}
+
+func (g *gen) event(name string, e abi.Event) {
+	var (
+		st     = name + "Event"
+		names  = fieldNames(e.Inputs, "Arg")
+		hash   = e.SignatureHash()
+		data   []abi.Input
+		topics []string // go names of indexed fields
+	)
+	for i, inp := range e.Inputs {
+		if inp.Indexed {
+			topics = append(topics, names[i])
+			continue
+		}
+		data = append(data, inp)
+	}
+	if len(topics) > 3 {
+		g.p("")
+		g.p("// skipped %s: more than 3 indexed inputs", e.Signature())
+		return
+	}
+	g.p("")
+	g.p("// %s", e.Signature())
+	g.p("var %sSignatureHash = %s", unexported(st), byteLit(hash[:]))
+	g.p("")
+	for _, inp := range e.Inputs {
+		if inp.Indexed && !static(inp) {
+			g.p("// Indexed inputs with dynamic types are the keccak hash of their value.")
+			break
+		}
+	}
+	g.p("type %s struct {", st)
+	for i, inp := range e.Inputs {
+		if inp.Indexed && !static(inp) {
+			g.p("%s abi.TopicHash", names[i])
+			continue
+		}
+		g.p("%s %s", names[i], g.goType(inp, st, names[i]))
+	}
+	g.p("}")
+	g.p("")
+	g.p("// Returns abi.ErrNoMatch if l's first topic isn't the signature hash of %s", e.Signature())
+	g.p("func Match%s(l abi.Log) (%s, error) {", st, st)
+	g.p("var x %s", st)
+	g.p("if l.Topics[0] != %sSignatureHash {", unexported(st))
+	g.p("return x, abi.ErrNoMatch")
+	g.p("}")
+	if len(data) > 0 {
+		g.p("it, err := abi.DecodeChecked(l.Data, %s)", abitTuple(data))
+		g.p("if err != nil {")
+		g.p("return x, err")
+		g.p("}")
+	}
+	var d, t int
+	for i, inp := range e.Inputs {
+		dst := "x." + names[i]
+		switch {
+		case inp.Indexed && !static(inp):
+			g.p("%s = abi.TopicHash(l.Topics[%d])", dst, t+1)
+			t++
+		case inp.Indexed:
+			g.decode(dst, fmt.Sprintf("abi.Bytes(l.Topics[%d][:])", t+1), inp, st, names[i])
+			t++
+		default:
+			g.decode(dst, fmt.Sprintf("it.At(%d)", d), inp, st, names[i])
+			d++
+		}
+	}
+	g.p("return x, nil")
+	g.p("}")
+}
+
+func (g *gen) method(name string, m abi.Method) {
+	var (
+		call = name + "Call"
+		res  = name + "Result"
+		sel  = m.Selector()
+	)
+	g.p("")
+	g.p("// %s", m.Signature())
+	g.p("var %sSelector = %s", unexported(name), byteLit(sel[:]))
+	g.fields(call, m.Inputs, "Arg")
+	g.p("")
+	g.p("// Returns the calldata for %s", m.Signature())
+	g.p("func Encode%s(x %s) []byte {", call, call)
+	g.encodeTuple("x", m.Inputs, call, "Arg")
+	g.p("return append(%sSelector[:], abi.Encode(it)...)", unexported(name))
+	g.p("}")
+	g.p("")
+	g.p("// Returns abi.ErrNoMatch if b doesn't start with the selector for %s", m.Signature())
+	g.p("func Decode%s(b []byte) (%s, error) {", call, call)
+	g.p("var x %s", call)
+	g.p("if len(b) < 4 || *(*[4]byte)(b[:4]) != %sSelector {", unexported(name))
+	g.p("return x, abi.ErrNoMatch")
+	g.p("}")
+	g.decodeTuple("x", "b[4:]", m.Inputs, call, "Arg")
+	g.p("return x, nil")
+	g.p("}")
+
+	if len(m.Outputs) == 0 {
+		return
+	}
+	g.fields(res, m.Outputs, "Out")
+	g.p("")
+	g.p("// Decodes the data returned by %s", m.Signature())
+	g.p("func Decode%s(b []byte) (%s, error) {", res, res)
+	g.p("var x %s", res)
+	g.decodeTuple("x", "b", m.Outputs, res, "Out")
+	g.p("return x, nil")
+	g.p("}")
+}
+
+func (g *gen) tuple(t tuple) {
+	g.fields(t.name, t.inputs, "Arg")
+	g.p("")
+	g.p("func (x %s) abiItem() abi.Item {", t.name)
+	g.encodeTuple("x", t.inputs, t.name, "Arg")
+	g.p("return it")
+	g.p("}")
+	g.p("")
+	g.p("func decode%s(it abi.Item) %s {", t.name, t.name)
+	g.p("var x %s", t.name)
+	names := fieldNames(t.inputs, "Arg")
+	for i, inp := range t.inputs {
+		g.decode("x."+names[i], fmt.Sprintf("it.At(%d)", i), inp, t.name, names[i])
+	}
+	g.p("return x")
+	g.p("}")
+}
+
+// Assigns the abi.Tuple for src's fields to it
+func (g *gen) encodeTuple(src string, inputs []abi.Input, parent, prefix string) {
+	names := fieldNames(inputs, prefix)
+	if len(inputs) == 0 {
+		g.p("it := abi.Tuple()")
+		return
+	}
+	g.p("it := abi.Tuple(")
+	for i, inp := range inputs {
+		g.p("%s,", g.encode(src+"."+names[i], inp, parent, names[i]))
+	}
+	g.p(")")
+}
+
+func (g *gen) decodeTuple(dst, src string, inputs []abi.Input, parent, prefix string) {
+	if len(inputs) == 0 {
+		return
+	}
+	it := g.v("it")
+	g.p("%s, err := abi.DecodeChecked(%s, %s)", it, src, abitTuple(inputs))
+	g.p("if err != nil {")
+	g.p("return x, err")
+	g.p("}")
+	names := fieldNames(inputs, prefix)
+	for i, inp := range inputs {
+		g.decode(dst+"."+names[i], fmt.Sprintf("%s.At(%d)", it, i), inp, parent, names[i])
+	}
+}
+
+func static(inp abi.Input) bool {
+	switch inp.Type {
+	case "address", "bool", "bytes32", "uint8", "uint64", "uint256", "int256":
+		return true
+	}
+	return false
+}
+
+// Returns an expression that converts src to an abi.Item
+func (g *gen) encode(src string, inp abi.Input, parent, field string) string {
+	if strings.HasSuffix(inp.Type, "[]") {
+		var (
+			elem = inp
+			i    = g.v("i")
+			l    = g.v("l")
+		)
+		elem.Type = strings.TrimSuffix(inp.Type, "[]")
+		return fmt.Sprintf(
+			"func() abi.Item { %s := make([]abi.Item, len(%s)); for %s := range %s { %s[%s] = %s }; return abi.ListOf(%s, %s...) }()",
+			l, src, i, src, l, i, g.encode(src+"["+i+"]", elem, parent, field),
+			abitType(elem), l,
+		)
+	}
+	switch inp.Type {
+	case "tuple":
+		return src + ".abiItem()"
+	case "address":
+		return "abi.Address(" + src + ")"
+	case "bool":
+		return "abi.Bool(" + src + ")"
+	case "bytes":
+		return "abi.Bytes(" + src + ")"
+	case "bytes32":
+		return "abi.Bytes32(" + src + ")"
+	case "string":
+		return "abi.String(" + src + ")"
+	case "uint8":
+		return "abi.Uint8(" + src + ")"
+	case "uint64":
+		return "abi.Uint64(" + src + ")"
+	case "int256":
+		return "abi.Int(abit.Int256, " + src + ")"
+	default:
+		return "abi.BigInt(" + src + ")"
+	}
+}
+
+// Writes statements that assign the abi.Item src to dst
+func (g *gen) decode(dst, src string, inp abi.Input, parent, field string) {
+	if strings.HasSuffix(inp.Type, "[]") {
+		var (
+			elem = inp
+			l    = g.v("l")
+			i    = g.v("i")
+		)
+		elem.Type = strings.TrimSuffix(inp.Type, "[]")
+		g.p("%s := %s", l, src)
+		g.p("%s = make(%s, %s.Len())", dst, g.typeOnly(inp, parent, field), l)
+		g.p("for %s := range %s {", i, dst)
+		g.decode(dst+"["+i+"]", l+".At("+i+")", elem, parent, field)
+		g.p("}")
+		return
+	}
+	switch inp.Type {
+	case "tuple":
+		g.p("%s = decode%s%s(%s)", dst, parent, field, src)
+	case "address":
+		g.p("%s = %s.Address()", dst, src)
+	case "bool":
+		g.p("%s = %s.Bool()", dst, src)
+	case "bytes":
+		g.p("%s = %s.Bytes()", dst, src)
+	case "bytes32":
+		g.p("copy(%s[:], %s.Bytes())", dst, src)
+	case "string":
+		g.p("%s = %s.String()", dst, src)
+	case "uint8":
+		g.p("%s = %s.Uint8()", dst, src)
+	case "uint64":
+		g.p("%s = %s.Uint64()", dst, src)
+	case "int256":
+		g.p("%s = %s.Int()", dst, src)
+	default:
+		g.p("%s = %s.BigInt()", dst, src)
+	}
+}
+
+// Like goType but doesn't register tuples
+func (g *gen) typeOnly(inp abi.Input, parent, field string) string {
+	n := len(g.tuples)
+	t := g.goType(inp, parent, field)
+	g.tuples = g.tuples[:n]
+	return t
+}
+
+func byteLit(b []byte) string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "[%d]byte{", len(b))
+	for i := range b {
+		if i > 0 {
+			s.WriteString(", ")
+		}
+		fmt.Fprintf(&s, "0x%02x", b[i])
+	}
+	s.WriteString("}")
+	return s.String()
+}
+
+func unexported(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
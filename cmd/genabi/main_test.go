@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join("internal", "testabi")
+	js, err := os.ReadFile(filepath.Join(dir, "test.json"))
+	tc.NoErr(t, err)
+	got, err := generate("testabi", js)
+	tc.NoErr(t, err)
+	want, err := os.ReadFile(filepath.Join(dir, "test_abi.go"))
+	tc.NoErr(t, err)
+	if !bytes.Equal(want, got) {
+		t.Error("testabi/test_abi.go is stale. run go generate ./...")
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	if _, err := generate("x", []byte(`{"type": "event"}`)); err == nil {
+		t.Error("expected error for non-array abi")
+	}
+}
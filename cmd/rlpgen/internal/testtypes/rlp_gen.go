@@ -0,0 +1,324 @@
+// Code generated by rlpgen. DO NOT EDIT.
+
+package testtypes
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/indexsupply/x/rlp"
+)
+
+func (x *Header) MarshalRLP() ([]byte, error) {
+	it, err := x.rlpItem()
+	if err != nil {
+		return nil, err
+	}
+	return rlp.Encode(it), nil
+}
+
+func (x *Header) UnmarshalRLP(b []byte) error {
+	it, err := rlp.Decode(b)
+	if err != nil {
+		return err
+	}
+	return x.rlpDecode(it)
+}
+
+func (x *Header) rlpItem() (rlp.Item, error) {
+	items := make([]rlp.Item, 15)
+	items[0] = rlp.Bytes(x.Parent[:])
+	items[1] = rlp.Uint64(uint64(x.Number))
+	items[2] = rlp.Uint64(uint64(x.Gas))
+	items[3] = rlp.Bytes(x.Extra)
+	b1, err := rlpgenBigInt(x.Diff)
+	if err != nil {
+		return rlp.Item{}, fmt.Errorf("field Diff: %w", err)
+	}
+	items[4] = b1
+	b2, err := rlpgenBigInt(&x.Total)
+	if err != nil {
+		return rlp.Item{}, fmt.Errorf("field Total: %w", err)
+	}
+	items[5] = b2
+	items[6] = rlp.Bool(x.Final)
+	items[7] = rlp.String(x.Name)
+	s3, err := x.Nested.rlpItem()
+	if err != nil {
+		return rlp.Item{}, fmt.Errorf("field Nested: %w", err)
+	}
+	items[8] = s3
+	l4 := make([]rlp.Item, len(x.Nums))
+	for i5 := range x.Nums {
+		l4[i5] = rlp.Uint64(uint64(x.Nums[i5]))
+	}
+	items[9] = rlp.List(l4...)
+	l6 := make([]rlp.Item, len(x.Hashes))
+	for i7 := range x.Hashes {
+		l6[i7] = rlp.Bytes(x.Hashes[i7][:])
+	}
+	items[10] = rlp.List(l6...)
+	items[11] = rlp.Bytes(x.Pair[:])
+	items[12] = x.Raw
+	b8, err := rlpgenBigInt(x.Base)
+	if err != nil {
+		return rlp.Item{}, fmt.Errorf("field Base: %w", err)
+	}
+	items[13] = b8
+	items[14] = rlp.Uint64(uint64(x.Blob))
+	n := 15
+	if n == 15 && x.Blob == 0 {
+		n = 14
+	}
+	if n == 14 && x.Base == nil {
+		n = 13
+	}
+	return rlp.List(items[:n]...), nil
+}
+
+func (x *Header) rlpDecode(it rlp.Item) error {
+	l, err := rlpgenList(it)
+	if err != nil {
+		return err
+	}
+	if len(l) < 13 || len(l) > 15 {
+		return fmt.Errorf("rlp: expected list of 15 for Header. got %d", len(l))
+	}
+	b9, err := rlpgenBytes(l[0])
+	if err != nil {
+		return fmt.Errorf("field Parent: %w", err)
+	}
+	if len(b9) != 32 {
+		return fmt.Errorf("field Parent: expected 32 bytes. got %d", len(b9))
+	}
+	copy(x.Parent[:], b9)
+	n10, err := rlpgenUint(l[1], 8)
+	if err != nil {
+		return fmt.Errorf("field Number: %w", err)
+	}
+	x.Number = uint64(n10)
+	n11, err := rlpgenUint(l[2], 4)
+	if err != nil {
+		return fmt.Errorf("field Gas: %w", err)
+	}
+	x.Gas = uint32(n11)
+	b12, err := rlpgenBytes(l[3])
+	if err != nil {
+		return fmt.Errorf("field Extra: %w", err)
+	}
+	x.Extra = append([]byte{}, b12...)
+	b13, err := rlpgenDecodeBigInt(l[4])
+	if err != nil {
+		return fmt.Errorf("field Diff: %w", err)
+	}
+	x.Diff = b13
+	b14, err := rlpgenDecodeBigInt(l[5])
+	if err != nil {
+		return fmt.Errorf("field Total: %w", err)
+	}
+	x.Total.Set(b14)
+	b15, err := l[6].Bool()
+	if err != nil {
+		return fmt.Errorf("field Final: %w", err)
+	}
+	x.Final = b15
+	b16, err := rlpgenBytes(l[7])
+	if err != nil {
+		return fmt.Errorf("field Name: %w", err)
+	}
+	x.Name = string(b16)
+	if err := x.Nested.rlpDecode(l[8]); err != nil {
+		return fmt.Errorf("field Nested: %w", err)
+	}
+	l17, err := rlpgenList(l[9])
+	if err != nil {
+		return fmt.Errorf("field Nums: %w", err)
+	}
+	x.Nums = make([]uint16, len(l17))
+	for i18 := range l17 {
+		n19, err := rlpgenUint(l17[i18], 2)
+		if err != nil {
+			return fmt.Errorf("field Nums: %w", err)
+		}
+		x.Nums[i18] = uint16(n19)
+	}
+	l20, err := rlpgenList(l[10])
+	if err != nil {
+		return fmt.Errorf("field Hashes: %w", err)
+	}
+	x.Hashes = make([][32]byte, len(l20))
+	for i21 := range l20 {
+		b22, err := rlpgenBytes(l20[i21])
+		if err != nil {
+			return fmt.Errorf("field Hashes: %w", err)
+		}
+		if len(b22) != 32 {
+			return fmt.Errorf("field Hashes: expected 32 bytes. got %d", len(b22))
+		}
+		copy(x.Hashes[i21][:], b22)
+	}
+	b23, err := rlpgenBytes(l[11])
+	if err != nil {
+		return fmt.Errorf("field Pair: %w", err)
+	}
+	if len(b23) != 2 {
+		return fmt.Errorf("field Pair: expected 2 bytes. got %d", len(b23))
+	}
+	copy(x.Pair[:], b23)
+	x.Raw = l[12]
+	if len(l) > 13 {
+		b24, err := rlpgenDecodeBigInt(l[13])
+		if err != nil {
+			return fmt.Errorf("field Base: %w", err)
+		}
+		x.Base = b24
+	} else {
+		x.Base = nil
+	}
+	if len(l) > 14 {
+		n25, err := rlpgenUint(l[14], 8)
+		if err != nil {
+			return fmt.Errorf("field Blob: %w", err)
+		}
+		x.Blob = uint64(n25)
+	} else {
+		x.Blob = 0
+	}
+	return nil
+}
+
+func (x *Nested) MarshalRLP() ([]byte, error) {
+	it, err := x.rlpItem()
+	if err != nil {
+		return nil, err
+	}
+	return rlp.Encode(it), nil
+}
+
+func (x *Nested) UnmarshalRLP(b []byte) error {
+	it, err := rlp.Decode(b)
+	if err != nil {
+		return err
+	}
+	return x.rlpDecode(it)
+}
+
+func (x *Nested) rlpItem() (rlp.Item, error) {
+	items := make([]rlp.Item, 3)
+	l26 := make([]rlp.Item, len(x.A))
+	for i27 := range x.A {
+		l26[i27] = rlp.Uint64(uint64(x.A[i27]))
+	}
+	items[0] = rlp.List(l26...)
+	s28 := rlp.List()
+	if x.B != nil {
+		var err error
+		s28, err = x.B.rlpItem()
+		if err != nil {
+			return rlp.Item{}, fmt.Errorf("field B: %w", err)
+		}
+	}
+	items[1] = s28
+	l29 := make([]rlp.Item, len(x.List))
+	for i30 := range x.List {
+		s31, err := x.List[i30].rlpItem()
+		if err != nil {
+			return rlp.Item{}, fmt.Errorf("field List: %w", err)
+		}
+		l29[i30] = s31
+	}
+	items[2] = rlp.List(l29...)
+	return rlp.List(items...), nil
+}
+
+func (x *Nested) rlpDecode(it rlp.Item) error {
+	l, err := rlpgenList(it)
+	if err != nil {
+		return err
+	}
+	if len(l) != 3 {
+		return fmt.Errorf("rlp: expected list of 3 for Nested. got %d", len(l))
+	}
+	l32, err := rlpgenList(l[0])
+	if err != nil {
+		return fmt.Errorf("field A: %w", err)
+	}
+	if len(l32) != 2 {
+		return fmt.Errorf("field A: expected list of 2. got %d", len(l32))
+	}
+	for i33 := range l32 {
+		n34, err := rlpgenUint(l32[i33], 2)
+		if err != nil {
+			return fmt.Errorf("field A: %w", err)
+		}
+		x.A[i33] = uint16(n34)
+	}
+	x.B = nil
+	if l35 := l[1].List(); l35 == nil || len(l35) != 0 {
+		x.B = new(Nested)
+		if err := x.B.rlpDecode(l[1]); err != nil {
+			return fmt.Errorf("field B: %w", err)
+		}
+	}
+	l36, err := rlpgenList(l[2])
+	if err != nil {
+		return fmt.Errorf("field List: %w", err)
+	}
+	x.List = make([]Nested, len(l36))
+	for i37 := range l36 {
+		if err := x.List[i37].rlpDecode(l36[i37]); err != nil {
+			return fmt.Errorf("field List: %w", err)
+		}
+	}
+	return nil
+}
+
+func rlpgenBytes(it rlp.Item) ([]byte, error) {
+	if it.List() != nil {
+		return nil, errors.New("rlp: expected bytes. got list")
+	}
+	return it.Bytes(), nil
+}
+
+func rlpgenList(it rlp.Item) ([]rlp.Item, error) {
+	if it.List() == nil {
+		return nil, errors.New("rlp: expected list. got bytes")
+	}
+	return it.List(), nil
+}
+
+func rlpgenUint(it rlp.Item, size int) (uint64, error) {
+	b, err := rlpgenBytes(it)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) > size {
+		return 0, fmt.Errorf("rlp: %d bytes overflows uint%d", len(b), size*8)
+	}
+	if len(b) > 0 && b[0] == 0 {
+		return 0, errors.New("rlp: non-canonical integer with leading zero")
+	}
+	return it.Uint64()
+}
+
+func rlpgenBigInt(n *big.Int) (rlp.Item, error) {
+	if n == nil {
+		return rlp.Bytes(nil), nil
+	}
+	if n.Sign() < 0 {
+		return rlp.Item{}, errors.New("rlp: cannot marshal negative big.Int")
+	}
+	return rlp.Bytes(n.Bytes()), nil
+}
+
+func rlpgenDecodeBigInt(it rlp.Item) (*big.Int, error) {
+	b, err := rlpgenBytes(it)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > 0 && b[0] == 0 {
+		return nil, errors.New("rlp: non-canonical integer with leading zero")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
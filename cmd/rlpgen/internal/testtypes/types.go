@@ -0,0 +1,38 @@
+// Types used to test the code generated by rlpgen
+package testtypes
+
+import (
+	"math/big"
+
+	"github.com/indexsupply/x/rlp"
+)
+
+//go:generate go run github.com/indexsupply/x/cmd/rlpgen
+
+//rlp:gen
+type Header struct {
+	Parent  [32]byte
+	Number  uint64
+	Gas     uint32
+	Extra   []byte
+	Diff    *big.Int
+	Total   big.Int
+	Final   bool
+	Name    string
+	Nested  Nested
+	Nums    []uint16
+	Hashes  [][32]byte
+	Pair    [2]uint8
+	Raw     rlp.Item
+	Ignored string `rlp:"-"`
+	private int
+	Base    *big.Int `rlp:"optional"`
+	Blob    uint64   `rlp:"optional"`
+}
+
+//rlp:gen
+type Nested struct {
+	A    [2]uint16
+	B    *Nested
+	List []Nested
+}
@@ -0,0 +1,111 @@
+package testtypes
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/indexsupply/x/rlp"
+	"github.com/indexsupply/x/tc"
+)
+
+func testHeader() Header {
+	h := Header{
+		Parent: [32]byte{1},
+		Number: 1024,
+		Extra:  []byte("extra"),
+		Diff:   big.NewInt(17179869184),
+		Final:  true,
+		Name:   "foo",
+		Nested: Nested{
+			A:    [2]uint16{1, 256},
+			B:    &Nested{A: [2]uint16{3, 4}, List: []Nested{}},
+			List: []Nested{{List: []Nested{}}},
+		},
+		Nums:   []uint16{1, 2, 3},
+		Hashes: [][32]byte{{2}, {3}},
+		Pair:   [2]uint8{5, 6},
+		Raw:    rlp.List(rlp.String("raw")),
+	}
+	h.Total.SetUint64(42)
+	return h
+}
+
+func TestMarshalRLP(t *testing.T) {
+	h := testHeader()
+	got, err := h.MarshalRLP()
+	tc.NoErr(t, err)
+	want, err := rlp.Marshal(h)
+	tc.NoErr(t, err)
+	if !bytes.Equal(want, got) {
+		t.Errorf("want:\n%x\ngot:\n%x", want, got)
+	}
+
+	var (
+		fromGen Header
+		fromRef Header
+	)
+	tc.NoErr(t, fromGen.UnmarshalRLP(got))
+	tc.NoErr(t, rlp.Unmarshal(got, &fromRef))
+	if !reflect.DeepEqual(fromRef, fromGen) {
+		t.Errorf("want:\n%#v\ngot:\n%#v", fromRef, fromGen)
+	}
+	h.Ignored = ""
+	if d := rlp.Diff(h.Raw, fromGen.Raw); d != "" {
+		t.Errorf("raw %s", d)
+	}
+	h.Raw = fromGen.Raw
+	if !reflect.DeepEqual(h, fromGen) {
+		t.Errorf("want:\n%#v\ngot:\n%#v", h, fromGen)
+	}
+}
+
+func TestUnmarshalRLP_Errors(t *testing.T) {
+	cases := []rlp.Item{
+		rlp.String("not a list"),
+		rlp.List(rlp.Uint64(1)),
+		rlp.List(
+			rlp.List(rlp.Bytes([]byte{0, 1}), rlp.Uint64(1)),
+			rlp.List(),
+			rlp.List(),
+		),
+		rlp.List(
+			rlp.List(rlp.Bytes([]byte{1, 2, 3}), rlp.Uint64(1)),
+			rlp.List(),
+			rlp.List(),
+		),
+	}
+	for _, c := range cases {
+		var n Nested
+		if err := n.UnmarshalRLP(rlp.Encode(c)); err == nil {
+			t.Errorf("expected error for %x", rlp.Encode(c))
+		}
+	}
+}
+
+func TestMarshalRLP_Optional(t *testing.T) {
+	for _, blob := range []uint64{0, 1} {
+		h := testHeader()
+		h.Blob = blob
+		got, err := h.MarshalRLP()
+		tc.NoErr(t, err)
+		want, err := rlp.Marshal(h)
+		tc.NoErr(t, err)
+		if !bytes.Equal(want, got) {
+			t.Errorf("want:\n%x\ngot:\n%x", want, got)
+		}
+		var (
+			fromGen = Header{Base: big.NewInt(1), Blob: 2}
+			fromRef Header
+		)
+		tc.NoErr(t, fromGen.UnmarshalRLP(got))
+		tc.NoErr(t, rlp.Unmarshal(got, &fromRef))
+		if !reflect.DeepEqual(fromRef, fromGen) {
+			t.Errorf("want:\n%#v\ngot:\n%#v", fromRef, fromGen)
+		}
+		if fromGen.Blob != blob {
+			t.Errorf("want: %d got: %d", blob, fromGen.Blob)
+		}
+	}
+}
@@ -0,0 +1,621 @@
+// Generates MarshalRLP and UnmarshalRLP methods for structs
+// annotated with //rlp:gen. The generated code follows the
+// same mapping as rlp.Marshal and rlp.Unmarshal but avoids
+// reflection. Use it with go generate:
+//
+//	//go:generate go run github.com/indexsupply/x/cmd/rlpgen
+//
+//	//rlp:gen
+//	type Header struct {
+//		Parent [32]byte
+//		Number uint64
+//		...
+//	}
+//
+// Supported field types are: bool, uint8 - uint64, uint, string,
+// []byte, [N]byte, big.Int, *big.Int, rlp.Item, annotated structs
+// from the same package (or pointers to them) and slices or arrays
+// of supported types. Fields tagged with `rlp:"-"` are skipped.
+// Trailing fields may be tagged with `rlp:"optional"`.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const annotation = "//rlp:gen"
+
+func check(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rlpgen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	var out string
+	flag.StringVar(&out, "out", "rlp_gen.go", "name of generated file")
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	src, err := generate(dir, out)
+	check(err)
+	check(os.WriteFile(filepath.Join(dir, out), src, 0644))
+}
+
+// Parses the non-test files in dir (excluding out)
+// and returns the formatted source for the
+// annotated structs.
+func generate(dir, out string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != out
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected 1 package in %s. got %d", dir, len(pkgs))
+	}
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+	g := &gen{pkg: pkg.Name, structs: map[string]*ast.StructType{}}
+	var fileNames []string
+	for name := range pkg.Files {
+		fileNames = append(fileNames, name)
+	}
+	sort.Strings(fileNames)
+	for _, name := range fileNames {
+		for _, decl := range pkg.Files[name].Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if !annotated(gd.Doc) && !annotated(ts.Doc) {
+					continue
+				}
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					return nil, fmt.Errorf("%s is annotated but is not a struct", ts.Name.Name)
+				}
+				g.names = append(g.names, ts.Name.Name)
+				g.structs[ts.Name.Name] = st
+			}
+		}
+	}
+	if len(g.names) == 0 {
+		return nil, fmt.Errorf("no structs annotated with %s in %s", annotation, dir)
+	}
+	return g.source()
+}
+
+func annotated(cg *ast.CommentGroup) bool {
+	if cg == nil {
+		return false
+	}
+	for _, c := range cg.List {
+		if strings.TrimSpace(c.Text) == annotation {
+			return true
+		}
+	}
+	return false
+}
+
+type kind int
+
+const (
+	kBool kind = iota
+	kUint
+	kString
+	kBytes
+	kByteArray
+	kBigInt
+	kItem
+	kStruct
+	kSlice
+	kArray
+)
+
+type typ struct {
+	kind kind
+	name string // go type name for kUint and kStruct
+	size int    // bytes for kUint. length for kByteArray and kArray
+	ptr  bool   // for kBigInt and kStruct
+	elem *typ   // for kSlice and kArray
+}
+
+type field struct {
+	name     string
+	t        *typ
+	optional bool
+}
+
+type gen struct {
+	pkg     string
+	names   []string
+	structs map[string]*ast.StructType
+
+	buf bytes.Buffer
+	tmp int
+}
+
+func (g *gen) p(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *gen) v(prefix string) string {
+	g.tmp++
+	return fmt.Sprintf("%s%d", prefix, g.tmp)
+}
+
+var uintSizes = map[string]int{
+	"uint8":  1,
+	"byte":   1,
+	"uint16": 2,
+	"uint32": 4,
+	"uint64": 8,
+	"uint":   8,
+}
+
+func (g *gen) parse(e ast.Expr) (*typ, error) {
+	switch e := e.(type) {
+	case *ast.Ident:
+		switch e.Name {
+		case "bool":
+			return &typ{kind: kBool}, nil
+		case "string":
+			return &typ{kind: kString}, nil
+		}
+		if n, ok := uintSizes[e.Name]; ok {
+			return &typ{kind: kUint, name: e.Name, size: n}, nil
+		}
+		if _, ok := g.structs[e.Name]; ok {
+			return &typ{kind: kStruct, name: e.Name}, nil
+		}
+		return nil, fmt.Errorf("unsupported type %s. structs must be annotated with %s", e.Name, annotation)
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		switch {
+		case ok && x.Name == "big" && e.Sel.Name == "Int":
+			return &typ{kind: kBigInt}, nil
+		case ok && x.Name == "rlp" && e.Sel.Name == "Item":
+			return &typ{kind: kItem}, nil
+		}
+	case *ast.StarExpr:
+		t, err := g.parse(e.X)
+		if err != nil {
+			return nil, err
+		}
+		if t.kind != kBigInt && t.kind != kStruct {
+			break
+		}
+		t.ptr = true
+		return t, nil
+	case *ast.ArrayType:
+		elem, err := g.parse(e.Elt)
+		if err != nil {
+			return nil, err
+		}
+		isByte := elem.kind == kUint && elem.size == 1
+		if e.Len == nil {
+			if isByte {
+				return &typ{kind: kBytes}, nil
+			}
+			return &typ{kind: kSlice, elem: elem}, nil
+		}
+		lit, ok := e.Len.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return nil, errors.New("array length must be an integer literal")
+		}
+		n, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			return nil, err
+		}
+		if isByte {
+			return &typ{kind: kByteArray, size: n}, nil
+		}
+		return &typ{kind: kArray, size: n, elem: elem}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", e)
+}
+
+// Returns the exported fields of st that are
+// not tagged with `rlp:"-"`. Fields tagged with
+// `rlp:"optional"` must not precede required fields.
+func (g *gen) fields(st *ast.StructType) ([]field, error) {
+	var (
+		res      []field
+		optional bool
+	)
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			return nil, errors.New("embedded fields are not supported")
+		}
+		var tag string
+		if f.Tag != nil {
+			s, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(s).Get("rlp")
+		}
+		if hasTag(tag, "-") {
+			continue
+		}
+		for _, n := range f.Names {
+			if !n.IsExported() {
+				continue
+			}
+			switch {
+			case hasTag(tag, "optional"):
+				optional = true
+			case optional:
+				return nil, fmt.Errorf("field %s must be optional since it follows an optional field", n.Name)
+			}
+			t, err := g.parse(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", n.Name, err)
+			}
+			if optional && zero(t) == "" {
+				return nil, fmt.Errorf("field %s: optional is not supported for %s", n.Name, g.goType(t))
+			}
+			res = append(res, field{name: n.Name, t: t, optional: optional})
+		}
+	}
+	return res, nil
+}
+
+// Returns the zero value literal for optional fields. Returns
+// an empty string for types that can't be optional.
+func zero(t *typ) string {
+	switch {
+	case t.ptr, t.kind == kBytes, t.kind == kSlice:
+		return "nil"
+	case t.kind == kBool:
+		return "false"
+	case t.kind == kUint:
+		return "0"
+	case t.kind == kString:
+		return `""`
+	case t.kind == kByteArray:
+		return fmt.Sprintf("[%d]byte{}", t.size)
+	default:
+		return ""
+	}
+}
+
+func hasTag(tag, opt string) bool {
+	for _, s := range strings.Split(tag, ",") {
+		if strings.TrimSpace(s) == opt {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *gen) source() ([]byte, error) {
+	g.p("// Code generated by rlpgen. DO NOT EDIT.")
+	g.p("")
+	g.p("package %s", g.pkg)
+	g.p("")
+	g.p("import (")
+	g.p(`"errors"`)
+	g.p(`"fmt"`)
+	g.p(`"math/big"`)
+	g.p("")
+	g.p(`"github.com/indexsupply/x/rlp"`)
+	g.p(")")
+	for _, name := range g.names {
+		fs, err := g.fields(g.structs[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		g.methods(name, fs)
+	}
+	g.buf.WriteString(helpers)
+	return format.Source(g.buf.Bytes())
+}
+
+func (g *gen) methods(name string, fs []field) {
+	g.p("")
+	g.p("func (x *%s) MarshalRLP() ([]byte, error) {", name)
+	g.p("it, err := x.rlpItem()")
+	g.p("if err != nil { return nil, err }")
+	g.p("return rlp.Encode(it), nil")
+	g.p("}")
+	g.p("")
+	g.p("func (x *%s) UnmarshalRLP(b []byte) error {", name)
+	g.p("it, err := rlp.Decode(b)")
+	g.p("if err != nil { return err }")
+	g.p("return x.rlpDecode(it)")
+	g.p("}")
+	g.p("")
+	g.p("func (x *%s) rlpItem() (rlp.Item, error) {", name)
+	g.p("items := make([]rlp.Item, %d)", len(fs))
+	var required int
+	for i, f := range fs {
+		g.encode(f.t, "x."+f.name, fmt.Sprintf("items[%d]", i), f.name)
+		if !f.optional {
+			required++
+		}
+	}
+	if required == len(fs) {
+		g.p("return rlp.List(items...), nil")
+		g.p("}")
+	} else {
+		// omit trailing optional fields with zero values
+		g.p("n := %d", len(fs))
+		for i := len(fs) - 1; i >= required; i-- {
+			g.p("if n == %d && x.%s == %s {", i+1, fs[i].name, zero(fs[i].t))
+			g.p("n = %d", i)
+			g.p("}")
+		}
+		g.p("return rlp.List(items[:n]...), nil")
+		g.p("}")
+	}
+	g.p("")
+	g.p("func (x *%s) rlpDecode(it rlp.Item) error {", name)
+	g.p("l, err := rlpgenList(it)")
+	g.p("if err != nil { return err }")
+	if required == len(fs) {
+		g.p("if len(l) != %d {", len(fs))
+	} else {
+		g.p("if len(l) < %d || len(l) > %d {", required, len(fs))
+	}
+	g.p(`return fmt.Errorf("rlp: expected list of %d for %s. got %%d", len(l))`, len(fs), name)
+	g.p("}")
+	for i, f := range fs {
+		if !f.optional {
+			g.decode(f.t, fmt.Sprintf("l[%d]", i), "x."+f.name, f.name)
+			continue
+		}
+		g.p("if len(l) > %d {", i)
+		g.decode(f.t, fmt.Sprintf("l[%d]", i), "x."+f.name, f.name)
+		g.p("} else {")
+		g.p("x.%s = %s", f.name, zero(f.t))
+		g.p("}")
+	}
+	g.p("return nil")
+	g.p("}")
+}
+
+func (g *gen) encErr(field string) {
+	g.p("if err != nil {")
+	g.p(`return rlp.Item{}, fmt.Errorf("field %s: %%w", err)`, field)
+	g.p("}")
+}
+
+func (g *gen) decErr(field string) {
+	g.p("if err != nil {")
+	g.p(`return fmt.Errorf("field %s: %%w", err)`, field)
+	g.p("}")
+}
+
+// Writes statements that set dst to the rlp.Item for src
+func (g *gen) encode(t *typ, src, dst, field string) {
+	switch t.kind {
+	case kBool:
+		g.p("%s = rlp.Bool(%s)", dst, src)
+	case kUint:
+		g.p("%s = rlp.Uint64(uint64(%s))", dst, src)
+	case kString:
+		g.p("%s = rlp.String(%s)", dst, src)
+	case kBytes:
+		g.p("%s = rlp.Bytes(%s)", dst, src)
+	case kByteArray:
+		g.p("%s = rlp.Bytes(%s[:])", dst, src)
+	case kItem:
+		g.p("%s = %s", dst, src)
+	case kBigInt:
+		if !t.ptr {
+			src = "&" + src
+		}
+		v := g.v("b")
+		g.p("%s, err := rlpgenBigInt(%s)", v, src)
+		g.encErr(field)
+		g.p("%s = %s", dst, v)
+	case kStruct:
+		v := g.v("s")
+		if t.ptr {
+			g.p("%s := rlp.List()", v)
+			g.p("if %s != nil {", src)
+			g.p("var err error")
+			g.p("%s, err = %s.rlpItem()", v, src)
+			g.encErr(field)
+			g.p("}")
+		} else {
+			g.p("%s, err := %s.rlpItem()", v, src)
+			g.encErr(field)
+		}
+		g.p("%s = %s", dst, v)
+	case kSlice, kArray:
+		var (
+			l = g.v("l")
+			i = g.v("i")
+		)
+		g.p("%s := make([]rlp.Item, len(%s))", l, src)
+		g.p("for %s := range %s {", i, src)
+		g.encode(t.elem, fmt.Sprintf("%s[%s]", src, i), fmt.Sprintf("%s[%s]", l, i), field)
+		g.p("}")
+		g.p("%s = rlp.List(%s...)", dst, l)
+	}
+}
+
+// Writes statements that decode the rlp.Item src into dst
+func (g *gen) decode(t *typ, src, dst, field string) {
+	switch t.kind {
+	case kBool:
+		v := g.v("b")
+		g.p("%s, err := %s.Bool()", v, src)
+		g.decErr(field)
+		g.p("%s = %s", dst, v)
+	case kUint:
+		v := g.v("n")
+		g.p("%s, err := rlpgenUint(%s, %d)", v, src, t.size)
+		g.decErr(field)
+		g.p("%s = %s(%s)", dst, t.name, v)
+	case kString:
+		v := g.v("b")
+		g.p("%s, err := rlpgenBytes(%s)", v, src)
+		g.decErr(field)
+		g.p("%s = string(%s)", dst, v)
+	case kBytes:
+		v := g.v("b")
+		g.p("%s, err := rlpgenBytes(%s)", v, src)
+		g.decErr(field)
+		g.p("%s = append([]byte{}, %s...)", dst, v)
+	case kByteArray:
+		v := g.v("b")
+		g.p("%s, err := rlpgenBytes(%s)", v, src)
+		g.decErr(field)
+		g.p("if len(%s) != %d {", v, t.size)
+		g.p(`return fmt.Errorf("field %s: expected %d bytes. got %%d", len(%s))`, field, t.size, v)
+		g.p("}")
+		g.p("copy(%s[:], %s)", dst, v)
+	case kItem:
+		g.p("%s = %s", dst, src)
+	case kBigInt:
+		v := g.v("b")
+		g.p("%s, err := rlpgenDecodeBigInt(%s)", v, src)
+		g.decErr(field)
+		if t.ptr {
+			g.p("%s = %s", dst, v)
+		} else {
+			g.p("%s.Set(%s)", dst, v)
+		}
+	case kStruct:
+		if t.ptr {
+			l := g.v("l")
+			g.p("%s = nil", dst)
+			g.p("if %s := %s.List(); %s == nil || len(%s) != 0 {", l, src, l, l)
+			g.p("%s = new(%s)", dst, t.name)
+			g.p("if err := %s.rlpDecode(%s); err != nil {", dst, src)
+			g.p(`return fmt.Errorf("field %s: %%w", err)`, field)
+			g.p("}")
+			g.p("}")
+		} else {
+			g.p("if err := %s.rlpDecode(%s); err != nil {", dst, src)
+			g.p(`return fmt.Errorf("field %s: %%w", err)`, field)
+			g.p("}")
+		}
+	case kSlice, kArray:
+		var (
+			l = g.v("l")
+			i = g.v("i")
+		)
+		g.p("%s, err := rlpgenList(%s)", l, src)
+		g.decErr(field)
+		if t.kind == kArray {
+			g.p("if len(%s) != %d {", l, t.size)
+			g.p(`return fmt.Errorf("field %s: expected list of %d. got %%d", len(%s))`, field, t.size, l)
+			g.p("}")
+		} else {
+			g.p("%s = make(%s, len(%s))", dst, g.goType(t), l)
+		}
+		g.p("for %s := range %s {", i, l)
+		g.decode(t.elem, fmt.Sprintf("%s[%s]", l, i), fmt.Sprintf("%s[%s]", dst, i), field)
+		g.p("}")
+	}
+}
+
+func (g *gen) goType(t *typ) string {
+	switch t.kind {
+	case kBool:
+		return "bool"
+	case kUint:
+		return t.name
+	case kString:
+		return "string"
+	case kBytes:
+		return "[]byte"
+	case kByteArray:
+		return fmt.Sprintf("[%d]byte", t.size)
+	case kItem:
+		return "rlp.Item"
+	case kBigInt:
+		if t.ptr {
+			return "*big.Int"
+		}
+		return "big.Int"
+	case kStruct:
+		if t.ptr {
+			return "*" + t.name
+		}
+		return t.name
+	case kSlice:
+		return "[]" + g.goType(t.elem)
+	default:
+		return fmt.Sprintf("[%d]%s", t.size, g.goType(t.elem))
+	}
+}
+
+const helpers = `
+func rlpgenBytes(it rlp.Item) ([]byte, error) {
+	if it.List() != nil {
+		return nil, errors.New("rlp: expected bytes. got list")
+	}
+	return it.Bytes(), nil
+}
+
+func rlpgenList(it rlp.Item) ([]rlp.Item, error) {
+	if it.List() == nil {
+		return nil, errors.New("rlp: expected list. got bytes")
+	}
+	return it.List(), nil
+}
+
+func rlpgenUint(it rlp.Item, size int) (uint64, error) {
+	b, err := rlpgenBytes(it)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) > size {
+		return 0, fmt.Errorf("rlp: %d bytes overflows uint%d", len(b), size*8)
+	}
+	if len(b) > 0 && b[0] == 0 {
+		return 0, errors.New("rlp: non-canonical integer with leading zero")
+	}
+	return it.Uint64()
+}
+
+func rlpgenBigInt(n *big.Int) (rlp.Item, error) {
+	if n == nil {
+		return rlp.Bytes(nil), nil
+	}
+	if n.Sign() < 0 {
+		return rlp.Item{}, errors.New("rlp: cannot marshal negative big.Int")
+	}
+	return rlp.Bytes(n.Bytes()), nil
+}
+
+func rlpgenDecodeBigInt(it rlp.Item) (*big.Int, error) {
+	b, err := rlpgenBytes(it)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > 0 && b[0] == 0 {
+		return nil, errors.New("rlp: non-canonical integer with leading zero")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+`
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join("internal", "testtypes")
+	got, err := generate(dir, "rlp_gen.go")
+	tc.NoErr(t, err)
+	want, err := os.ReadFile(filepath.Join(dir, "rlp_gen.go"))
+	tc.NoErr(t, err)
+	if !bytes.Equal(want, got) {
+		t.Error("testtypes/rlp_gen.go is stale. run go generate ./...")
+	}
+}
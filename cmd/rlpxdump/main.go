@@ -0,0 +1,80 @@
+// Prints messages recorded by rlpx.Capture
+//
+//	rlpxdump capture/*.rlpx
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/indexsupply/x/rlpx"
+)
+
+var names = map[uint64]string{
+	0x00: "Hello",
+	0x01: "Disconnect",
+	0x02: "Ping",
+	0x03: "Pong",
+	0x10: "Status",
+	0x11: "NewBlockHashes",
+	0x12: "Transactions",
+	0x13: "GetBlockHeaders",
+	0x14: "BlockHeaders",
+	0x15: "GetBlockBodies",
+	0x16: "BlockBodies",
+	0x17: "NewBlock",
+	0x18: "NewPooledTransactionHashes",
+	0x19: "GetPooledTransactions",
+	0x1a: "PooledTransactions",
+	0x1f: "GetReceipts",
+	0x20: "Receipts",
+}
+
+func check(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: rlpxdump FILE...")
+		os.Exit(1)
+	}
+	for _, name := range os.Args[1:] {
+		f, err := os.Open(name)
+		check(err)
+		dump(bufio.NewReader(f))
+		f.Close()
+	}
+}
+
+func dump(r io.Reader) {
+	for {
+		m, err := rlpx.ReadMessage(r)
+		if err == io.EOF {
+			return
+		}
+		check(err)
+		dir := "<"
+		if m.Egress {
+			dir = ">"
+		}
+		name, ok := names[m.ID]
+		if !ok {
+			name = "Unknown"
+		}
+		fmt.Printf("%s %s %s(0x%02x) len=%d\n%x\n",
+			m.Time.Format(time.RFC3339Nano),
+			dir,
+			name,
+			m.ID,
+			len(m.Data),
+			m.Data,
+		)
+	}
+}
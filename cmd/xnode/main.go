@@ -1,11 +1,14 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"net"
+	"os"
 
 	"github.com/indexsupply/x/enr"
+	"github.com/indexsupply/x/keystore"
 	"github.com/indexsupply/x/rlpx"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
@@ -60,19 +63,58 @@ func serve(c net.Conn, node *enr.Record) {
 	if rw.err != nil {
 		fmt.Printf("serve-error: %s\n", rw.err)
 	}
+	disconnect(c, rw.err, rs.DisconnectFor)
+}
+
+// Politely informs the remote of why the connection is
+// being closed. Nothing is sent if the remote initiated
+// the disconnect.
+func disconnect(c net.Conn, err error, f func(error) ([]byte, error)) {
+	b, err := f(err)
+	if err != nil || b == nil {
+		return
+	}
+	c.Write(b)
 }
 
 func main() {
-	var remoteURL string
-	flag.StringVar(&remoteURL, "remote", "", "enode://XXX@host:port")
+	var (
+		remoteURL, captureDir, keyPath string
+		rotateKey                      bool
+	)
+	flag.StringVar(&remoteURL, "remote", "", "enode://XXX@host:port or enr:XXX")
+	flag.StringVar(&captureDir, "capture", "", "directory for recording rlpx messages. see: cmd/rlpxdump")
+	flag.StringVar(&keyPath, "key", "", "encrypted node key file. created if missing. passphrase read from XNODE_KEY_PASSPHRASE")
+	flag.BoolVar(&rotateKey, "rotate-key", false, "replace the node key, print the re-signed enr, and exit")
 	flag.Parse()
 
 	self := new(enr.Record)
-	self.PrivateKey, _ = secp256k1.GeneratePrivateKey()
-	self.PublicKey = self.PrivateKey.PubKey()
+	self.IDScheme = "v4"
 	self.Ip = net.ParseIP("127.0.0.1")
 	self.TcpPort = 30303
 	self.UdpPort = 30303
+	switch {
+	case keyPath != "":
+		pass := []byte(os.Getenv("XNODE_KEY_PASSPHRASE"))
+		load := keystore.LoadOrCreate
+		if rotateKey {
+			load = keystore.Rotate
+		}
+		k, err := load(keyPath, pass)
+		check(err)
+		k.Apply(self)
+		if rotateKey {
+			txt, err := self.MarshalText()
+			check(err)
+			fmt.Printf("%s\n", txt)
+			return
+		}
+	case rotateKey:
+		check(errors.New("-rotate-key requires -key"))
+	default:
+		self.PrivateKey, _ = secp256k1.GeneratePrivateKey()
+		self.PublicKey = self.PrivateKey.PubKey()
+	}
 
 	remote := new(enr.Record)
 	remote.PrivateKey, _ = secp256k1.GeneratePrivateKey()
@@ -83,7 +125,7 @@ func main() {
 
 	if remoteURL != "" {
 		var err error
-		remote, err = enr.ParseV4(remoteURL)
+		remote, err = enr.Parse(remoteURL)
 		check(err)
 	} else {
 		listening := make(chan struct{}, 1)
@@ -112,10 +154,17 @@ func main() {
 	rs, err := rlpx.Session(self, hs)
 	check(err)
 	rs.Verbose = true
+	if captureDir != "" {
+		id := remote.ID()
+		rs.Capture, err = rlpx.NewCapture(captureDir, fmt.Sprintf("%x", id[:8]), 64<<20)
+		check(err)
+		defer rs.Capture.Close()
+	}
 
 	rw.Write(rs.Hello)
 	rw.Read(rs.HandleMessage)
 	rw.Write(rs.EthStatus)
 	rw.Read(rs.HandleMessage)
+	disconnect(conn, rw.err, rs.DisconnectFor)
 	check(rw.err)
 }
@@ -0,0 +1,138 @@
+package discv4
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// score at which a node is banned. Each
+	// offense adds 1 and scores halve after each
+	// banHalfLife so occasional bad packets
+	// (eg clock skew) don't lead to a ban.
+	banThreshold = 10
+	banHalfLife  = time.Minute
+	banDuration  = 10 * time.Minute
+
+	// sources tracked before idle ones are pruned
+	maxBanSources = 4096
+)
+
+var (
+	errBanned  = errors.New("source is banned")
+	errExpired = errors.New("expired packet")
+)
+
+// Counts of packets rejected by the ban lists
+type BanStats struct {
+	Banned  int    // sources currently banned
+	Dropped uint64 // packets dropped from banned sources
+	Invalid uint64 // offenses: invalid hashes, invalid signatures and expired packets
+}
+
+func (s BanStats) add(t BanStats) BanStats {
+	return BanStats{
+		Banned:  s.Banned + t.Banned,
+		Dropped: s.Dropped + t.Dropped,
+		Invalid: s.Invalid + t.Invalid,
+	}
+}
+
+// Scores packet sources. discv4 keeps two lists: source
+// ips are scored for packets with invalid hashes or
+// signatures and are checked before any hashing or key
+// recovery. Node ids are scored for validly signed
+// packets that have expired.
+type bans[K comparable] struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	sources map[K]*offender
+	stats   BanStats
+}
+
+type offender struct {
+	score   int
+	updated time.Time // start of the current half life
+	until   time.Time
+}
+
+func newBans[K comparable]() *bans[K] {
+	return &bans[K]{now: time.Now, sources: map[K]*offender{}}
+}
+
+// Reports whether packets from k should be dropped
+func (b *bans[K]) banned(k K) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	o, ok := b.sources[k]
+	if !ok || !b.now().Before(o.until) {
+		return false
+	}
+	b.stats.Dropped++
+	return true
+}
+
+// Records an offense from k and bans
+// k when its score reaches banThreshold.
+func (b *bans[K]) offend(k K) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stats.Invalid++
+	now := b.now()
+	o, ok := b.sources[k]
+	if !ok {
+		if len(b.sources) >= maxBanSources {
+			b.prune(now)
+		}
+		o = &offender{updated: now}
+		b.sources[k] = o
+	}
+	o.decay(now)
+	o.score++
+	if o.score >= banThreshold {
+		o.score = 0
+		o.until = now.Add(banDuration)
+	}
+}
+
+func (o *offender) decay(now time.Time) {
+	n := now.Sub(o.updated) / banHalfLife
+	if n <= 0 {
+		return
+	}
+	if n > 31 {
+		n = 31
+	}
+	o.score >>= n
+	o.updated = o.updated.Add(n * banHalfLife)
+	if o.score == 0 {
+		o.updated = now
+	}
+}
+
+// Removes sources that aren't banned and
+// whose score has decayed to almost nothing.
+func (b *bans[K]) prune(now time.Time) {
+	for k, o := range b.sources {
+		if now.Before(o.until) {
+			continue
+		}
+		if o.decay(now); o.score == 0 {
+			delete(b.sources, k)
+		}
+	}
+}
+
+func (b *bans[K]) Stats() BanStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.stats
+	now := b.now()
+	for _, o := range b.sources {
+		if now.Before(o.until) {
+			s.Banned++
+		}
+	}
+	return s
+}
@@ -2,9 +2,13 @@ package discv4
 
 import (
 	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"net/netip"
+	"sort"
 	"sync"
 	"time"
 
@@ -18,25 +22,167 @@ import (
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 )
 
+// Looks up nodes until there are enough peers. Lookups
+// alternate between our own key, which fills the closest
+// buckets, and random keys, which fill the rest.
 func (p *process) Update() {
+	var self bool
 	for ; ; time.Sleep(5 * time.Second) {
-		fmt.Printf("peer-count: %d\n", len(p.peers))
-		if len(p.peers) >= 16 {
+		n := p.peerCount()
+		p.logger().Info("peers", "count", n)
+		if n >= 16 {
 			continue
 		}
-		for _, peer := range p.peers {
-			err := p.FindNode(p.prv.PubKey(), peer)
+		self = !self
+		target := p.prv.PubKey()
+		if !self {
+			k, err := secp256k1.GeneratePrivateKey()
 			if err != nil {
-				fmt.Printf("error: find-node: %s\n", err)
+				p.logger().Warn("lookup-target", "err", err)
 				continue
 			}
-			break
+			target = k.PubKey()
 		}
+		p.lookup(target)
 	}
 }
 
+func (p *process) peerCount() int {
+	p.writeMut.Lock()
+	defer p.writeMut.Unlock()
+	return len(p.peers)
+}
+
+// number of nodes returned by a lookup
+const lookupSize = 16
+
+// Iteratively searches for the nodes closest to target.
+// Each round sends FindNode to the alpha closest nodes
+// that haven't been queried and waits up to the rtt
+// timeout for their responses. The lookup ends when a
+// round doesn't find a node closer than the closest
+// known node or when every known node has been queried.
+func (p *process) lookup(target *secp256k1.PublicKey) []*enr.Record {
+	var (
+		tb      = isxsecp256k1.Encode(target)
+		tid     = isxhash.Keccak32(tb[:])
+		seen    = map[[32]byte]bool{p.self.ID(): true}
+		queried = map[[32]byte]bool{}
+		closest []*enr.Record
+	)
+	for _, r := range p.seeds(tid) {
+		seen[r.ID()] = true
+		closest = append(closest, r)
+	}
+	sortClosest(tid, closest)
+	for {
+		var batch, alpha = []*enr.Record(nil), p.rtt.alpha()
+		for _, r := range closest {
+			if len(batch) == alpha {
+				break
+			}
+			if !queried[r.ID()] {
+				queried[r.ID()] = true
+				batch = append(batch, r)
+			}
+		}
+		if len(batch) == 0 {
+			return closest
+		}
+		var closer bool
+		for _, r := range p.query(target, batch) {
+			if seen[r.ID()] {
+				continue
+			}
+			seen[r.ID()] = true
+			if kademlia.Closer(tid, r.ID(), closest[0].ID()) {
+				closer = true
+			}
+			closest = append(closest, r)
+		}
+		sortClosest(tid, closest)
+		if len(closest) > lookupSize {
+			closest = closest[:lookupSize]
+		}
+		if !closer {
+			return closest
+		}
+	}
+}
+
+func sortClosest(target [32]byte, recs []*enr.Record) {
+	sort.Slice(recs, func(i, j int) bool {
+		return kademlia.Closer(target, recs[i].ID(), recs[j].ID())
+	})
+}
+
+// Returns copies of the bonded nodes closest to tid
+// or of every known peer when none have bonded.
+func (p *process) seeds(tid [32]byte) []*enr.Record {
+	p.writeMut.Lock()
+	defer p.writeMut.Unlock()
+	recs := p.ktable.FindClosest(tid, lookupSize)
+	if len(recs) == 0 {
+		for _, r := range p.peers {
+			recs = append(recs, r)
+		}
+	}
+	res := make([]*enr.Record, len(recs))
+	for i, r := range recs {
+		c := *r
+		res[i] = &c
+	}
+	return res
+}
+
+// Sends FindNode to each of dests and returns the nodes
+// from their responses. Waits until each has responded
+// or the rtt timeout has passed. See: handleNeighbors
+func (p *process) query(target *secp256k1.PublicKey, dests []*enr.Record) []*enr.Record {
+	ch := make(chan []*enr.Record, len(dests))
+	p.writeMut.Lock()
+	for _, d := range dests {
+		p.queries[d.ID()] = ch
+	}
+	p.writeMut.Unlock()
+	defer func() {
+		p.writeMut.Lock()
+		for _, d := range dests {
+			if p.queries[d.ID()] == ch {
+				delete(p.queries, d.ID())
+			}
+		}
+		p.writeMut.Unlock()
+	}()
+
+	var pending int
+	for _, d := range dests {
+		if err := p.FindNode(target, d); err != nil {
+			p.logger().Warn("find-node", peerAttr(d), "err", err)
+			continue
+		}
+		pending++
+	}
+	var (
+		res     []*enr.Record
+		timeout = time.NewTimer(p.rtt.timeout())
+	)
+	defer timeout.Stop()
+	for ; pending > 0; pending-- {
+		select {
+		case recs := <-ch:
+			res = append(res, recs...)
+		case <-timeout.C:
+			return res
+		}
+	}
+	return res
+}
+
 type process struct {
-	Verbose bool
+	// Packets are logged at debug level and failures
+	// at warn level. Defaults to slog.Default()
+	Logger *slog.Logger
 
 	conn     net.PacketConn
 	prv      *secp256k1.PrivateKey
@@ -44,12 +190,40 @@ type process struct {
 	writeMut sync.Mutex
 	peers    map[[32]byte]*enr.Record
 	ktable   *kademlia.Table
+	rtt      *rtt
+	bans     *bans[[32]byte]
+	ipBans   *bans[netip.Addr]
+	enrs     *enr.Cache
+
+	queue chan outPacket
+
+	// Known nodes that have been seen at a new endpoint.
+	// The entry in peers is kept until the new endpoint
+	// responds to a ping. See: handlePong
+	pending map[[32]byte]*enr.Record
+
+	// Lookups waiting for a node's neighbors. See: query
+	queries map[[32]byte]chan<- []*enr.Record
 }
 
-func (p *process) log(format string, args ...any) {
-	if p.Verbose {
-		fmt.Printf(format, args...)
+func (p *process) logger() *slog.Logger {
+	if p.Logger == nil {
+		return slog.Default()
 	}
+	return p.Logger
+}
+
+// Logs a sent (>) or received (<) packet
+func (p *process) logPacket(dir, kind string, r *enr.Record, args ...any) {
+	p.logger().Debug(dir+kind, append([]any{"type", kind, peerAttr(r)}, args...)...)
+}
+
+func peerAttr(r *enr.Record) slog.Attr {
+	id := r.ID()
+	return slog.Group("peer",
+		"id", hex.EncodeToString(id[:4]),
+		"addr", r.UDPAddr().String(),
+	)
 }
 
 func New(
@@ -57,20 +231,51 @@ func New(
 	prv *secp256k1.PrivateKey,
 	self *enr.Record,
 ) *process {
-	return &process{
-		conn:   conn,
-		prv:    prv,
-		self:   self,
-		peers:  map[[32]byte]*enr.Record{},
-		ktable: kademlia.New(self),
-	}
+	p := &process{
+		conn:    conn,
+		prv:     prv,
+		self:    self,
+		peers:   map[[32]byte]*enr.Record{},
+		pending: map[[32]byte]*enr.Record{},
+		queries: map[[32]byte]chan<- []*enr.Record{},
+		ktable:  kademlia.New(self),
+		rtt:     newRTT(),
+		bans:    newBans[[32]byte](),
+		ipBans:  newBans[netip.Addr](),
+		enrs:    enr.NewCache(enrCacheSize),
+		queue:   make(chan outPacket, queueSize),
+	}
+	go p.send()
+	return p
+}
+
+// Counts of packets rejected due to expirations
+func (p *process) BanStats() BanStats {
+	return p.bans.Stats().add(p.ipBans.Stats())
+}
+
+// Counts of ENR responses that were and
+// weren't verified by a previous response
+func (p *process) ENRCacheStats() enr.CacheStats {
+	return p.enrs.Stats()
+}
+
+// Counts of nodes inserted into, updated in
+// and evicted from the routing table.
+func (p *process) TableStats() kademlia.Stats {
+	return p.ktable.Stats()
+}
+
+// A snapshot of the routing table's buckets
+func (p *process) DumpBuckets() []kademlia.Bucket {
+	return p.ktable.DumpBuckets()
 }
 
 func (p *process) Serve() {
 	for {
 		err := p.read()
 		if err != nil {
-			p.log("read error: %s\n", err)
+			p.logger().Warn("read", "err", err)
 		}
 	}
 }
@@ -88,6 +293,11 @@ func (p *process) read() error {
 	return p.serve(uaddr, buf[:n])
 }
 
+// Packets are at most 1280 bytes and the most
+// nested packet (neighbors) contains 3 levels of lists.
+// The extra depth allows for forward compatible fields.
+var limits = rlp.Limits{MaxDepth: 8, MaxPayload: 1280}
+
 const (
 	// packet = hash || sig || pt || pd
 	// hash = keccak256(sig || pt || pd)
@@ -98,10 +308,17 @@ const (
 )
 
 func (p *process) serve(uaddr *net.UDPAddr, packet []byte) error {
+	ip, _ := netip.AddrFromSlice(uaddr.IP)
+	ip = ip.Unmap()
+	if p.ipBans.banned(ip) {
+		return errBanned
+	}
 	if len(packet) <= headerSize {
+		p.ipBans.offend(ip)
 		return errors.New("discv4 packet too small")
 	}
 	if !bytes.Equal(packet[:hashSize], isxhash.Keccak(packet[hashSize:])) {
+		p.ipBans.offend(ip)
 		return errors.New("packet contains invalid hash")
 	}
 	var sig [65]byte
@@ -111,13 +328,17 @@ func (p *process) serve(uaddr *net.UDPAddr, packet []byte) error {
 		isxhash.Keccak32(packet[hashSize+sigSize:]),
 	)
 	if err != nil {
+		p.ipBans.offend(ip)
 		return errors.New("unable to extract pubkey from packet")
 	}
 	req := &enr.Record{
 		PublicKey: fromPubkey,
-		Ip:        uaddr.IP,
 		UdpPort:   uint16(uaddr.Port),
 	}
+	setIP(req, uaddr.IP)
+	if p.bans.banned(req.ID()) {
+		return errBanned
+	}
 
 	kind := packet[hashSize+sigSize : headerSize][0]
 	switch kind {
@@ -131,49 +352,122 @@ func (p *process) serve(uaddr *net.UDPAddr, packet []byte) error {
 		err = p.handleNeighbors(req, packet)
 	case 0x05:
 		err = p.handleENRRequest(req, packet)
+	case 0x06:
+		err = p.handleENRResponse(req, packet)
 	default:
-		p.log("< %x\n", packet)
+		p.logPacket("<", "unknown", req, "kind", kind)
+	}
+	if errors.Is(err, errExpired) {
+		p.bans.offend(req.ID())
 	}
 	return isxerrors.Errorf("serving %x: %w", kind, err)
 }
 
+// Returns errExpired when the expiration
+// timestamp in it has passed.
+func checkExpiration(it rlp.Item) error {
+	t, err := it.Time()
+	if err != nil {
+		return isxerrors.Errorf("decoding expiration: %w", err)
+	}
+	if t.Before(time.Now()) {
+		return errExpired
+	}
+	return nil
+}
+
 func (p *process) handleENRRequest(req *enr.Record, packet []byte) error {
-	// packet-data = [request-hash, ENR]
-	item, err := rlp.Decode(packet[headerSize:])
+	// packet-data = [expiration]
+	item, err := rlp.DecodeWithLimits(packet[headerSize:], limits)
 	if err != nil {
 		return err
 	}
-	expiration := item.At(0).Time()
-	if expiration.Before(time.Now()) {
-		return errors.New("expired enr request")
+	if err := checkExpiration(item.At(0)); err != nil {
+		return err
 	}
-	rec, err := p.self.MarshalRLP(p.prv)
+	b, err := p.self.MarshalRLP(p.prv)
+	if err != nil {
+		return err
+	}
+	rec, err := rlp.Decode(b)
 	if err != nil {
 		return err
 	}
 	_, err = p.write(0x06, req.UDPAddr(), rlp.List(
-		rlp.Bytes(isxhash.Keccak(packet)),
-		rlp.Bytes(rec),
+		rlp.Bytes(packet[:hashSize]),
+		rec,
 	))
 	return err
 }
 
+// The record's signature is verified and it must be signed
+// by the sender, be a response to the last ENRRequest sent
+// to the sender, and not have a lower sequence number than
+// the known record. The sender's endpoint is kept as is.
+func (p *process) handleENRResponse(req *enr.Record, packet []byte) error {
+	// packet-data = [request-hash, ENR]
+	item, err := rlp.DecodeWithLimits(packet[headerSize:], limits)
+	if err != nil {
+		return err
+	}
+	if len(item.List()) < 2 {
+		return errors.New("enr response must contain request-hash and enr")
+	}
+	hash, err := item.At(0).Hash()
+	if err != nil {
+		return isxerrors.Errorf("decoding request hash: %w", err)
+	}
+	rec, err := p.enrs.UnmarshalRLP(item.At(1).Raw())
+	if err != nil {
+		return isxerrors.Errorf("decoding enr: %w", err)
+	}
+	if rec.ID() != req.ID() {
+		return errors.New("enr not signed by sender")
+	}
+
+	p.writeMut.Lock()
+	defer p.writeMut.Unlock()
+	peer := p.peers[req.ID()]
+	switch {
+	case peer == nil:
+		return errors.New("missing peer")
+	case !bytes.Equal(peer.SentENRRequestHash[:], hash[:]):
+		return errors.New("invalid enr request hash")
+	case time.Since(peer.SentENRRequest) > time.Minute:
+		return errors.New("expired enr request hash")
+	case rec.Seq() < peer.Seq():
+		return fmt.Errorf("stale enr seq %d < %d", rec.Seq(), peer.Seq())
+	}
+	p.logPacket("<", "enr", req, "seq", rec.Seq())
+	rec.Ip, rec.Ip6, rec.UdpPort = peer.Ip, peer.Ip6, peer.UdpPort
+	rec.SentPing, rec.SentPingHash = peer.SentPing, peer.SentPingHash
+	rec.ReceivedPing, rec.ReceivedPong = peer.ReceivedPing, peer.ReceivedPong
+	*peer = rec
+	return nil
+}
+
 func (p *process) handleFindNode(req *enr.Record, packet []byte) error {
 	// packet-data = [target, expiration, ...]
-	item, err := rlp.Decode(packet[headerSize:])
+	item, err := rlp.DecodeWithLimits(packet[headerSize:], limits)
 	if err != nil {
 		return err
 	}
+	if err := checkExpiration(item.At(1)); err != nil {
+		return err
+	}
 	var (
 		recs  = p.ktable.FindClosest(isxhash.Keccak32(item.At(0).Bytes()), 16)
 		nodes []rlp.Item
 	)
 	for _, rec := range recs {
-		id := isxsecp256k1.Encode(rec.PublicKey)
+		var (
+			id  = isxsecp256k1.Encode(rec.PublicKey)
+			udp = rec.UDPAddr()
+		)
 		nodes = append(nodes, rlp.List(
-			rlp.Bytes(rec.Ip),
-			rlp.Uint16(rec.UdpPort),
-			rlp.Uint16(rec.TcpPort),
+			rlp.IP(udp.IP),
+			rlp.Uint16(uint16(udp.Port)),
+			rlp.Uint16(uint16(rec.TCPAddr().Port)),
 			rlp.Bytes(id[:]),
 		))
 	}
@@ -188,10 +482,13 @@ func (p *process) handleNeighbors(req *enr.Record, packet []byte) error {
 	// packet-data = [nodes, expiration, ...]
 	// nodes = [[ip, udp-port, tcp-port, node-id], ...]
 	pd := packet[headerSize:]
-	item, err := rlp.Decode(pd)
+	item, err := rlp.DecodeWithLimits(pd, limits)
 	if err != nil {
 		return err
 	}
+	if err := checkExpiration(item.At(1)); err != nil {
+		return err
+	}
 	var (
 		nodes   = item.At(0)
 		records []*enr.Record
@@ -202,12 +499,19 @@ func (p *process) handleNeighbors(req *enr.Record, packet []byte) error {
 			rec  = &enr.Record{}
 			err  error
 		)
-		rec.Ip, err = node.At(0).IP()
+		ip, err := node.At(0).IP()
 		if err != nil {
 			return err
 		}
-		rec.UdpPort = node.At(1).Uint16()
-		rec.TcpPort = node.At(2).Uint16()
+		setIP(rec, ip)
+		rec.UdpPort, err = node.At(1).Uint16()
+		if err != nil {
+			return isxerrors.Errorf("reading udp port: %w", err)
+		}
+		rec.TcpPort, err = node.At(2).Uint16()
+		if err != nil {
+			return isxerrors.Errorf("reading tcp port: %w", err)
+		}
 		rec.PublicKey, err = node.At(3).Secp256k1PublicKey()
 		if err != nil {
 			return isxerrors.Errorf("reading pubkey: %w", err)
@@ -218,13 +522,28 @@ func (p *process) handleNeighbors(req *enr.Record, packet []byte) error {
 		records = append(records, rec)
 	}
 
+	// Only the first response to a query is used.
+	// The lookup gets copies since Ping stores
+	// records in peers.
+	p.writeMut.Lock()
+	if ch, ok := p.queries[req.ID()]; ok {
+		delete(p.queries, req.ID())
+		found := make([]*enr.Record, len(records))
+		for i, rec := range records {
+			c := *rec
+			found[i] = &c
+		}
+		ch <- found
+	}
+	p.writeMut.Unlock()
+
 	for _, rec := range records {
 		err := p.Ping(rec)
 		if err != nil {
 			return err
 		}
 	}
-	p.log("<neighbors: %d\n", len(records))
+	p.logPacket("<", "neighbors", req, "count", len(records))
 	return nil
 }
 
@@ -237,22 +556,28 @@ func (p *process) handlePing(req *enr.Record, packet []byte) error {
 		hash = packet[:hashSize]
 		pd   = packet[headerSize:]
 	)
-	item, err := rlp.Decode(pd)
+	item, err := rlp.DecodeWithLimits(pd, limits)
 	if err != nil {
 		return err
 	}
+	if err := checkExpiration(item.At(3)); err != nil {
+		return err
+	}
 	reqFrom, err := item.At(1).At(0).IP()
 	if err != nil {
 		return errors.New("malformed ping from data")
 	}
-	if !reqFrom.Equal(req.Ip) {
+	if !reqFrom.Equal(req.UDPAddr().IP) {
 		return errors.New("packet ip address doesn't match udp")
 	}
-	reqFromPort := item.At(1).At(1).Uint16()
+	reqFromPort, err := item.At(1).At(1).Uint16()
+	if err != nil {
+		return errors.New("malformed ping from port")
+	}
 	if reqFromPort != req.UdpPort {
 		return errors.New("mismatch ping from-port with udp packet")
 	}
-	p.log("<ping: %s %x\n", req, hash[:4])
+	p.logPacket("<", "ping", req, "hash", hex.EncodeToString(hash[:4]))
 
 	err = p.Pong(hash, req)
 	if err != nil {
@@ -261,9 +586,20 @@ func (p *process) handlePing(req *enr.Record, packet []byte) error {
 
 	p.writeMut.Lock()
 	peer, ok := p.peers[req.ID()]
-	if !ok {
+	switch {
+	case !ok:
 		peer = req
 		p.peers[peer.ID()] = peer
+	case !sameEndpoint(peer, req):
+		// A known node has shown up at a new endpoint.
+		// Keep the existing entry and bond with the new
+		// endpoint before trusting it.
+		if pe, ok := p.pending[req.ID()]; ok && sameEndpoint(pe, req) {
+			req = pe
+		}
+		req.ReceivedPing = time.Now()
+		p.writeMut.Unlock()
+		return p.Ping(req)
 	}
 	peer.ReceivedPing = time.Now()
 	if !peer.ReceivedPing.IsZero() && !peer.ReceivedPong.IsZero() {
@@ -280,20 +616,30 @@ func (p *process) handlePing(req *enr.Record, packet []byte) error {
 
 func (p *process) handlePong(req *enr.Record, packet []byte) error {
 	// packet-data = [to, ping-hash, expiration, enr-seq, ...]
-	item, err := rlp.Decode(packet[headerSize:])
+	item, err := rlp.DecodeWithLimits(packet[headerSize:], limits)
 	if err != nil {
 		return err
 	}
+	if err := checkExpiration(item.At(2)); err != nil {
+		return err
+	}
 	hash, err := item.At(1).Hash()
 	if err != nil {
 		return err
 	}
 
-	p.log("<pong: %s %x\n", req, hash[:4])
-
 	p.writeMut.Lock()
 	defer p.writeMut.Unlock()
-	peer := p.peers[req.ID()]
+	var (
+		peer  = p.peers[req.ID()]
+		moved = peer != nil && !sameEndpoint(peer, req)
+	)
+	if moved {
+		peer = p.pending[req.ID()]
+		if peer == nil || !sameEndpoint(peer, req) {
+			return errors.New("pong from unconfirmed endpoint")
+		}
+	}
 	switch {
 	case peer == nil:
 		return errors.New("missing peer")
@@ -304,47 +650,135 @@ func (p *process) handlePong(req *enr.Record, packet []byte) error {
 	}
 
 	peer.ReceivedPong = time.Now()
+	latency := peer.ReceivedPong.Sub(peer.SentPing)
+	p.rtt.observe(latency)
+	p.logPacket("<", "pong", req, "hash", hex.EncodeToString(hash[:4]), "latency", latency)
+	if moved {
+		p.logger().Debug("moved", peerAttr(peer))
+		p.peers[peer.ID()] = peer
+		delete(p.pending, peer.ID())
+	}
 	if !peer.ReceivedPing.IsZero() && !peer.ReceivedPong.IsZero() {
 		p.ktable.Insert(peer)
 	}
+	// enr-seq is optional for older clients
+	if len(item.List()) > 3 {
+		seq, err := item.At(3).Uint64()
+		if err != nil {
+			return isxerrors.Errorf("decoding enr-seq: %w", err)
+		}
+		if seq > peer.Seq() {
+			return p.requestENR(peer)
+		}
+	}
 	return nil
 }
 
+// Records built from observed addresses keep IPv6
+// addresses in Ip6 so that they select and encode
+// the same as records received from the node.
+// The ports apply to both since Udp6Port and
+// Tcp6Port default to UdpPort and TcpPort.
+func setIP(r *enr.Record, ip net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		r.Ip = ip4
+		return
+	}
+	r.Ip6 = ip
+}
+
+func sameEndpoint(a, b *enr.Record) bool {
+	ua, ub := a.UDPAddr(), b.UDPAddr()
+	return ua.IP.Equal(ub.IP) && ua.Port == ub.Port
+}
+
+// endpoint = [ip, udp-port, tcp-port]
+// IPv6 only records use their IPv6 endpoint.
+func endpoint(r *enr.Record) rlp.Item {
+	udp, tcp := r.UDPAddr(), r.TCPAddr()
+	return rlp.List(
+		rlp.IP(udp.IP),
+		rlp.Uint16(uint16(udp.Port)),
+		rlp.Uint16(uint16(tcp.Port)),
+	)
+}
+
 // Assembles an Item for transmission. Steps include:
 // 1. rlp encoding item
 // 2. assembling a signature
 // 3. hashing the contents
 // 4. combining all of that data into a packet
-// 5. sending the packet
+// 5. queueing the packet for sending. See: send
 // The packet composition is as follows:
 // - packet = packet-header || packet-data
 // - packet-header = hash || signature || packet-type
 // - hash = keccak256(signature || packet-type || packet-data)
 // - signature = sign(packet-type || packet-data)
 func (p *process) write(pt byte, to *net.UDPAddr, it rlp.Item) ([]byte, error) {
-	pd := rlp.Encode(it)
-	var ts []byte
-	ts = append(ts, pt)
-	ts = append(ts, pd...)
-	sig, err := isxsecp256k1.Sign(p.prv, isxhash.Keccak32(ts))
+	packet := make([]byte, 32+65, 32+65+1+rlp.EncodedLen(it))
+	packet = append(packet, pt)
+	packet = rlp.AppendEncode(packet, it)
+	sig, err := isxsecp256k1.Sign(p.prv, isxhash.Keccak32(packet[32+65:]))
 	if err != nil {
 		return nil, err
 	}
+	copy(packet[32:], sig[:])
+	hash := isxhash.Keccak(packet[32:])
+	copy(packet, hash)
 
-	var th, hash []byte
-	th = append(th, sig[:]...)
-	th = append(th, pt)
-	th = append(th, pd...)
-	hash = isxhash.Keccak(th)
+	select {
+	case p.queue <- outPacket{to: to, packet: packet}:
+		return hash, nil
+	default:
+		return nil, errors.New("send queue is full")
+	}
+}
 
-	var header []byte
-	header = append(header, hash...)
-	header = append(header, sig[:]...)
-	header = append(header, pt)
+const (
+	queueSize    = 256
+	enrCacheSize = 1024
+	// minimum time between any two packets
+	sendInterval = time.Millisecond
+	// minimum time between two packets to the same destination
+	peerInterval = 5 * time.Millisecond
+)
 
-	packet := append(header, pd...)
-	_, err = p.conn.WriteTo(packet, to)
-	return hash, err
+type outPacket struct {
+	to     *net.UDPAddr
+	packet []byte
+}
+
+// Sends queued packets in the order they were queued.
+// Writes are paced so that a burst of packets (eg responding
+// to many FindNode requests) doesn't block packet handling
+// or flood a single peer.
+func (p *process) send() {
+	var (
+		last     time.Time
+		lastPeer = map[string]time.Time{}
+	)
+	for op := range p.queue {
+		var (
+			key  = op.to.String()
+			next = last.Add(sendInterval)
+		)
+		if t, ok := lastPeer[key]; ok && t.Add(peerInterval).After(next) {
+			next = t.Add(peerInterval)
+		}
+		time.Sleep(time.Until(next))
+		if _, err := p.conn.WriteTo(op.packet, op.to); err != nil {
+			p.logger().Warn("write", "addr", op.to.String(), "err", err)
+		}
+		last = time.Now()
+		lastPeer[key] = last
+		if len(lastPeer) > queueSize {
+			for k, t := range lastPeer {
+				if last.Sub(t) > peerInterval {
+					delete(lastPeer, k)
+				}
+			}
+		}
+	}
 }
 
 func (p *process) FindNode(target *secp256k1.PublicKey, dest *enr.Record) error {
@@ -353,21 +787,18 @@ func (p *process) FindNode(target *secp256k1.PublicKey, dest *enr.Record) error
 		rlp.Bytes(tb[:]),
 		rlp.Time(time.Now().Add(time.Hour)),
 	))
-	p.log(">find: %s %x\n", dest, tb[:4])
+	p.logPacket(">", "find-node", dest, "target", hex.EncodeToString(tb[:4]))
 	return err
 }
 
 func (p *process) Pong(pingHash []byte, dest *enr.Record) error {
 	_, err := p.write(0x02, dest.UDPAddr(), rlp.List(
-		rlp.List(
-			rlp.Bytes(dest.Ip),
-			rlp.Uint16(dest.UdpPort),
-			rlp.Uint16(dest.TcpPort),
-		),
+		endpoint(dest),
 		rlp.Bytes(pingHash),
 		rlp.Time(time.Now().Add(time.Hour)),
+		rlp.Uint64(p.self.Seq()),
 	))
-	p.log(">pong: %s\n", dest)
+	p.logPacket(">", "pong", dest)
 	return err
 }
 
@@ -375,32 +806,60 @@ func (p *process) Ping(dest *enr.Record) error {
 	p.writeMut.Lock()
 	defer p.writeMut.Unlock()
 
-	if pr, ok := p.peers[dest.ID()]; ok && time.Since(pr.SentPing) < time.Hour {
-		p.log("skip-ping %s\n", pr)
+	pr, known := p.peers[dest.ID()]
+	moved := known && !sameEndpoint(pr, dest)
+	switch {
+	case moved:
+		pe, ok := p.pending[dest.ID()]
+		if ok && sameEndpoint(pe, dest) && time.Since(pe.SentPing) < time.Minute {
+			p.logger().Debug("skip-ping", peerAttr(pe))
+			return nil
+		}
+	case known && time.Since(pr.SentPing) < time.Hour:
+		p.logger().Debug("skip-ping", peerAttr(pr))
 		return nil
 	}
 
 	h, err := p.write(0x01, dest.UDPAddr(), rlp.List(
 		rlp.Byte(4),
-		rlp.List(
-			rlp.Bytes(p.self.Ip),
-			rlp.Uint16(p.self.UdpPort),
-			rlp.Uint16(p.self.TcpPort),
-		),
-		rlp.List(
-			rlp.Bytes(dest.Ip),
-			rlp.Uint16(dest.UdpPort),
-			rlp.Uint16(dest.TcpPort),
-		),
+		endpoint(p.self),
+		endpoint(dest),
 		rlp.Time(time.Now().Add(time.Hour)),
+		rlp.Uint64(p.self.Seq()),
 	))
 	if err != nil {
 		return err
 	}
 
-	p.log(">ping: %s %x\n", dest, h[:4])
+	p.logPacket(">", "ping", dest, "hash", hex.EncodeToString(h[:4]))
 	dest.SentPing = time.Now()
 	dest.SentPingHash = *(*[32]byte)(h)
+	if moved {
+		p.pending[dest.ID()] = dest
+		return nil
+	}
 	p.peers[dest.ID()] = dest
 	return nil
 }
+
+// Requests dest's latest record. The response
+// replaces dest's record. See: handleENRResponse
+func (p *process) RequestENR(dest *enr.Record) error {
+	p.writeMut.Lock()
+	defer p.writeMut.Unlock()
+	return p.requestENR(dest)
+}
+
+// Requires p.writeMut
+func (p *process) requestENR(dest *enr.Record) error {
+	h, err := p.write(0x05, dest.UDPAddr(), rlp.List(
+		rlp.Time(time.Now().Add(time.Hour)),
+	))
+	if err != nil {
+		return err
+	}
+	p.logPacket(">", "enr-request", dest, "hash", hex.EncodeToString(h[:4]))
+	dest.SentENRRequest = time.Now()
+	dest.SentENRRequestHash = *(*[32]byte)(h)
+	return nil
+}
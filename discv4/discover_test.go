@@ -1,11 +1,20 @@
 package discv4
 
 import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"net/netip"
 	"testing"
+	"time"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/indexsupply/x/enr"
+	"github.com/indexsupply/x/isxhash"
+	"github.com/indexsupply/x/rlp"
 	"github.com/indexsupply/x/tc"
 	"golang.org/x/net/nettest"
 )
@@ -13,6 +22,10 @@ import (
 func TestPing(t *testing.T) {
 	p1 := testProcess(t)
 	p2 := testProcess(t)
+	var logs bytes.Buffer
+	p1.Logger = slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
 
 	tc.NoErr(t, p1.Ping(p2.self))
 	tc.NoErr(t, p2.read())
@@ -35,6 +48,73 @@ func TestPing(t *testing.T) {
 	if p2.peers[p1.self.ID()].SentPing.IsZero() {
 		t.Errorf("expected p2 to have sent a ping")
 	}
+	if p1.rtt.n == 0 {
+		t.Errorf("expected p1 to have measured rtt to p2")
+	}
+
+	var (
+		id    = p2.self.ID()
+		found bool
+		dec   = json.NewDecoder(&logs)
+	)
+	for dec.More() {
+		var l struct {
+			Msg     string
+			Type    string
+			Latency int64
+			Peer    struct{ ID, Addr string }
+		}
+		tc.NoErr(t, dec.Decode(&l))
+		if l.Msg != "<pong" {
+			continue
+		}
+		found = true
+		if l.Type != "pong" || l.Latency <= 0 || l.Peer.ID != hex.EncodeToString(id[:4]) || l.Peer.Addr != p2.self.UDPAddr().String() {
+			t.Errorf("unexpected pong log: %+v", l)
+		}
+	}
+	if !found {
+		t.Error("expected pong to be logged")
+	}
+}
+
+func TestRTT(t *testing.T) {
+	r := newRTT()
+	if r.timeout() != defaultTimeout || r.alpha() != minAlpha {
+		t.Errorf("expected defaults before samples")
+	}
+	for i := 0; i < 10; i++ {
+		r.observe(5 * time.Millisecond)
+	}
+	if got := r.timeout(); got != minTimeout {
+		t.Errorf("fast network timeout want: %s got: %s", minTimeout, got)
+	}
+	if got := r.alpha(); got != maxAlpha {
+		t.Errorf("fast network alpha want: %d got: %d", maxAlpha, got)
+	}
+
+	r = newRTT()
+	for i := 0; i < 10; i++ {
+		d := 100 * time.Millisecond
+		if i%2 == 0 {
+			d = time.Second
+		}
+		r.observe(d)
+	}
+	if got := r.timeout(); got != maxTimeout {
+		t.Errorf("jittery network timeout want: %s got: %s", maxTimeout, got)
+	}
+	if got := r.alpha(); got != minAlpha {
+		t.Errorf("jittery network alpha want: %d got: %d", minAlpha, got)
+	}
+
+	r = newRTT()
+	for i := 0; i < 10; i++ {
+		r.observe(600 * time.Millisecond)
+	}
+	if got := r.alpha(); got <= minAlpha || got >= maxAlpha {
+		t.Errorf("moderate network alpha got: %d", got)
+	}
 }
 
 func TestFindNode(t *testing.T) {
@@ -67,16 +147,347 @@ func TestFindNode(t *testing.T) {
 	}
 }
 
+func TestLookup(t *testing.T) {
+	var (
+		p1 = testProcess(t)
+		p2 = testProcess(t)
+		p3 = testProcess(t)
+	)
+	for _, pair := range [][2]*process{{p1, p2}, {p3, p2}} {
+		a, b := pair[0], pair[1]
+		tc.NoErr(t, a.Ping(b.self))
+		tc.NoErr(t, b.read()) //read ping
+		tc.NoErr(t, a.read()) //read pong
+		tc.NoErr(t, a.read()) //read ping
+		tc.NoErr(t, b.read()) //read pong
+	}
+	for _, p := range []*process{p1, p2, p3} {
+		p.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		go p.Serve()
+	}
+
+	got := p1.lookup(p3.prv.PubKey())
+	if len(got) == 0 || got[0].ID() != p3.self.ID() {
+		t.Fatalf("expected p3 to be found through p2. got: %v", got)
+	}
+	p1.writeMut.Lock()
+	defer p1.writeMut.Unlock()
+	if len(p1.queries) != 0 {
+		t.Errorf("expected finished queries to be removed. got: %d", len(p1.queries))
+	}
+}
+
+func TestPing_EndpointChange(t *testing.T) {
+	p1 := testProcess(t)
+	p2 := testProcess(t)
+	tc.NoErr(t, p1.Ping(p2.self))
+	tc.NoErr(t, p2.read()) //read ping
+	tc.NoErr(t, p1.read()) //read pong
+	tc.NoErr(t, p1.read()) //read ping
+	tc.NoErr(t, p2.read()) //read pong
+
+	// same identity, new endpoint
+	moved := testProcessKey(t, p2.prv)
+	tc.NoErr(t, moved.Ping(p1.self))
+	tc.NoErr(t, p1.read()) //read ping
+
+	id := p2.self.ID()
+	if !sameEndpoint(p1.peers[id], p2.self) {
+		t.Fatal("expected existing endpoint to be kept until confirmed")
+	}
+	if !sameEndpoint(p1.pending[id], moved.self) {
+		t.Fatal("expected new endpoint to be pending")
+	}
+
+	tc.NoErr(t, moved.read()) //read pong
+	tc.NoErr(t, moved.read()) //read ping
+	tc.NoErr(t, p1.read())    //read pong
+
+	if !sameEndpoint(p1.peers[id], moved.self) {
+		t.Error("expected confirmed endpoint to replace existing endpoint")
+	}
+	if _, ok := p1.pending[id]; ok {
+		t.Error("expected pending endpoint to be removed")
+	}
+}
+
+func TestENRResponse(t *testing.T) {
+	p1 := testProcess(t)
+	p2 := testProcess(t)
+	p2.self.Sequence = 2
+	p2.self.Eth2 = []byte{1, 2, 3, 4}
+
+	// p1 only knows p2's endpoint
+	known := *p2.self
+	known.Sequence, known.Eth2 = 0, nil
+	tc.NoErr(t, p1.Ping(&known))
+	tc.NoErr(t, p2.read()) //read ping
+	tc.NoErr(t, p1.read()) //read pong with enr-seq 2
+	tc.NoErr(t, p1.read()) //read ping
+	tc.NoErr(t, p2.read()) //read enr request
+	tc.NoErr(t, p2.read()) //read pong
+	tc.NoErr(t, p1.read()) //read enr response
+
+	peer := p1.peers[p2.self.ID()]
+	if peer.Seq() != 2 || !peer.IsConsensus() {
+		t.Fatalf("expected updated record. got: %d %x", peer.Seq(), peer.Eth2)
+	}
+	if peer.ReceivedPong.IsZero() || !sameEndpoint(peer, p2.self) {
+		t.Error("expected ping state and endpoint to be kept")
+	}
+
+	// the same record is only verified once
+	tc.NoErr(t, p1.RequestENR(peer))
+	tc.NoErr(t, p2.read())
+	tc.NoErr(t, p1.read())
+	if s := p1.ENRCacheStats(); s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("unexpected enr cache stats: %+v", s)
+	}
+
+	// missing enr
+	_, err := p2.write(0x06, p1.self.UDPAddr(), rlp.List(
+		rlp.Bytes(peer.SentENRRequestHash[:]),
+	))
+	tc.NoErr(t, err)
+	if err := p1.read(); err == nil {
+		t.Error("expected error for missing enr")
+	}
+
+	// unsolicited
+	_, err = p2.write(0x06, p1.self.UDPAddr(), rlp.List(
+		rlp.Bytes(make([]byte, 32)),
+		testENR(t, p2),
+	))
+	tc.NoErr(t, err)
+	if err := p1.read(); err == nil {
+		t.Error("expected error for unsolicited response")
+	}
+
+	// stale
+	p2.self.Sequence = 1
+	tc.NoErr(t, p1.RequestENR(peer))
+	tc.NoErr(t, p2.read())
+	if err := p1.read(); err == nil {
+		t.Error("expected error for stale record")
+	}
+
+	// signed by another node
+	p3 := testProcess(t)
+	tc.NoErr(t, p1.RequestENR(peer))
+	tc.NoErr(t, p2.read()) // response is discarded below
+	buf := make([]byte, 1280)
+	_, _, err = p1.conn.ReadFrom(buf)
+	tc.NoErr(t, err)
+	_, err = p2.write(0x06, p1.self.UDPAddr(), rlp.List(
+		rlp.Bytes(peer.SentENRRequestHash[:]),
+		testENR(t, p3),
+	))
+	tc.NoErr(t, err)
+	if err := p1.read(); err == nil {
+		t.Error("expected error for record signed by another node")
+	}
+}
+
+func testENR(t *testing.T, p *process) rlp.Item {
+	b, err := p.self.MarshalRLP(p.prv)
+	tc.NoErr(t, err)
+	it, err := rlp.Decode(b)
+	tc.NoErr(t, err)
+	return it
+}
+
+func TestPing_IPv6(t *testing.T) {
+	if !nettest.SupportsIPv6() {
+		t.Skip("ipv6 not supported")
+	}
+	p1 := testProcessIPv6(t)
+	p2 := testProcessIPv6(t)
+	if len(p2.self.Ip) != 0 || p2.self.UDPAddr().IP.To4() != nil {
+		t.Fatal("expected ipv6 only record")
+	}
+
+	tc.NoErr(t, p2.Ping(p1.self))
+	tc.NoErr(t, p1.read()) //read ping
+	tc.NoErr(t, p2.read()) //read pong
+	if p2.peers[p1.self.ID()].ReceivedPong.IsZero() {
+		t.Error("expected p2 to have received a pong")
+	}
+	peer := p1.peers[p2.self.ID()]
+	if len(peer.Ip) != 0 || !peer.Ip6.Equal(p2.self.Ip6) {
+		t.Errorf("expected observed ipv6 in ip6. got: %s %s", peer.Ip, peer.Ip6)
+	}
+	rec := *peer
+	rec.IDScheme = "v4"
+	b, err := rec.MarshalRLP(p2.prv)
+	tc.NoErr(t, err)
+	_, err = enr.UnmarshalRLP(b)
+	tc.NoErr(t, err)
+}
+
+func TestSend_Pacing(t *testing.T) {
+	p1 := testProcess(t)
+	p2 := testProcess(t)
+	const n = 4
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		_, err := p1.write(0x05, p2.self.UDPAddr(), rlp.List(rlp.Time(time.Now())))
+		tc.NoErr(t, err)
+	}
+	buf := make([]byte, 1280)
+	for i := 0; i < n; i++ {
+		_, _, err := p2.conn.ReadFrom(buf)
+		tc.NoErr(t, err)
+	}
+	if d := time.Since(start); d < (n-1)*peerInterval {
+		t.Errorf("expected packets to be paced. took: %s", d)
+	}
+}
+
 func testProcess(t *testing.T) *process {
 	prv, err := secp256k1.GeneratePrivateKey()
 	tc.NoErr(t, err)
+	return testProcessKey(t, prv)
+}
+
+func testProcessIPv6(t *testing.T) *process {
+	prv, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	c, err := nettest.NewLocalPacketListener("udp6")
+	tc.NoErr(t, err)
+	ap := netip.MustParseAddrPort(c.LocalAddr().String())
+	return New(c, prv, &enr.Record{
+		IDScheme:  "v4",
+		Ip6:       ap.Addr().AsSlice(),
+		PublicKey: prv.PubKey(),
+		UdpPort:   ap.Port(),
+		TcpPort:   ap.Port(),
+	})
+}
+
+func testProcessKey(t *testing.T, prv *secp256k1.PrivateKey) *process {
 	c, err := nettest.NewLocalPacketListener("udp4")
 	tc.NoErr(t, err)
 	ap := netip.MustParseAddrPort(c.LocalAddr().String())
 	return New(c, prv, &enr.Record{
+		IDScheme:  "v4",
 		Ip:        ap.Addr().AsSlice(),
 		PublicKey: prv.PubKey(),
 		UdpPort:   ap.Port(),
 		TcpPort:   ap.Port(),
 	})
 }
+
+func TestBans(t *testing.T) {
+	var (
+		b   = newBans[[32]byte]()
+		now = time.Now()
+		id  = [32]byte{1}
+	)
+	b.now = func() time.Time { return now }
+	for i := 0; i < banThreshold-1; i++ {
+		b.offend(id)
+	}
+	if b.banned(id) {
+		t.Fatal("expected source to not be banned")
+	}
+	// offenses decay
+	now = now.Add(10 * banHalfLife)
+	b.offend(id)
+	if b.banned(id) {
+		t.Fatal("expected decayed score to not ban")
+	}
+	for i := 0; i < banThreshold; i++ {
+		b.offend(id)
+	}
+	if !b.banned(id) {
+		t.Fatal("expected source to be banned")
+	}
+	want := BanStats{Banned: 1, Dropped: 1, Invalid: 2 * banThreshold}
+	if got := b.Stats(); got != want {
+		t.Errorf("want: %+v got: %+v", want, got)
+	}
+	now = now.Add(banDuration)
+	if b.banned(id) {
+		t.Error("expected ban to expire")
+	}
+}
+
+func TestServe_Ban(t *testing.T) {
+	p1 := testProcess(t)
+	p2 := testProcess(t)
+
+	// invalid hashes and signatures are scored by source ip
+	var (
+		badHash = make([]byte, 1280)
+		badSig  = make([]byte, 1280)
+	)
+	copy(badSig, isxhash.Keccak(badSig[hashSize:]))
+	for i := 0; i < banThreshold; i++ {
+		b := badHash
+		if i%2 == 0 {
+			b = badSig
+		}
+		_, err := p1.conn.WriteTo(b, p2.self.UDPAddr())
+		tc.NoErr(t, err)
+		if err := p2.read(); err == nil || errors.Is(err, errBanned) {
+			t.Fatalf("expected invalid packet error. got: %v", err)
+		}
+	}
+	_, err := p1.conn.WriteTo(badHash, p2.self.UDPAddr())
+	tc.NoErr(t, err)
+	if err := p2.read(); !errors.Is(err, errBanned) {
+		t.Fatalf("expected errBanned. got: %v", err)
+	}
+	if s := p2.BanStats(); s != (BanStats{Banned: 1, Dropped: 1, Invalid: banThreshold}) {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+
+	// signed packets are scored by node id
+	p2 = testProcess(t)
+
+	expiredPing := func() {
+		_, err := p1.write(0x01, p2.self.UDPAddr(), rlp.List(
+			rlp.Byte(4),
+			endpoint(p1.self),
+			endpoint(p2.self),
+			rlp.Time(time.Now().Add(-time.Minute)),
+		))
+		tc.NoErr(t, err)
+	}
+	for i := 0; i < banThreshold; i++ {
+		expiredPing()
+		if err := p2.read(); !errors.Is(err, errExpired) {
+			t.Fatalf("expected errExpired. got: %v", err)
+		}
+	}
+	expiredPing()
+	if err := p2.read(); !errors.Is(err, errBanned) {
+		t.Errorf("expected errBanned. got: %v", err)
+	}
+	if s := p2.BanStats(); s.Banned != 1 || s.Dropped != 1 || s.Invalid != banThreshold {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestServe_Expired(t *testing.T) {
+	p1 := testProcess(t)
+	p2 := testProcess(t)
+	past := rlp.Time(time.Now().Add(-time.Minute))
+	cases := []struct {
+		kind byte
+		it   rlp.Item
+	}{
+		{0x01, rlp.List(rlp.Byte(4), endpoint(p1.self), endpoint(p2.self), past)},
+		{0x02, rlp.List(endpoint(p2.self), rlp.Bytes(make([]byte, 32)), past)},
+		{0x03, rlp.List(rlp.Bytes(make([]byte, 64)), past)},
+		{0x04, rlp.List(rlp.List(), past)},
+		{0x05, rlp.List(past)},
+	}
+	for _, c := range cases {
+		_, err := p1.write(c.kind, p2.self.UDPAddr(), c.it)
+		tc.NoErr(t, err)
+		if err := p2.read(); !errors.Is(err, errExpired) {
+			t.Errorf("%x: expected errExpired. got: %v", c.kind, err)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package kademlia
 import (
 	"container/list"
 	"math/bits"
+	"net/netip"
 	"sort"
 	"sync"
 	"time"
@@ -36,8 +37,26 @@ type Table struct {
 }
 
 type bucketEntry struct {
-	node     *enr.Record
-	lastSeen time.Time
+	node *enr.Record
+	// Copied from node when it's stored. Callers may
+	// modify node without holding the table's lock
+	// so DumpBuckets doesn't read it.
+	info Node
+}
+
+// Returns the dumpable fields of r
+func nodeInfo(r *enr.Record) Node {
+	var (
+		udp   = r.UDPAddr()
+		ip, _ = netip.AddrFromSlice(udp.IP)
+	)
+	return Node{
+		ID:      r.ID(),
+		IP:      ip.Unmap(),
+		UDPPort: uint16(udp.Port),
+		TCPPort: uint16(r.TCPAddr().Port),
+		Seq:     r.Seq(),
+	}
 }
 
 // kBucket stores an ordered list of nodes, from
@@ -46,6 +65,8 @@ type bucketEntry struct {
 type kBucket struct {
 	lru     list.List
 	entries map[[32]byte]*list.Element
+
+	inserts, updates, evictions uint64
 }
 
 // nodes returns a slice of all the nodes (ENR) stored in this bucket.
@@ -62,16 +83,25 @@ func (bucket *kBucket) nodes() []*enr.Record {
 func (bucket *kBucket) store(node *enr.Record) {
 	if el, ok := bucket.entries[node.ID()]; ok {
 		// cache hit; update
-		el.Value.(*bucketEntry).lastSeen = time.Now()
-		el.Value.(*bucketEntry).node = node
+		e := el.Value.(*bucketEntry)
+		added := e.info.Added
+		e.node = node
+		e.info = nodeInfo(node)
+		e.info.Added, e.info.LastSeen = added, time.Now()
 		bucket.lru.MoveToFront(el)
+		bucket.updates++
 		return
 	}
 
-	newEntry := bucket.lru.PushFront(&bucketEntry{node: node, lastSeen: time.Now()})
+	info := nodeInfo(node)
+	info.Added = time.Now()
+	info.LastSeen = info.Added
+	newEntry := bucket.lru.PushFront(&bucketEntry{node: node, info: info})
 	bucket.entries[node.ID()] = newEntry
+	bucket.inserts++
 
 	if bucket.lru.Len() > maxBucketSize {
+		bucket.evictions++
 		// evict least recently seen
 		last := bucket.lru.Back()
 		bucket.lru.Remove(last)
@@ -94,6 +124,69 @@ func (kt *Table) Insert(node *enr.Record) {
 	kt.buckets[distance-minLogDistance].store(node)
 }
 
+// A snapshot of a k-bucket. See [Table.DumpBuckets]
+type Bucket struct {
+	// Log distance from self of the bucket's nodes.
+	// The closest bucket also holds closer nodes.
+	Distance int
+	// Most recently seen first
+	Nodes []Node
+
+	Inserts, Updates, Evictions uint64
+}
+
+// A node's record as of the last time it was inserted
+type Node struct {
+	ID               [32]byte
+	IP               netip.Addr
+	UDPPort, TCPPort uint16
+	Seq              uint64
+
+	Added    time.Time
+	LastSeen time.Time
+}
+
+// Totals across the table's buckets
+type Stats struct {
+	Size                        int
+	Inserts, Updates, Evictions uint64
+}
+
+// Returns a snapshot of each bucket,
+// from the closest to the furthest.
+func (kt *Table) DumpBuckets() []Bucket {
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+
+	res := make([]Bucket, len(kt.buckets))
+	for i, b := range kt.buckets {
+		res[i] = Bucket{
+			Distance:  i + minLogDistance,
+			Inserts:   b.inserts,
+			Updates:   b.updates,
+			Evictions: b.evictions,
+		}
+		for el := b.lru.Front(); el != nil; el = el.Next() {
+			res[i].Nodes = append(res[i].Nodes, el.Value.(*bucketEntry).info)
+		}
+	}
+	return res
+}
+
+func (kt *Table) Stats() Stats {
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+
+	var s Stats
+	for _, b := range kt.buckets {
+		s.Size += b.lru.Len()
+		s.Inserts += b.inserts
+		s.Updates += b.updates
+		s.Evictions += b.evictions
+	}
+	return s
+}
+
 // FindClosest returns the n closest nodes in the local table to target.
 // It does a full table scan since the actual algorithm to do this is quite complex
 // and the table is not expected to be that large.
@@ -152,3 +245,15 @@ func logDistance(h1, h2 [32]byte) int {
 	}
 	return distance
 }
+
+// Reports whether a is closer to target than b
+// using the xor distance metric.
+func Closer(target, a, b [32]byte) bool {
+	for i := range target {
+		da, db := a[i]^target[i], b[i]^target[i]
+		if da != db {
+			return da < db
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package kademlia
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/indexsupply/x/enr"
+	"github.com/indexsupply/x/tc"
+)
+
+func TestDumpBuckets(t *testing.T) {
+	kt := New(testRecord(t))
+	// fill the furthest bucket past capacity
+	var recs []*enr.Record
+	for len(recs) < maxBucketSize+1 {
+		r := testRecord(t)
+		if logDistance(kt.self.ID(), r.ID()) == addrByteSize {
+			recs = append(recs, r)
+		}
+	}
+	for _, r := range recs {
+		kt.Insert(r)
+	}
+	kt.Insert(recs[len(recs)-1])
+
+	want := Stats{Size: maxBucketSize, Inserts: maxBucketSize + 1, Updates: 1, Evictions: 1}
+	if got := kt.Stats(); got != want {
+		t.Errorf("want: %+v got: %+v", want, got)
+	}
+	buckets := kt.DumpBuckets()
+	if len(buckets) != bucketsCount {
+		t.Fatalf("want %d buckets got: %d", bucketsCount, len(buckets))
+	}
+	b := buckets[len(buckets)-1]
+	if b.Distance != addrByteSize || len(b.Nodes) != maxBucketSize {
+		t.Fatalf("unexpected bucket: %d %d", b.Distance, len(b.Nodes))
+	}
+	if b.Nodes[0].ID != recs[len(recs)-1].ID() {
+		t.Error("expected most recently seen node first")
+	}
+	if b.Nodes[0].LastSeen.Before(b.Nodes[0].Added) || b.Nodes[0].Added.IsZero() {
+		t.Error("expected updated node to be seen after it was added")
+	}
+	for _, n := range b.Nodes {
+		if n.ID == recs[0].ID() {
+			t.Error("expected least recently seen node to be evicted")
+		}
+	}
+}
+
+func TestDumpBuckets_Copy(t *testing.T) {
+	kt := New(testRecord(t))
+	r := testRecord(t)
+	r.Ip, r.UdpPort, r.TcpPort, r.Sequence = net.IPv4(1, 2, 3, 4), 30303, 30304, 1
+	kt.Insert(r)
+
+	*r = enr.Record{PublicKey: r.PublicKey, Sequence: 2}
+	want := Node{
+		ID:      r.ID(),
+		IP:      netip.MustParseAddr("1.2.3.4"),
+		UDPPort: 30303,
+		TCPPort: 30304,
+		Seq:     1,
+	}
+	var got []Node
+	for _, b := range kt.DumpBuckets() {
+		got = append(got, b.Nodes...)
+	}
+	if len(got) != 1 {
+		t.Fatalf("want 1 node got: %d", len(got))
+	}
+	got[0].Added, got[0].LastSeen = time.Time{}, time.Time{}
+	if got[0] != want {
+		t.Errorf("want: %+v got: %+v", want, got[0])
+	}
+}
+
+func TestCloser(t *testing.T) {
+	var (
+		target = [32]byte{0xf0}
+		a      = [32]byte{0xf1}
+		b      = [32]byte{0x70}
+	)
+	if !Closer(target, a, b) || Closer(target, b, a) {
+		t.Error("expected a to be closer than b")
+	}
+	if Closer(target, a, a) {
+		t.Error("expected a to not be closer than itself")
+	}
+}
+
+func testRecord(t *testing.T) *enr.Record {
+	prv, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	return &enr.Record{PublicKey: prv.PubKey()}
+}
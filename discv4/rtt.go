@@ -0,0 +1,97 @@
+package discv4
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// lookup parallelism bounds. minAlpha is the
+	// kademlia default and is used until there
+	// are enough samples to estimate the network.
+	minAlpha = 3
+	maxAlpha = 8
+
+	minTimeout     = 100 * time.Millisecond
+	maxTimeout     = 2 * time.Second
+	defaultTimeout = 500 * time.Millisecond
+
+	// samples required before the estimate is trusted
+	minSamples = 4
+)
+
+// Estimates network round trip time from ping/pong
+// exchanges using the smoothed mean and variance
+// described in RFC 6298. The estimate is used to tune
+// lookup timeouts and parallelism: fast, consistent networks
+// get short timeouts and more parallel queries while slow or
+// jittery networks fall back to conservative values.
+// Only the aggregates are kept so memory is constant
+// regardless of the number of peers observed.
+type rtt struct {
+	mu     sync.Mutex
+	n      int
+	srtt   time.Duration
+	rttvar time.Duration
+}
+
+func newRTT() *rtt {
+	return &rtt{}
+}
+
+func (r *rtt) observe(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.n == 0 {
+		r.srtt, r.rttvar = d, d/2
+		r.n++
+		return
+	}
+	diff := r.srtt - d
+	if diff < 0 {
+		diff = -diff
+	}
+	r.rttvar = (3*r.rttvar + diff) / 4
+	r.srtt = (7*r.srtt + d) / 8
+	r.n++
+}
+
+// Time to wait for a response before considering
+// a lookup query failed.
+func (r *rtt) timeout() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.n < minSamples {
+		return defaultTimeout
+	}
+	t := r.srtt + 4*r.rttvar
+	switch {
+	case t < minTimeout:
+		return minTimeout
+	case t > maxTimeout:
+		return maxTimeout
+	}
+	return t
+}
+
+// Number of concurrent queries in a lookup round.
+// Scales linearly from maxAlpha at minTimeout
+// down to minAlpha at maxTimeout.
+func (r *rtt) alpha() int {
+	t := r.timeout()
+	r.mu.Lock()
+	n := r.n
+	r.mu.Unlock()
+	if n < minSamples {
+		return minAlpha
+	}
+	span := maxTimeout - minTimeout
+	a := maxAlpha - int(time.Duration(maxAlpha-minAlpha)*(t-minTimeout)/span)
+	if a < minAlpha {
+		return minAlpha
+	}
+	return a
+}
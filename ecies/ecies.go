@@ -15,6 +15,8 @@ import (
 	"crypto/subtle"
 	"errors"
 
+	"github.com/indexsupply/x/isxsecp256k1"
+
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 )
 
@@ -47,6 +49,11 @@ func Encrypt(destPubKey *secp256k1.PublicKey, msg, shared []byte) ([]byte, error
 		ke = k[:16]
 		km = sha256.Sum256(k[16:])
 	)
+	defer func() {
+		isxsecp256k1.Zero(r)
+		isxsecp256k1.ZeroBytes(s)
+		isxsecp256k1.ZeroBytes(k[:])
+	}()
 
 	iv := make([]byte, aes.BlockSize)
 	rand.Read(iv)
@@ -103,6 +110,10 @@ func Decrypt(prvKey *secp256k1.PrivateKey, ciphertext, shared []byte) ([]byte, e
 		ke = k[:16]
 		km = sha256.Sum256(k[16:])
 	)
+	defer func() {
+		isxsecp256k1.ZeroBytes(s)
+		isxsecp256k1.ZeroBytes(k[:])
+	}()
 
 	mac := hmac.New(sha256.New, km[:])
 	mac.Write(ciphertext[pubKeyLen:msgEnd]) // iv || c
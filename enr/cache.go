@@ -0,0 +1,107 @@
+package enr
+
+import (
+	"bytes"
+	"container/list"
+	"sync"
+
+	"github.com/indexsupply/x/rlp"
+)
+
+// Memoizes [UnmarshalRLP]. Records are keyed by node id
+// and sequence number so receiving the same record again
+// (common while crawling) skips signature verification.
+// Only a byte for byte copy of a verified record is a hit.
+// Returned records are copies and may be modified.
+//
+// The least recently used record is evicted
+// once the cache holds max records.
+type Cache struct {
+	mu    sync.Mutex
+	max   int
+	recs  map[cacheKey]*list.Element
+	lru   *list.List
+	stats CacheStats
+}
+
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type cacheKey struct {
+	id  [32]byte
+	seq uint64
+}
+
+type cacheEntry struct {
+	key cacheKey
+	raw []byte
+	rec Record
+}
+
+func NewCache(max int) *Cache {
+	return &Cache{
+		max:  max,
+		recs: map[cacheKey]*list.Element{},
+		lru:  list.New(),
+	}
+}
+
+// Like [UnmarshalRLP] but returns the cached
+// record when b has already been verified.
+func (c *Cache) UnmarshalRLP(b []byte) (Record, error) {
+	// the record references the bytes it was
+	// decoded from and may outlive b
+	b = append([]byte(nil), b...)
+	item, err := rlp.Decode(b)
+	if err != nil {
+		return Record{}, err
+	}
+	rec, err := decode(item)
+	if err != nil {
+		return Record{}, err
+	}
+	if rec.PublicKey == nil {
+		return Record{}, verify(item, rec)
+	}
+	key := cacheKey{rec.ID(), rec.Sequence}
+
+	c.mu.Lock()
+	if e, ok := c.recs[key]; ok && bytes.Equal(e.Value.(*cacheEntry).raw, b) {
+		c.lru.MoveToFront(e)
+		c.stats.Hits++
+		rec := e.Value.(*cacheEntry).rec.copy()
+		c.mu.Unlock()
+		return rec, nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	if err := verify(item, rec); err != nil {
+		return Record{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.recs[key]; ok {
+		c.lru.Remove(e)
+	}
+	c.recs[key] = c.lru.PushFront(&cacheEntry{
+		key: key,
+		raw: b,
+		rec: rec.copy(),
+	})
+	for c.lru.Len() > c.max {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.recs, e.Value.(*cacheEntry).key)
+	}
+	return rec, nil
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
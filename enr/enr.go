@@ -12,11 +12,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/indexsupply/x/isxerrors"
 	"github.com/indexsupply/x/isxhash"
 	"github.com/indexsupply/x/isxsecp256k1"
 	"github.com/indexsupply/x/rlp"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
 )
 
 // An Ethereum Node Record contains network information
@@ -38,10 +40,43 @@ type Record struct {
 	Tcp6Port uint16 // IPv6-specific TCP port. If omitted, same as TcpPort.
 	Udp6Port uint16 // IPv6-specific UDP port. If omitted, same as UdpPort.
 
+	// Consensus layer entries. See:
+	// https://github.com/ethereum/consensus-specs/blob/dev/specs/phase0/p2p-interface.md#enr-structure
+	Eth2      []byte // SSZ ENRForkID: fork-digest || next-fork-version || next-fork-epoch
+	Attnets   []byte // SSZ Bitvector[64] of attestation subnet subscriptions
+	Syncnets  []byte // SSZ Bitvector[4] of sync committee subnet subscriptions
+	QuicPort  uint16
+	Quic6Port uint16
+
 	SentPing     time.Time
 	SentPingHash [32]byte
 	ReceivedPong time.Time
 	ReceivedPing time.Time
+
+	SentENRRequest     time.Time
+	SentENRRequestHash [32]byte
+
+	// The signed encoding the record was decoded from.
+	// Includes entries that this package ignores.
+	raw []byte
+}
+
+// Returns r with its byte slices copied
+func (r Record) copy() Record {
+	clone := func(b []byte) []byte {
+		if b == nil {
+			return nil
+		}
+		return append([]byte{}, b...)
+	}
+	r.Signature = clone(r.Signature)
+	r.Ip = clone(r.Ip)
+	r.Ip6 = clone(r.Ip6)
+	r.Eth2 = clone(r.Eth2)
+	r.Attnets = clone(r.Attnets)
+	r.Syncnets = clone(r.Syncnets)
+	r.raw = clone(r.raw)
+	return r
 }
 
 func (r *Record) String() string {
@@ -49,26 +84,129 @@ func (r *Record) String() string {
 	return fmt.Sprintf("%s:%d %x", r.Ip.String(), r.UdpPort, id[:4])
 }
 
+// Returns the record's sequence number. A record with a
+// higher sequence number replaces one with a lower number.
+func (r *Record) Seq() uint64 {
+	return r.Sequence
+}
+
 func (r *Record) ID() [32]byte {
 	pkb := isxsecp256k1.Encode(r.PublicKey)
 	return isxhash.Keccak32(pkb[:])
 }
 
+// Reports whether the record contains the eth2 entry
+// which is set by consensus layer nodes.
+func (r *Record) IsConsensus() bool {
+	return len(r.Eth2) != 0
+}
+
+// Returns the first 4 bytes of the eth2 entry. The fork digest
+// identifies the chain and fork that the node is on.
+func (r *Record) ForkDigest() ([4]byte, error) {
+	if len(r.Eth2) < 4 {
+		return [4]byte{}, errors.New("eth2 entry must contain a 4 byte fork digest")
+	}
+	return *(*[4]byte)(r.Eth2), nil
+}
+
+// Returns the attestation subnets that the node is subscribed to
+func (r *Record) AttestationSubnets() []int {
+	return bitvector(r.Attnets)
+}
+
+// Returns the sync committee subnets that the node is subscribed to
+func (r *Record) SyncSubnets() []int {
+	return bitvector(r.Syncnets)
+}
+
+// SSZ bitvectors are little-endian: bit i is
+// stored in byte i/8 at position i%8.
+func bitvector(b []byte) []int {
+	var res []int
+	for i := 0; i < len(b)*8; i++ {
+		if b[i/8]&(1<<(i%8)) != 0 {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// Returns the IPv6 udp port. Same as UdpPort if omitted.
+func (r Record) UDP6() uint16 {
+	if r.Udp6Port != 0 {
+		return r.Udp6Port
+	}
+	return r.UdpPort
+}
+
+// Returns the IPv6 tcp port. Same as TcpPort if omitted.
+func (r Record) TCP6() uint16 {
+	if r.Tcp6Port != 0 {
+		return r.Tcp6Port
+	}
+	return r.TcpPort
+}
+
+// Returns the IPv4 endpoint when the record has
+// an ip entry and the IPv6 endpoint otherwise.
 func (r Record) UDPAddr() *net.UDPAddr {
+	if len(r.Ip) == 0 && len(r.Ip6) != 0 {
+		return r.UDP6Addr()
+	}
 	return &net.UDPAddr{
 		IP:   r.Ip,
 		Port: int(r.UdpPort),
 	}
 }
 
+func (r Record) UDP6Addr() *net.UDPAddr {
+	return &net.UDPAddr{
+		IP:   r.Ip6,
+		Port: int(r.UDP6()),
+	}
+}
+
+// See [Record.UDPAddr]
 func (r Record) TCPAddr() *net.TCPAddr {
+	if len(r.Ip) == 0 && len(r.Ip6) != 0 {
+		return r.TCP6Addr()
+	}
 	return &net.TCPAddr{
 		IP:   r.Ip,
 		Port: int(r.TcpPort),
 	}
 }
 
+func (r Record) TCP6Addr() *net.TCPAddr {
+	return &net.TCPAddr{
+		IP:   r.Ip6,
+		Port: int(r.TCP6()),
+	}
+}
+
+// Parses a bootstrap node given either as
+// an enode URL or as an ENR text encoding
+func Parse(s string) (*Record, error) {
+	if strings.HasPrefix(s, textPrefix) {
+		r, err := UnmarshalText(s)
+		return &r, err
+	}
+	return ParseEnode(s)
+}
+
+// Deprecated: use [ParseEnode]
 func ParseV4(s string) (*Record, error) {
+	return ParseEnode(s)
+}
+
+// Parses a legacy enode URL:
+//
+//	enode://<hex node id>@<ip>:<tcp port>?discport=<udp port>
+//
+// The udp port is the same as the tcp port
+// when discport is omitted.
+func ParseEnode(s string) (*Record, error) {
 	nurl, err := url.Parse(s)
 	if err != nil {
 		return nil, err
@@ -81,7 +219,10 @@ func ParseV4(s string) (*Record, error) {
 	}
 	nidb, err := hex.DecodeString(nurl.User.String())
 	if err != nil {
-		return nil, err
+		return nil, isxerrors.Errorf("decoding node-id: %w", err)
+	}
+	if len(nidb) != 64 {
+		return nil, fmt.Errorf("node-id must be 64 bytes. got: %d", len(nidb))
 	}
 	npubk, err := isxsecp256k1.Decode(*(*[64]byte)(nidb))
 	if err != nil {
@@ -93,135 +234,322 @@ func ParseV4(s string) (*Record, error) {
 	}
 	nport, err := strconv.ParseUint(nurl.Port(), 10, 16)
 	if err != nil {
-		return nil, err
+		return nil, isxerrors.Errorf("decoding port: %w", err)
 	}
-	return &Record{
+	dport := nport
+	if q := nurl.Query().Get("discport"); q != "" {
+		dport, err = strconv.ParseUint(q, 10, 16)
+		if err != nil {
+			return nil, isxerrors.Errorf("decoding discport: %w", err)
+		}
+	}
+	rec := &Record{
 		PublicKey: npubk,
-		Ip:        nip,
 		TcpPort:   uint16(nport),
-		UdpPort:   uint16(nport),
-	}, nil
+		UdpPort:   uint16(dport),
+	}
+	if ip4 := nip.To4(); ip4 != nil {
+		rec.Ip = ip4
+	} else {
+		rec.Ip6 = nip
+	}
+	return rec, nil
 }
 
-// Given a text encoding of an Ethereum Node Record, which is formatted
-// as the base-64 encoding of its RLP content prefixed by enr:, this
-// method decodes it into an Record struct.
-func UnmarshalText(str string) (Record, error) {
-	const enrTextPrefix = "enr:"
-	if !strings.HasPrefix(str, enrTextPrefix) {
-		return Record{}, errors.New("Invalid prefix for ENR text encoding.")
+// Returns the enode URL for r. See [ParseEnode]
+func (r *Record) EnodeURL() string {
+	var (
+		pkb  = isxsecp256k1.Encode(r.PublicKey)
+		tcp  = r.TCPAddr()
+		udp  = r.UDPAddr()
+		host = net.JoinHostPort(tcp.IP.String(), strconv.Itoa(tcp.Port))
+		u    = url.URL{
+			Scheme: "enode",
+			User:   url.User(hex.EncodeToString(pkb[:])),
+			Host:   host,
+		}
+	)
+	if udp.Port != tcp.Port {
+		u.RawQuery = "discport=" + strconv.Itoa(udp.Port)
 	}
+	return u.String()
+}
+
+const textPrefix = "enr:"
 
-	str = str[len(enrTextPrefix):]
-	b, err := base64.RawURLEncoding.DecodeString(str)
+// Decodes the text encoding of a record: "enr:" followed
+// by the URL safe base64 encoding (without padding) of its
+// RLP encoding. This is the format used by bootnode lists.
+// The record's signature is verified.
+func UnmarshalText(str string) (Record, error) {
+	var r Record
+	return r, r.UnmarshalText([]byte(str))
+}
+
+// Implements [encoding.TextUnmarshaler]. See [UnmarshalText]
+func (r *Record) UnmarshalText(b []byte) error {
+	if !bytes.HasPrefix(b, []byte(textPrefix)) {
+		return errors.New("missing enr: prefix")
+	}
+	// tolerate padding from encoders that add it
+	b = bytes.TrimRight(b[len(textPrefix):], "=")
+	d := make([]byte, base64.RawURLEncoding.DecodedLen(len(b)))
+	n, err := base64.RawURLEncoding.Decode(d, b)
 	if err != nil {
-		return Record{}, err
+		return isxerrors.Errorf("decoding base64: %w", err)
 	}
+	rec, err := UnmarshalRLP(d[:n])
+	if err != nil {
+		return err
+	}
+	*r = rec
+	return nil
+}
 
+// Decodes the RLP encoding of a record and verifies
+// its signature using the v4 identity scheme.
+func UnmarshalRLP(b []byte) (Record, error) {
 	item, err := rlp.Decode(b)
 	if err != nil {
 		return Record{}, err
 	}
+	rec, err := decode(item)
+	if err != nil {
+		return Record{}, err
+	}
+	return rec, verify(item, rec)
+}
 
-	return decode(item)
+// The signature is over keccak256(rlp([seq, k, v, ...]))
+// and is encoded as r || s. See: MarshalRLP
+// The content is hashed as it was received.
+func verify(item rlp.Item, rec Record) error {
+	if rec.IDScheme != "v4" {
+		return fmt.Errorf("unsupported id scheme %q", rec.IDScheme)
+	}
+	if rec.PublicKey == nil {
+		return errors.New("missing secp256k1 public key")
+	}
+	if len(rec.Signature) != 64 {
+		return fmt.Errorf("signature must be 64 bytes. got: %d", len(rec.Signature))
+	}
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(rec.Signature[:32]) || s.SetByteSlice(rec.Signature[32:]) {
+		return errors.New("signature overflows curve order")
+	}
+	var enc rlp.Encoder
+	enc.List(func() {
+		for _, it := range item.List()[1:] {
+			enc.Raw(it.Raw())
+		}
+	})
+	hash := isxhash.Keccak32(enc.Finish())
+	if !ecdsa.NewSignature(&r, &s).Verify(hash[:], rec.PublicKey) {
+		return errors.New("invalid signature")
+	}
+	return nil
 }
 
+// Maximum size of an encoded record. See EIP-778
+const maxSize = 300
+
+// record = [signature, seq, k, v, ...]
+// Keys must be sorted and unique.
 func decode(item rlp.Item) (Record, error) {
-	var rec = Record{}
-	rec.Sequence = item.At(1).Uint64()
+	if len(item.Raw()) > maxSize {
+		return Record{}, fmt.Errorf("record exceeds %d bytes", maxSize)
+	}
+	if len(item.List())%2 != 0 {
+		return Record{}, errors.New("key without value")
+	}
+	var (
+		rec = Record{}
+		err error
+	)
+	rec.Sequence, err = item.At(1).Uint64()
+	if err != nil {
+		return Record{}, isxerrors.Errorf("decoding seq: %w", err)
+	}
 	rec.Signature = item.At(0).Bytes()
 	if len(rec.Signature) == 0 {
 		return Record{}, errors.New("missing signature")
 	}
+	rec.raw = item.Raw()
 
 	for i := 2; i < len(item.List()); i += 2 {
-		var err error
-		switch k := item.At(i).String(); k {
-		case "id":
-			rec.IDScheme = item.At(i + 1).String()
-			if len(rec.IDScheme) == 0 {
-				return rec, errors.New("missing id scheme eg v4")
-			}
-		case "secp256k1":
-			rec.PublicKey, err = item.At(i + 1).Secp256k1PublicKey()
-			if err != nil {
-				return rec, err
-			}
-		case "ip":
-			rec.Ip, err = item.At(i + 1).IP()
-			if err != nil {
-				return rec, err
-			}
-		case "ip6":
-			rec.Ip6, err = item.At(i + 1).IP()
-			if err != nil {
-				return rec, err
-			}
-		case "tcp":
-			rec.TcpPort = item.At(i + 1).Uint16()
-		case "udp":
-			rec.UdpPort = item.At(i + 1).Uint16()
-		case "tcp6":
-			rec.Tcp6Port = item.At(i + 1).Uint16()
-		case "udp6":
-			rec.Udp6Port = item.At(i + 1).Uint16()
+		if i > 2 && item.At(i).String() <= item.At(i-2).String() {
+			return Record{}, fmt.Errorf("unsorted or duplicate key %s", item.At(i).String())
+		}
+		err := rec.set(item.At(i).String(), item.At(i+1))
+		if err != nil && !errors.Is(err, errUnknownKey) {
+			return rec, isxerrors.Errorf("decoding %s: %w", item.At(i).String(), err)
 		}
 	}
 
 	return rec, nil
 }
 
-func (r Record) MarshalText(prv *secp256k1.PrivateKey) ([]byte, error) {
-	b, err := r.MarshalRLP(prv)
-	if err != nil {
-		return nil, err
+var errUnknownKey = errors.New("unknown key")
+
+func (r *Record) set(k string, v rlp.Item) error {
+	var err error
+	switch k {
+	case "id":
+		r.IDScheme = v.String()
+		if len(r.IDScheme) == 0 {
+			return errors.New("missing id scheme eg v4")
+		}
+	case "secp256k1":
+		r.PublicKey, err = v.Secp256k1PublicKey()
+	case "ip":
+		r.Ip, err = v.IP()
+		if err == nil && len(r.Ip) != net.IPv4len {
+			err = errors.New("ip must be 4 bytes")
+		}
+	case "ip6":
+		r.Ip6, err = v.IP()
+		if err == nil && len(r.Ip6) != net.IPv6len {
+			err = errors.New("ip6 must be 16 bytes")
+		}
+	case "tcp":
+		r.TcpPort, err = v.Uint16()
+	case "udp":
+		r.UdpPort, err = v.Uint16()
+	case "tcp6":
+		r.Tcp6Port, err = v.Uint16()
+	case "udp6":
+		r.Udp6Port, err = v.Uint16()
+	case "eth2":
+		r.Eth2 = v.Bytes()
+	case "attnets":
+		r.Attnets = v.Bytes()
+	case "syncnets":
+		r.Syncnets = v.Bytes()
+	case "quic":
+		r.QuicPort, err = v.Uint16()
+	case "quic6":
+		r.Quic6Port, err = v.Uint16()
+	default:
+		return fmt.Errorf("%w: %s", errUnknownKey, k)
 	}
-	var buf = new(bytes.Buffer)
-	encoder := base64.NewEncoder(base64.RawURLEncoding, buf)
-	_, err = encoder.Write(b)
+	return err
+}
+
+// Sets the ip entry for IPv4 addresses and the
+// ip6 entry for IPv6 addresses. See [Record.SetPair]
+func (r *Record) SetIP(ip net.IP) error {
+	if ip4 := ip.To4(); ip4 != nil {
+		if ip4.Equal(r.Ip) {
+			return nil
+		}
+		return r.update(func() error {
+			r.Ip = ip4
+			return nil
+		})
+	}
+	if len(ip) != net.IPv6len {
+		return fmt.Errorf("invalid ip %s", ip)
+	}
+	if ip.Equal(r.Ip6) {
+		return nil
+	}
+	return r.update(func() error {
+		r.Ip6 = ip
+		return nil
+	})
+}
+
+// Sets the udp entry. See [Record.SetPair]
+func (r *Record) SetUDP(port uint16) error {
+	if port == r.UdpPort {
+		return nil
+	}
+	return r.update(func() error {
+		r.UdpPort = port
+		return nil
+	})
+}
+
+// Sets the entry for a key with a pre-defined meaning
+// (eg "tcp" or "eth2"), increments the sequence number and
+// re-signs the record with r.PrivateKey. The record is
+// unchanged if v doesn't decode or signing fails.
+// Setting "secp256k1" is an error since the
+// public key is derived from r.PrivateKey.
+func (r *Record) SetPair(k string, v rlp.Item) error {
+	if k == "secp256k1" {
+		return errors.New("secp256k1 is derived from the private key")
+	}
+	return r.update(func() error {
+		return r.set(k, v)
+	})
+}
+
+func (r *Record) update(f func() error) error {
+	if r.PrivateKey == nil {
+		return errors.New("missing private key to sign record")
+	}
+	prev := *r
+	if err := f(); err != nil {
+		*r = prev
+		return err
+	}
+	r.Sequence++
+	r.PublicKey = r.PrivateKey.PubKey()
+	r.raw = nil
+	b, err := r.MarshalRLP(r.PrivateKey)
 	if err != nil {
-		return nil, err
+		*r = prev
+		return err
 	}
-	err = encoder.Close()
+	item, err := rlp.Decode(b)
 	if err != nil {
-		return nil, err
+		*r = prev
+		return err
 	}
-	return buf.Bytes(), nil
+	r.Signature = item.At(0).Bytes()
+	return nil
 }
 
-func (r *Record) MarshalRLP(prv *secp256k1.PrivateKey) ([]byte, error) {
-	// From the devp2p docs:
-	//
-	// The key/value pairs must be sorted by key and must be unique,
-	// i.e. any key may be present only once. The keys can technically
-	// be any byte sequence, but ASCII text is preferred. Key names in
-	// the table below have pre-defined meaning.
-	var items []rlp.Item
-	items = append(items, rlp.Uint64(r.Sequence))
-	items = append(items, rlp.String("id"))
-	items = append(items, rlp.String(r.IDScheme))
-	items = append(items, rlp.String("ip"))
-	items = append(items, rlp.Bytes(r.Ip))
-	if len(r.Ip6) != 0 {
-		items = append(items, rlp.String("ip6"))
-		items = append(items, rlp.Bytes(r.Ip6))
+// Implements [encoding.TextMarshaler]. See [UnmarshalText]
+//
+// A record with a PrivateKey is signed with it. Otherwise the
+// existing signature is used and records decoded from the
+// network are written exactly as they were received, including
+// entries this package ignores, since only the node can re-sign.
+func (r Record) MarshalText() ([]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+	switch {
+	case r.PrivateKey != nil:
+		b, err = r.MarshalRLP(r.PrivateKey)
+	case r.raw != nil:
+		b = r.raw
+	default:
+		b, err = r.encodeSigned()
 	}
-	items = append(items, rlp.String("secp256k1"))
-	items = append(items, rlp.Bytes(r.PublicKey.SerializeCompressed()))
-	if r.TcpPort != 0 {
-		items = append(items, rlp.String("tcp"))
-		items = append(items, rlp.Uint16(r.TcpPort))
-	}
-	if r.Tcp6Port != 0 {
-		items = append(items, rlp.String("tcp6"))
-		items = append(items, rlp.Uint16(r.Tcp6Port))
+	if err != nil {
+		return nil, err
 	}
-	items = append(items, rlp.String("udp"))
-	items = append(items, rlp.Uint16(r.UdpPort))
-	if r.Udp6Port != 0 {
-		items = append(items, rlp.String("udp6"))
-		items = append(items, rlp.Uint16(r.Udp6Port))
+	res := make([]byte, len(textPrefix)+base64.RawURLEncoding.EncodedLen(len(b)))
+	copy(res, textPrefix)
+	base64.RawURLEncoding.Encode(res[len(textPrefix):], b)
+	return res, nil
+}
+
+// Encodes r with its existing signature
+func (r *Record) encodeSigned() ([]byte, error) {
+	if len(r.Signature) != 64 {
+		return nil, errors.New("missing private key or signature")
 	}
+	items := append([]rlp.Item{rlp.Bytes(r.Signature)}, r.content()...)
+	return rlp.Encode(rlp.List(items...)), nil
+}
+
+func (r *Record) MarshalRLP(prv *secp256k1.PrivateKey) ([]byte, error) {
+	items := r.content()
 	// From the devp2p docs:
 	//
 	// To sign record content with this scheme, apply the keccak256
@@ -238,3 +566,49 @@ func (r *Record) MarshalRLP(prv *secp256k1.PrivateKey) ([]byte, error) {
 	items = append([]rlp.Item{rlp.Bytes(sigNoFmt)}, items...)
 	return rlp.Encode(rlp.List(items...)), nil
 }
+
+// content = [seq, k, v, ...]
+func (r *Record) content() []rlp.Item {
+	// From the devp2p docs:
+	//
+	// The key/value pairs must be sorted by key and must be unique,
+	// i.e. any key may be present only once. The keys can technically
+	// be any byte sequence, but ASCII text is preferred. Key names in
+	// the table below have pre-defined meaning.
+	kv := map[string]rlp.Item{
+		"id":        rlp.String(r.IDScheme),
+		"secp256k1": rlp.Bytes(r.PublicKey.SerializeCompressed()),
+		"udp":       rlp.Uint16(r.UdpPort),
+	}
+	if len(r.Ip) != 0 {
+		kv["ip"] = rlp.IP(r.Ip)
+	}
+	if len(r.Attnets) != 0 {
+		kv["attnets"] = rlp.Bytes(r.Attnets)
+	}
+	if len(r.Eth2) != 0 {
+		kv["eth2"] = rlp.Bytes(r.Eth2)
+	}
+	if len(r.Ip6) != 0 {
+		kv["ip6"] = rlp.Bytes(r.Ip6.To16())
+	}
+	if r.QuicPort != 0 {
+		kv["quic"] = rlp.Uint16(r.QuicPort)
+	}
+	if r.Quic6Port != 0 {
+		kv["quic6"] = rlp.Uint16(r.Quic6Port)
+	}
+	if len(r.Syncnets) != 0 {
+		kv["syncnets"] = rlp.Bytes(r.Syncnets)
+	}
+	if r.TcpPort != 0 {
+		kv["tcp"] = rlp.Uint16(r.TcpPort)
+	}
+	if r.Tcp6Port != 0 {
+		kv["tcp6"] = rlp.Uint16(r.Tcp6Port)
+	}
+	if r.Udp6Port != 0 {
+		kv["udp6"] = rlp.Uint16(r.Udp6Port)
+	}
+	return append([]rlp.Item{rlp.Uint64(r.Sequence)}, rlp.KV(kv)...)
+}
@@ -1,11 +1,17 @@
 package enr
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"net"
 	"reflect"
 	"testing"
 
+	"github.com/indexsupply/x/isxhash"
 	"github.com/indexsupply/x/isxsecp256k1"
+	"github.com/indexsupply/x/rlp"
 	"github.com/indexsupply/x/tc"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
@@ -33,26 +39,423 @@ func TestUnmarshalText(t *testing.T) {
 	want.Ip = []byte{0x7f, 0x00, 0x00, 0x01}
 	want.UdpPort = uint16(30303)
 
+	got.raw = nil // see TestMarshalText_Remote
 	if !reflect.DeepEqual(want, got) {
 		t.Errorf("\nwant:\n%v\ngot:\n%v\n", want, got)
 	}
 }
 
 func TestMarshalText(t *testing.T) {
-	const tv = "-IS4QHCYrYZbAKWCBRlAy5zzaDZXJBGkcnh4MHcBFZntXNFrdvJjX04jRzjzCBOonrkTfj499SZuOh8R33Ls8RRcy5wBgmlkgnY0gmlwhH8AAAGJc2VjcDI1NmsxoQPKY0yuDUmstAHYpMa2_oxVtw0RW_QAdpzBQA8yWM0xOIN1ZHCCdl8"
+	const tv = "enr:-IS4QHCYrYZbAKWCBRlAy5zzaDZXJBGkcnh4MHcBFZntXNFrdvJjX04jRzjzCBOonrkTfj499SZuOh8R33Ls8RRcy5wBgmlkgnY0gmlwhH8AAAGJc2VjcDI1NmsxoQPKY0yuDUmstAHYpMa2_oxVtw0RW_QAdpzBQA8yWM0xOIN1ZHCCdl8"
 	kb, _ := hex.DecodeString("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 	prvk := secp256k1.PrivKeyFromBytes(kb)
 
+	r := &Record{
+		PrivateKey: prvk,
+		PublicKey:  prvk.PubKey(),
+		Sequence:   uint64(1),
+		IDScheme:   "v4",
+		Ip:         []byte{0x7f, 0x00, 0x00, 0x01},
+		UdpPort:    uint16(30303),
+	}
+	u, err := r.MarshalText()
+	tc.NoErr(t, err)
+	if string(u) != tv {
+		t.Error("expected marshalled text to match test vector")
+	}
+
+	// round trip through encoding.TextMarshaler
+	b, err := json.Marshal(map[string]Record{"self": *r})
+	tc.NoErr(t, err)
+	var got map[string]Record
+	tc.NoErr(t, json.Unmarshal(b, &got))
+	self := got["self"]
+	if self.ID() != r.ID() || self.UdpPort != 30303 {
+		t.Errorf("unexpected round trip: %v", self.String())
+	}
+
+	r.PrivateKey = nil
+	if _, err := r.MarshalText(); err == nil {
+		t.Error("expected error without private key or signature")
+	}
+}
+
+func TestMarshalText_Remote(t *testing.T) {
+	prvk, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	// signed by a client that publishes
+	// entries this package ignores
+	content := []rlp.Item{
+		rlp.Uint64(7),
+		rlp.String("id"), rlp.String("v4"),
+		rlp.String("ip"), rlp.IP(net.IPv4(10, 0, 0, 1)),
+		rlp.String("secp256k1"), rlp.Bytes(prvk.PubKey().SerializeCompressed()),
+		rlp.String("snap"), rlp.List(),
+		rlp.String("udp"), rlp.Uint16(30303),
+	}
+	hash := isxhash.Keccak32(rlp.Encode(rlp.List(content...)))
+	sig, err := isxsecp256k1.Sign(prvk, hash)
+	tc.NoErr(t, err)
+	b := rlp.Encode(rlp.List(append([]rlp.Item{rlp.Bytes(sig[:64])}, content...)...))
+	txt := "enr:" + base64.RawURLEncoding.EncodeToString(b)
+
+	remote, err := UnmarshalText(txt)
+	tc.NoErr(t, err)
+	got, err := remote.MarshalText()
+	tc.NoErr(t, err)
+	if string(got) != txt {
+		t.Errorf("want: %s got: %s", txt, got)
+	}
+	_, err = json.Marshal(remote)
+	tc.NoErr(t, err)
+
+	// constructed with an existing signature
+	// and without the snap entry
+	b, err = (&Record{PrivateKey: prvk, PublicKey: prvk.PubKey(), IDScheme: "v4", UdpPort: 1}).MarshalRLP(prvk)
+	tc.NoErr(t, err)
+	signed, err := UnmarshalRLP(b)
+	tc.NoErr(t, err)
+	copied := Record{
+		Signature: signed.Signature,
+		PublicKey: signed.PublicKey,
+		IDScheme:  signed.IDScheme,
+		UdpPort:   signed.UdpPort,
+	}
+	got, err = copied.MarshalText()
+	tc.NoErr(t, err)
+	_, err = UnmarshalText(string(got))
+	tc.NoErr(t, err)
+}
+
+func TestUnmarshalText_Errors(t *testing.T) {
+	const tv = "-IS4QHCYrYZbAKWCBRlAy5zzaDZXJBGkcnh4MHcBFZntXNFrdvJjX04jRzjzCBOonrkTfj499SZuOh8R33Ls8RRcy5wBgmlkgnY0gmlwhH8AAAGJc2VjcDI1NmsxoQPKY0yuDUmstAHYpMa2_oxVtw0RW_QAdpzBQA8yWM0xOIN1ZHCCdl8"
+	_, err := UnmarshalText("enr:" + tv + "=")
+	tc.NoErr(t, err)
+	cases := []string{
+		tv,
+		"enr:" + tv[:len(tv)-4],
+		"enr:" + tv + "!",
+		"enr:-IS5" + tv[4:],
+	}
+	for _, c := range cases {
+		if _, err := UnmarshalText(c); err == nil {
+			t.Errorf("expected error for %q", c)
+		}
+	}
+}
+
+func TestEnode(t *testing.T) {
+	const id = "ca634cae0d49acb401d8a4c6b6fe8c55b70d115bf400769cc1400f3258cd31387574077f301b421bc84df7266c44e9e6d569fc56be00812904767bf5ccd1fc7f"
+	cases := []struct {
+		url      string
+		ip       string
+		tcp, udp uint16
+	}{
+		{"enode://" + id + "@127.0.0.1:30303", "127.0.0.1", 30303, 30303},
+		{"enode://" + id + "@10.3.58.6:30303?discport=30301", "10.3.58.6", 30303, 30301},
+		{"enode://" + id + "@[::1]:30303", "::1", 30303, 30303},
+	}
+	for _, c := range cases {
+		r, err := ParseEnode(c.url)
+		tc.NoErr(t, err)
+		tcp, udp := r.TCPAddr(), r.UDPAddr()
+		if tcp.IP.String() != c.ip || tcp.Port != int(c.tcp) || udp.Port != int(c.udp) {
+			t.Errorf("%s: unexpected record: %s %s", c.url, tcp, udp)
+		}
+		if got := r.EnodeURL(); got != c.url {
+			t.Errorf("want: %s got: %s", c.url, got)
+		}
+	}
+	for _, s := range []string{
+		"enr://" + id + "@127.0.0.1:30303",
+		"enode://127.0.0.1:30303",
+		"enode://" + id[:64] + "@127.0.0.1:30303",
+		"enode://" + id + "@localhost:30303",
+		"enode://" + id + "@127.0.0.1",
+		"enode://" + id + "@127.0.0.1:30303?discport=x",
+	} {
+		if _, err := ParseEnode(s); err == nil {
+			t.Errorf("expected error for %s", s)
+		}
+	}
+
+	r, err := Parse("enr:-IS4QHCYrYZbAKWCBRlAy5zzaDZXJBGkcnh4MHcBFZntXNFrdvJjX04jRzjzCBOonrkTfj499SZuOh8R33Ls8RRcy5wBgmlkgnY0gmlwhH8AAAGJc2VjcDI1NmsxoQPKY0yuDUmstAHYpMa2_oxVtw0RW_QAdpzBQA8yWM0xOIN1ZHCCdl8")
+	tc.NoErr(t, err)
+	// the sample has no tcp entry
+	if want := "enode://" + id + "@127.0.0.1:0?discport=30303"; r.EnodeURL() != want {
+		t.Errorf("want: %s got: %s", want, r.EnodeURL())
+	}
+}
+
+func TestDualStack(t *testing.T) {
+	prvk, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	cases := []struct {
+		r        Record
+		udp, tcp string
+	}{
+		{
+			Record{Ip: net.IPv4(10, 0, 0, 1), UdpPort: 30301, TcpPort: 30303},
+			"10.0.0.1:30301",
+			"10.0.0.1:30303",
+		},
+		{
+			Record{Ip6: net.ParseIP("2001:db8::1"), UdpPort: 30301, TcpPort: 30303},
+			"[2001:db8::1]:30301",
+			"[2001:db8::1]:30303",
+		},
+		{
+			Record{Ip6: net.ParseIP("2001:db8::1"), UdpPort: 30301, Udp6Port: 9000, Tcp6Port: 9001},
+			"[2001:db8::1]:9000",
+			"[2001:db8::1]:9001",
+		},
+		{
+			Record{Ip: net.IPv4(10, 0, 0, 1), Ip6: net.ParseIP("2001:db8::1"), UdpPort: 30301, Udp6Port: 9000},
+			"10.0.0.1:30301",
+			"10.0.0.1:0",
+		},
+	}
+	for _, c := range cases {
+		c.r.IDScheme = "v4"
+		c.r.PublicKey = prvk.PubKey()
+		b, err := c.r.MarshalRLP(prvk)
+		tc.NoErr(t, err)
+		got, err := UnmarshalRLP(b)
+		tc.NoErr(t, err)
+		if got.UDPAddr().String() != c.udp || got.TCPAddr().String() != c.tcp {
+			t.Errorf("want: %s %s got: %s %s", c.udp, c.tcp, got.UDPAddr(), got.TCPAddr())
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	prvk, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	r := &Record{
+		PrivateKey: prvk,
+		IDScheme:   "v4",
+		Ip:         []byte{0x7f, 0x00, 0x00, 0x01},
+		UdpPort:    30303,
+	}
+	tc.NoErr(t, r.SetIP(net.IPv4(10, 0, 0, 1)))
+	tc.NoErr(t, r.SetIP(net.IPv4(10, 0, 0, 1)))
+	tc.NoErr(t, r.SetUDP(30301))
+	tc.NoErr(t, r.SetPair("tcp", rlp.Uint16(30303)))
+	tc.NoErr(t, r.SetIP(net.ParseIP("2001:db8::1")))
+	if r.Seq() != 4 {
+		t.Errorf("want seq 4 got: %d", r.Seq())
+	}
+	if err := r.SetIP(net.IP{1, 2, 3}); err == nil {
+		t.Error("expected error for invalid ip")
+	}
+
+	b, err := r.MarshalRLP(r.PrivateKey)
+	tc.NoErr(t, err)
+	got, err := UnmarshalRLP(b)
+	tc.NoErr(t, err)
+	if !bytes.Equal(got.Signature, r.Signature) {
+		t.Errorf("want signature %x got: %x", got.Signature, r.Signature)
+	}
+	if !got.Ip.Equal(net.IPv4(10, 0, 0, 1)) || got.UdpPort != 30301 || got.TcpPort != 30303 || got.Seq() != 4 {
+		t.Errorf("unexpected record: %s %d %d", got.Ip, got.TcpPort, got.UdpPort)
+	}
+	if !got.Ip6.Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("want ip6 2001:db8::1 got: %s", got.Ip6)
+	}
+
+	for _, c := range []struct {
+		k string
+		v rlp.Item
+	}{
+		{"foo", rlp.Uint16(1)},
+		{"secp256k1", rlp.Bytes(nil)},
+		{"udp", rlp.Bytes(make([]byte, 3))},
+	} {
+		if err := r.SetPair(c.k, c.v); err == nil {
+			t.Errorf("expected error for %s", c.k)
+		}
+	}
+	if r.Seq() != 4 || r.UdpPort != 30301 {
+		t.Errorf("expected record to be unchanged. got: %d %d", r.Seq(), r.UdpPort)
+	}
+	r.PrivateKey = nil
+	if err := r.SetUDP(1); err == nil || r.UdpPort != 30301 {
+		t.Error("expected error without private key")
+	}
+}
+
+func TestConsensusEntries(t *testing.T) {
+	prvk, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
 	r := &Record{
 		PublicKey: prvk.PubKey(),
 		Sequence:  uint64(1),
 		IDScheme:  "v4",
 		Ip:        []byte{0x7f, 0x00, 0x00, 0x01},
-		UdpPort:   uint16(30303),
+		UdpPort:   uint16(9000),
+		QuicPort:  uint16(9001),
+		Eth2:      []byte{0x6a, 0x95, 0xa1, 0xa9, 0x04, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		Attnets:   []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80},
+		Syncnets:  []byte{0x0a},
 	}
-	u, err := r.MarshalText(prvk)
+	r.PrivateKey = prvk
+	b, err := r.MarshalText()
 	tc.NoErr(t, err)
-	if string(u) != tv {
-		t.Error("expected marshalled text to match test vector")
+	got, err := UnmarshalText(string(b))
+	tc.NoErr(t, err)
+
+	if !got.IsConsensus() {
+		t.Error("expected consensus record")
+	}
+	fd, err := got.ForkDigest()
+	tc.NoErr(t, err)
+	if fd != [4]byte{0x6a, 0x95, 0xa1, 0xa9} {
+		t.Errorf("unexpected fork digest: %x", fd)
+	}
+	if got.QuicPort != 9001 {
+		t.Errorf("want quic port 9001 got: %d", got.QuicPort)
+	}
+	if want := []int{0, 63}; !reflect.DeepEqual(want, got.AttestationSubnets()) {
+		t.Errorf("want attnets %v got: %v", want, got.AttestationSubnets())
+	}
+	if want := []int{1, 3}; !reflect.DeepEqual(want, got.SyncSubnets()) {
+		t.Errorf("want syncnets %v got: %v", want, got.SyncSubnets())
+	}
+}
+
+func TestUnmarshalRLP(t *testing.T) {
+	prv, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	r := &Record{
+		PublicKey: prv.PubKey(),
+		Sequence:  2,
+		IDScheme:  "v4",
+		Ip:        []byte{0x7f, 0x00, 0x00, 0x01},
+		UdpPort:   30303,
+	}
+	b, err := r.MarshalRLP(prv)
+	tc.NoErr(t, err)
+	got, err := UnmarshalRLP(b)
+	tc.NoErr(t, err)
+	if got.ID() != r.ID() || got.Sequence != 2 || got.UdpPort != 30303 {
+		t.Errorf("unexpected record: %s", &got)
+	}
+
+	// change the udp port without re-signing
+	bad := append([]byte(nil), b...)
+	bad[len(bad)-1]++
+	if _, err := UnmarshalRLP(bad); err == nil {
+		t.Error("expected invalid signature error")
+	}
+}
+
+func TestUnmarshalRLP_Content(t *testing.T) {
+	prv, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	sign := func(content [][]byte) []byte {
+		var enc rlp.Encoder
+		enc.List(func() {
+			for _, b := range content {
+				enc.Raw(b)
+			}
+		})
+		sig, err := isxsecp256k1.Sign(prv, isxhash.Keccak32(enc.Finish()))
+		tc.NoErr(t, err)
+		enc.Reset()
+		enc.List(func() {
+			enc.Bytes(sig[:64])
+			for _, b := range content {
+				enc.Raw(b)
+			}
+		})
+		return enc.Finish()
+	}
+	var (
+		seq  = rlp.Encode(rlp.Uint64(1))
+		id   = rlp.Encode(rlp.String("id"))
+		v4   = rlp.Encode(rlp.String("v4"))
+		key  = rlp.Encode(rlp.String("secp256k1"))
+		pub  = rlp.Encode(rlp.Bytes(prv.PubKey().SerializeCompressed()))
+		udp  = rlp.Encode(rlp.String("udp"))
+		z    = rlp.Encode(rlp.String("z"))
+		big  = rlp.Encode(rlp.Bytes(make([]byte, maxSize)))
+		seq1 = []byte{0x81, 0x01} // non-canonical encoding of 1
+	)
+	cases := []struct {
+		desc    string
+		content [][]byte
+		ok      bool
+	}{
+		{"valid", [][]byte{seq, id, v4, key, pub}, true},
+		{"hashed as received", [][]byte{seq1, id, v4, key, pub}, true},
+		{"unsorted keys", [][]byte{seq, key, pub, id, v4}, false},
+		{"duplicate key", [][]byte{seq, id, v4, id, v4, key, pub}, false},
+		{"key without value", [][]byte{seq, id, v4, key, pub, udp}, false},
+		{"too large", [][]byte{seq, id, v4, key, pub, z, big}, false},
+	}
+	for _, c := range cases {
+		rec, err := UnmarshalRLP(sign(c.content))
+		switch {
+		case c.ok && err != nil:
+			t.Errorf("%s: %v", c.desc, err)
+		case c.ok && rec.Seq() != 1:
+			t.Errorf("%s: want seq 1 got: %d", c.desc, rec.Seq())
+		case !c.ok && err == nil:
+			t.Errorf("%s: expected error", c.desc)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	prv, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	c := NewCache(2)
+	var recs [][]byte
+	for seq := uint64(1); seq <= 3; seq++ {
+		r := &Record{PublicKey: prv.PubKey(), Sequence: seq, IDScheme: "v4", Ip: net.IPv4(1, 2, 3, 4).To4(), UdpPort: 1}
+		b, err := r.MarshalRLP(prv)
+		tc.NoErr(t, err)
+		recs = append(recs, b)
+	}
+	for _, b := range recs[:2] {
+		_, err := c.UnmarshalRLP(b)
+		tc.NoErr(t, err)
+	}
+	got, err := c.UnmarshalRLP(recs[0])
+	tc.NoErr(t, err)
+	if got.Sequence != 1 {
+		t.Errorf("want seq 1 got: %d", got.Sequence)
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 2 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+
+	// modifying a returned record doesn't modify the cache
+	sig := append([]byte{}, got.Signature...)
+	got.Ip[0], got.Signature[0] = 9, got.Signature[0]+1
+	got, err = c.UnmarshalRLP(recs[0])
+	tc.NoErr(t, err)
+	if !got.Ip.Equal(net.IPv4(1, 2, 3, 4)) || !bytes.Equal(got.Signature, sig) {
+		t.Errorf("expected cached record to be unchanged. got: %s %x", got.Ip, got.Signature)
+	}
+
+	// a record with a known id and seq but different
+	// contents must be verified
+	bad := append([]byte(nil), recs[0]...)
+	bad[len(bad)-1]++
+	if _, err := c.UnmarshalRLP(bad); err == nil {
+		t.Error("expected invalid signature error")
+	}
+
+	// seq 3 evicts seq 2, the least recently used
+	_, err = c.UnmarshalRLP(recs[2])
+	tc.NoErr(t, err)
+	_, err = c.UnmarshalRLP(recs[1])
+	tc.NoErr(t, err)
+	if s := c.Stats(); s.Hits != 2 || s.Misses != 5 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+	if c.lru.Len() != 2 {
+		t.Errorf("want 2 cached records got: %d", c.lru.Len())
 	}
 }
@@ -9,6 +9,16 @@
 // Sign and Recover are constructed from the secp256k1 author's
 // advice: https://github.com/decred/dcrd/issues/2889,
 // https://go.dev/play/p/gIbvbly7n9h
+//
+// Private keys are long-lived (node keys, wallets) and should
+// only be passed by pointer so that [Zero] clears the only copy.
+// Regarding timing: dcrd's field and scalar arithmetic on the
+// private key (eg s = k⁻¹(e + rd)) is constant time. However, Sign
+// uses ScalarBaseMultNonConst and InverseValNonConst on the
+// RFC6979 nonce and GenerateSharedSecret uses ScalarMultNonConst on the
+// private key. These variable time operations are an accepted trade-off
+// in dcrd and go-ethereum alike, but callers that need resistance to
+// local timing attacks must not rely on this package.
 package isxsecp256k1
 
 import (
@@ -47,6 +57,41 @@ func Recover(sig [65]byte, hash [32]byte) (*secp256k1.PublicKey, error) {
 	return pub, nil
 }
 
+// Clears the memory associated with k.
+// k must not be used afterwards.
+func Zero(k *secp256k1.PrivateKey) {
+	if k != nil {
+		k.Zero()
+	}
+}
+
+// Clears b. Use for intermediate buffers that
+// held key material such as shared secrets.
+func ZeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// Parses a 32 byte private key and clears b so that
+// the key material isn't left in the caller's buffer.
+func ParsePrivateKey(b []byte) (*secp256k1.PrivateKey, error) {
+	if len(b) != secp256k1.PrivKeyBytesLen {
+		return nil, errors.New("private key must be 32 bytes")
+	}
+	k := new(secp256k1.PrivateKey)
+	overflow := k.Key.SetByteSlice(b)
+	ZeroBytes(b)
+	switch {
+	case overflow:
+		k.Key.Zero()
+		return nil, errors.New("private key must be less than the curve order")
+	case k.Key.IsZero():
+		return nil, errors.New("private key must not be zero")
+	}
+	return k, nil
+}
+
 func Encode(pubkey *secp256k1.PublicKey) [64]byte {
 	// SerializeUncompressed returns:
 	// 0x04 || 32-byte x coordinate || 32-byte y coordinate
@@ -1,6 +1,7 @@
 package isxsecp256k1
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/indexsupply/x/tc"
@@ -33,3 +34,38 @@ func TestEncodeDecode(t *testing.T) {
 		)
 	}
 }
+
+func TestZero(t *testing.T) {
+	prv, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	Zero(prv)
+	if !prv.Key.IsZero() {
+		t.Error("expected key to be zero")
+	}
+	Zero(nil)
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	prv, err := secp256k1.GeneratePrivateKey()
+	tc.NoErr(t, err)
+	b := prv.Serialize()
+	got, err := ParsePrivateKey(b)
+	tc.NoErr(t, err)
+	if !got.Key.Equals(&prv.Key) {
+		t.Error("expected parsed key to match")
+	}
+	if !bytes.Equal(b, make([]byte, 32)) {
+		t.Errorf("expected input to be cleared. got: %x", b)
+	}
+	if _, err := ParsePrivateKey(make([]byte, 32)); err == nil {
+		t.Error("expected error for zero key")
+	}
+	// the curve order reduces to zero and 2^256-1 doesn't
+	order := secp256k1.Params().N.Bytes()
+	if _, err := ParsePrivateKey(order); err == nil {
+		t.Error("expected error for key equal to the curve order")
+	}
+	if _, err := ParsePrivateKey(bytes.Repeat([]byte{0xff}, 32)); err == nil {
+		t.Error("expected error for key greater than the curve order")
+	}
+}
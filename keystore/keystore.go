@@ -0,0 +1,210 @@
+// Persists node keys encrypted at rest.
+//
+// A key file contains the secp256k1 private key encrypted with
+// AES-256-GCM using a key derived from a passphrase via scrypt.
+// The ENR sequence number is stored alongside the key and is
+// authenticated by GCM. [LoadOrCreate] and [Rotate] increment
+// and save the sequence so that records signed after a restart
+// or a key rotation have a higher sequence than previously
+// published records. Callers that change their record while
+// running (eg [enr.Record.SetIP]) should save the record's
+// sequence with [Save].
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/indexsupply/x/enr"
+	"github.com/indexsupply/x/isxerrors"
+	"github.com/indexsupply/x/isxsecp256k1"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	version = 2
+
+	// scrypt parameters recommended for interactive logins
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+type Key struct {
+	PrivateKey *secp256k1.PrivateKey
+	Sequence   uint64
+}
+
+// Sets the key, public key, and sequence on r. The record
+// must be re-signed (eg [enr.Record.MarshalRLP]) to be published.
+func (k *Key) Apply(r *enr.Record) {
+	r.PrivateKey = k.PrivateKey
+	r.PublicKey = k.PrivateKey.PubKey()
+	r.Sequence = k.Sequence
+}
+
+type file struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+	Sequence   uint64 `json:"seq"`
+}
+
+func aead(passphrase, salt []byte) (cipher.AEAD, error) {
+	dk, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer isxsecp256k1.ZeroBytes(dk)
+	block, err := aes.NewCipher(dk)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypts k with passphrase and writes it to path.
+// The file is replaced atomically.
+func Save(path string, passphrase []byte, k *Key) error {
+	f := file{
+		Version:  version,
+		Salt:     make([]byte, 32),
+		Sequence: k.Sequence,
+	}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	c, err := aead(passphrase, f.Salt)
+	if err != nil {
+		return isxerrors.Errorf("deriving key: %w", err)
+	}
+	f.Nonce = make([]byte, c.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	pk := k.PrivateKey.Serialize()
+	f.Ciphertext = c.Seal(nil, f.Nonce, pk, additionalData(f.Sequence))
+	isxsecp256k1.ZeroBytes(pk)
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, b)
+}
+
+func additionalData(seq uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], seq)
+	return b[:]
+}
+
+// Writes b to path with mode 0600.
+// The file is replaced atomically.
+func writeFile(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Reads and decrypts the key stored at path
+func Load(path string, passphrase []byte) (*Key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, isxerrors.Errorf("decoding key file: %w", err)
+	}
+	if f.Version != version {
+		return nil, errors.New("unsupported key file version")
+	}
+	c, err := aead(passphrase, f.Salt)
+	if err != nil {
+		return nil, isxerrors.Errorf("deriving key: %w", err)
+	}
+	if len(f.Nonce) != c.NonceSize() {
+		return nil, errors.New("invalid nonce size")
+	}
+	pk, err := c.Open(nil, f.Nonce, f.Ciphertext, additionalData(f.Sequence))
+	if err != nil {
+		return nil, errors.New("unable to decrypt key. wrong passphrase or modified file?")
+	}
+	prv, err := isxsecp256k1.ParsePrivateKey(pk)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{PrivateKey: prv, Sequence: f.Sequence}, nil
+}
+
+// Loads the key stored at path and increments and saves its
+// sequence. If there is no file at path then a new
+// key is generated and saved.
+func LoadOrCreate(path string, passphrase []byte) (*Key, error) {
+	k, err := Load(path, passphrase)
+	switch {
+	case err == nil:
+		k.Sequence++
+		return k, Save(path, passphrase, k)
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+	prv, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	k = &Key{PrivateKey: prv, Sequence: 1}
+	return k, Save(path, passphrase, k)
+}
+
+// Replaces the key at path with a new key. The previous file
+// is copied to path.old. The sequence is incremented so that
+// the record signed by the new key supersedes the old one.
+// The key at path is unchanged if an error is returned.
+func Rotate(path string, passphrase []byte) (*Key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	old, err := Load(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer isxsecp256k1.Zero(old.PrivateKey)
+	prv, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(path+".old", b); err != nil {
+		return nil, err
+	}
+	k := &Key{PrivateKey: prv, Sequence: old.Sequence + 1}
+	if err := Save(path, passphrase, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
@@ -0,0 +1,131 @@
+package keystore
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestLoadOrCreate(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "node.key")
+		pass = []byte("hunter2")
+	)
+	k1, err := LoadOrCreate(path, pass)
+	tc.NoErr(t, err)
+	k2, err := LoadOrCreate(path, pass)
+	tc.NoErr(t, err)
+	if !k1.PrivateKey.Key.Equals(&k2.PrivateKey.Key) {
+		t.Error("expected the saved key to be loaded")
+	}
+	if k1.Sequence != 1 || k2.Sequence != 2 {
+		t.Errorf("want seq: 1, 2 got: %d, %d", k1.Sequence, k2.Sequence)
+	}
+	k3, err := Load(path, pass)
+	tc.NoErr(t, err)
+	if k3.Sequence != 2 {
+		t.Errorf("want saved seq: 2 got: %d", k3.Sequence)
+	}
+	fi, err := os.Stat(path)
+	tc.NoErr(t, err)
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("want mode 0600 got: %s", fi.Mode())
+	}
+	if _, err := Load(path, []byte("wrong")); err == nil {
+		t.Error("expected error for wrong passphrase")
+	}
+}
+
+func TestLoad_ModifiedSequence(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "node.key")
+		pass = []byte("hunter2")
+	)
+	_, err := LoadOrCreate(path, pass)
+	tc.NoErr(t, err)
+	b, err := os.ReadFile(path)
+	tc.NoErr(t, err)
+	var f file
+	tc.NoErr(t, json.Unmarshal(b, &f))
+	f.Sequence = 100
+	b, err = json.Marshal(f)
+	tc.NoErr(t, err)
+	tc.NoErr(t, os.WriteFile(path, b, 0600))
+	if _, err := Load(path, pass); err == nil {
+		t.Error("expected error for modified sequence")
+	}
+}
+
+func TestLoad_Version1(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "node.key")
+		pass = []byte("hunter2")
+	)
+	k, err := LoadOrCreate(path, pass)
+	tc.NoErr(t, err)
+	b, err := os.ReadFile(path)
+	tc.NoErr(t, err)
+	var f file
+	tc.NoErr(t, json.Unmarshal(b, &f))
+	// version 1 files didn't authenticate the sequence
+	c, err := aead(pass, f.Salt)
+	tc.NoErr(t, err)
+	f.Version = 1
+	f.Ciphertext = c.Seal(nil, f.Nonce, k.PrivateKey.Serialize(), nil)
+	b, err = json.Marshal(f)
+	tc.NoErr(t, err)
+	tc.NoErr(t, os.WriteFile(path, b, 0600))
+	if _, err := Load(path, pass); err == nil {
+		t.Error("expected error for version 1 file")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "node.key")
+		pass = []byte("hunter2")
+	)
+	k1, err := LoadOrCreate(path, pass)
+	tc.NoErr(t, err)
+	pub1 := k1.PrivateKey.PubKey()
+	k2, err := Rotate(path, pass)
+	tc.NoErr(t, err)
+	if k2.Sequence != 2 {
+		t.Errorf("want seq: 2 got: %d", k2.Sequence)
+	}
+	if pub1.IsEqual(k2.PrivateKey.PubKey()) {
+		t.Error("expected a new key")
+	}
+	old, err := Load(path+".old", pass)
+	tc.NoErr(t, err)
+	if !pub1.IsEqual(old.PrivateKey.PubKey()) {
+		t.Error("expected old key to be kept")
+	}
+	got, err := Load(path, pass)
+	tc.NoErr(t, err)
+	if !got.PrivateKey.PubKey().IsEqual(k2.PrivateKey.PubKey()) {
+		t.Error("expected rotated key to be saved")
+	}
+}
+
+func TestRotate_Error(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "node.key")
+		pass = []byte("hunter2")
+	)
+	k1, err := LoadOrCreate(path, pass)
+	tc.NoErr(t, err)
+	// path.old can't be replaced
+	tc.NoErr(t, os.MkdirAll(filepath.Join(path+".old", "x"), 0700))
+	if _, err := Rotate(path, pass); err == nil {
+		t.Fatal("expected error")
+	}
+	got, err := Load(path, pass)
+	tc.NoErr(t, err)
+	if !got.PrivateKey.PubKey().IsEqual(k1.PrivateKey.PubKey()) || got.Sequence != k1.Sequence {
+		t.Error("expected key at path to be unchanged")
+	}
+}
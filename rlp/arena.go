@@ -0,0 +1,95 @@
+package rlp
+
+// Arena batch-allocates the Items and data used when decoding.
+// It is intended for decoding many messages with a similar
+// shape (eg the receipts in a block) where per-Item allocations
+// dominate GC time.
+//
+// Items returned by [Arena.Decode] reference the Arena's memory
+// and are invalid after [Arena.Reset]. Use [Item.Copy] to retain
+// an Item across resets. An Arena is not safe for concurrent use.
+type Arena struct {
+	items []Item
+	data  []byte
+
+	// high water marks used to size
+	// the buffers after a reset
+	nitems, ndata int
+}
+
+const (
+	minArenaItems = 256
+	minArenaData  = 4096
+)
+
+// Like [Decode] except the Items and the copy of
+// input are allocated from the arena.
+func (a *Arena) Decode(input []byte) (Item, error) {
+	dec := &decoder{arena: a}
+	return dec.decode(a.bytes(input), 0)
+}
+
+// Makes the arena's memory available for reuse.
+// Items decoded prior to Reset must not be used.
+func (a *Arena) Reset() {
+	if a.nitems > cap(a.items) {
+		a.items = make([]Item, 0, a.nitems)
+	}
+	if a.ndata > cap(a.data) {
+		a.data = make([]byte, 0, a.ndata)
+	}
+	for i := range a.items {
+		a.items[i] = Item{}
+	}
+	a.items, a.data = a.items[:0], a.data[:0]
+	a.nitems, a.ndata = 0, 0
+}
+
+func (a *Arena) alloc(n int) []Item {
+	if n == 0 {
+		return []Item{}
+	}
+	a.nitems += n
+	if cap(a.items)-len(a.items) < n {
+		a.items = make([]Item, 0, maxInt(n, minArenaItems, 2*cap(a.items)))
+	}
+	i := len(a.items)
+	a.items = a.items[:i+n]
+	return a.items[i : i : i+n]
+}
+
+func (a *Arena) bytes(b []byte) []byte {
+	n := len(b)
+	a.ndata += n
+	if cap(a.data)-len(a.data) < n {
+		a.data = make([]byte, 0, maxInt(n, minArenaData, 2*cap(a.data)))
+	}
+	i := len(a.data)
+	a.data = append(a.data, b...)
+	return a.data[i : i+n : i+n]
+}
+
+func maxInt(n ...int) int {
+	var m int
+	for i := range n {
+		if n[i] > m {
+			m = n[i]
+		}
+	}
+	return m
+}
+
+// Returns the number of items in the payload of a list.
+// Stops counting at the first malformed item.
+func count(input []byte) int {
+	var n int
+	for len(input) > 0 {
+		hs, ps, err := header(input)
+		if err != nil || len(input) < hs+ps {
+			return n
+		}
+		input = input[hs+ps:]
+		n++
+	}
+	return n
+}
@@ -0,0 +1,75 @@
+package rlp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+// Roughly the shape of a receipt:
+// [status, gas, bloom, [[addr, [topics], data]...]]
+func testReceipt() []byte {
+	var logs []Item
+	for i := 0; i < 4; i++ {
+		logs = append(logs, List(
+			Bytes(randBytes(20)),
+			List(Bytes(randBytes(32)), Bytes(randBytes(32)), Bytes(randBytes(32))),
+			Bytes(randBytes(64)),
+		))
+	}
+	return Encode(List(
+		Uint64(1),
+		Uint64(21000),
+		Bytes(randBytes(256)),
+		List(logs...),
+	))
+}
+
+func TestArena(t *testing.T) {
+	var (
+		a Arena
+		b = testReceipt()
+	)
+	want, err := Decode(b)
+	tc.NoErr(t, err)
+	for i := 0; i < 3; i++ {
+		got, err := a.Decode(b)
+		tc.NoErr(t, err)
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want:\n%v\ngot:\n%v", want, got)
+		}
+		b[len(b)-1]++
+		if !reflect.DeepEqual(want, got) {
+			t.Error("decoded item references input")
+		}
+		b[len(b)-1]--
+		a.Reset()
+	}
+	_, err = a.Decode([]byte{0xc2, 0x01})
+	if err == nil {
+		t.Error("expected error")
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	input := testReceipt()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		Decode(input)
+	}
+}
+
+func BenchmarkArena_Decode(b *testing.B) {
+	var (
+		a     Arena
+		input = testReceipt()
+	)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		a.Decode(input)
+		if n%100 == 99 {
+			a.Reset()
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package rlp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Returns a description of the first difference between
+// want and got or an empty string if they are equal.
+// The description contains the path to the differing
+// item so that test failures don't require comparing
+// entire trees. For example:
+//
+//	[3][1]: want bytes 63 got bytes 64
+func Diff(want, got Item) string {
+	return diff(nil, want, got)
+}
+
+func diff(path []int, want, got Item) string {
+	wList := want.l != nil || want.d == nil
+	gList := got.l != nil || got.d == nil
+	switch {
+	case wList && !gList:
+		return fmt.Sprintf("%s: want list len=%d got bytes %x", fmtPath(path), len(want.l), got.d)
+	case !wList && gList:
+		return fmt.Sprintf("%s: want bytes %x got list len=%d", fmtPath(path), want.d, len(got.l))
+	case !wList:
+		if !bytes.Equal(want.d, got.d) {
+			return fmt.Sprintf("%s: want bytes %x got bytes %x", fmtPath(path), want.d, got.d)
+		}
+		return ""
+	}
+	for k := 0; k < len(want.l) && k < len(got.l); k++ {
+		if d := diff(append(path, k), want.l[k], got.l[k]); d != "" {
+			return d
+		}
+	}
+	if len(want.l) != len(got.l) {
+		return fmt.Sprintf("%s: want list len=%d got list len=%d", fmtPath(path), len(want.l), len(got.l))
+	}
+	return ""
+}
+
+func fmtPath(path []int) string {
+	if len(path) == 0 {
+		return "[]"
+	}
+	var sb strings.Builder
+	for _, p := range path {
+		fmt.Fprintf(&sb, "[%d]", p)
+	}
+	return sb.String()
+}
@@ -0,0 +1,33 @@
+package rlp
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	tree := func(last Item) Item {
+		return List(
+			Uint64(1),
+			List(String("a"), List(String("b"), last)),
+		)
+	}
+	cases := []struct {
+		want, got Item
+		diff      string
+	}{
+		{tree(String("c")), tree(String("c")), ""},
+		{List(), List(), ""},
+		{String("a"), String("b"), "[]: want bytes 61 got bytes 62"},
+		{tree(String("c")), tree(String("d")), "[1][1][1]: want bytes 63 got bytes 64"},
+		{tree(String("c")), tree(List()), "[1][1][1]: want bytes 63 got list len=0"},
+		{tree(List()), tree(String("c")), "[1][1][1]: want list len=0 got bytes 63"},
+		{List(Byte(1)), List(Byte(1), Byte(2)), "[]: want list len=1 got list len=2"},
+		{List(Byte(1), Byte(2)), List(Byte(2)), "[0]: want bytes 01 got bytes 02"},
+	}
+	for _, c := range cases {
+		if got := Diff(c.want, c.got); got != c.diff {
+			t.Errorf("want: %q got: %q", c.diff, got)
+		}
+		if (c.diff == "") != c.want.Equal(c.got) {
+			t.Errorf("Diff and Equal disagree for %q", c.diff)
+		}
+	}
+}
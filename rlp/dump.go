@@ -0,0 +1,43 @@
+package rlp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Returns a human readable rendering of the RLP items in b.
+// Each line contains the item's offset in b, its type,
+// the length of its payload, and the payload in hex.
+// Nested items are indented. Malformed input is rendered
+// up to the point of the error which is included in the output.
+//
+//	0000 list  len=8
+//	0001   bytes len=3 636174
+//	0005   bytes len=3 646f67
+func Dump(b []byte) string {
+	var sb strings.Builder
+	dump(&sb, b, 0, 0)
+	return sb.String()
+}
+
+func dump(sb *strings.Builder, b []byte, off, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for len(b) > 0 {
+		hs, ps, err := header(b)
+		if err == nil && len(b) < hs+ps {
+			err = errTooFewBytes
+		}
+		if err != nil {
+			fmt.Fprintf(sb, "%04x %serror: %s\n", off, indent, err)
+			return
+		}
+		if b[0] >= list55L {
+			fmt.Fprintf(sb, "%04x %slist  len=%d\n", off, indent, ps)
+			dump(sb, b[hs:hs+ps], off+hs, depth+1)
+		} else {
+			fmt.Fprintf(sb, "%04x %sbytes len=%d %x\n", off, indent, ps, b[hs:hs+ps])
+		}
+		b = b[hs+ps:]
+		off += hs + ps
+	}
+}
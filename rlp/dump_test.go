@@ -0,0 +1,34 @@
+package rlp
+
+import "testing"
+
+func TestDump(t *testing.T) {
+	cases := []struct {
+		input []byte
+		want  string
+	}{
+		{
+			Encode(List(String("cat"), String("dog"))),
+			"0000 list  len=8\n" +
+				"0001   bytes len=3 636174\n" +
+				"0005   bytes len=3 646f67\n",
+		},
+		{
+			Encode(List(List(), Byte(1))),
+			"0000 list  len=2\n" +
+				"0001   list  len=0\n" +
+				"0002   bytes len=1 01\n",
+		},
+		{
+			[]byte{0xc3, 0x01, 0x83, 0x01},
+			"0000 list  len=3\n" +
+				"0001   bytes len=1 01\n" +
+				"0002   error: input has fewer bytes than specified by header\n",
+		},
+	}
+	for _, tc := range cases {
+		if got := Dump(tc.input); got != tc.want {
+			t.Errorf("want:\n%s\ngot:\n%s", tc.want, got)
+		}
+	}
+}
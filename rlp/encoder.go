@@ -0,0 +1,92 @@
+package rlp
+
+// Encoder builds an encoding incrementally without an
+// intermediate [Item] tree. List headers are computed when
+// [Encoder.Finish] is called.
+//
+//	var enc rlp.Encoder
+//	enc.List(func() {
+//		enc.Uint64(1)
+//		enc.List(func() {
+//			enc.Bytes(b)
+//		})
+//	})
+//	b := enc.Finish()
+type Encoder struct {
+	buf    []byte
+	lheads []lhead
+	lsize  int // sum of the sizes of closed list headers
+}
+
+type lhead struct {
+	offset int // position in buf of the list's payload
+	size   int // size of payload including nested headers
+}
+
+// Removes all data from e but keeps its memory for reuse
+func (e *Encoder) Reset() {
+	e.buf, e.lheads, e.lsize = e.buf[:0], e.lheads[:0], 0
+}
+
+func (e *Encoder) Bytes(b []byte) {
+	e.buf = AppendEncode(e.buf, Bytes(b))
+}
+
+func (e *Encoder) String(s string) {
+	e.buf = AppendEncode(e.buf, String(s))
+}
+
+func (e *Encoder) Uint64(n uint64) {
+	e.buf = AppendEncode(e.buf, Uint64(n))
+}
+
+func (e *Encoder) Item(it Item) {
+	e.buf = AppendEncode(e.buf, it)
+}
+
+// Appends b which must be an encoded item
+// (eg [Item.Raw]) without re-encoding it
+func (e *Encoder) Raw(b []byte) {
+	e.buf = append(e.buf, b...)
+}
+
+// Encodes the elements appended by f as a list
+func (e *Encoder) List(f func()) {
+	var (
+		i     = len(e.lheads)
+		start = len(e.buf)
+		lsize = e.lsize
+	)
+	e.lheads = append(e.lheads, lhead{offset: start})
+	f()
+	size := len(e.buf) - start + e.lsize - lsize
+	e.lheads[i].size = size
+	e.lsize += headerSize(size)
+}
+
+func headerSize(n int) int {
+	if n <= 55 {
+		return 1
+	}
+	return 1 + lengthSize(n)
+}
+
+// Returns the encoding of the elements appended to e.
+// Must not be called while a list is open.
+func (e *Encoder) Finish() []byte {
+	var (
+		out = make([]byte, 0, len(e.buf)+e.lsize)
+		pos int
+	)
+	for _, h := range e.lheads {
+		out = append(out, e.buf[pos:h.offset]...)
+		pos = h.offset
+		if h.size <= 55 {
+			out = append(out, list55L+byte(h.size))
+			continue
+		}
+		out = append(out, list55H+byte(lengthSize(h.size)))
+		out = appendLength(out, h.size)
+	}
+	return append(out, e.buf[pos:]...)
+}
@@ -0,0 +1,68 @@
+package rlp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	var (
+		large = randBytes(1024)
+		want  = Encode(List(
+			Uint64(1),
+			List(),
+			List(String("a"), List(Bytes(large), List(String("b")))),
+			Bytes(large),
+			List(List(List())),
+		))
+		enc Encoder
+	)
+	for i := 0; i < 2; i++ {
+		enc.List(func() {
+			enc.Uint64(1)
+			enc.List(func() {})
+			enc.List(func() {
+				enc.String("a")
+				enc.List(func() {
+					enc.Bytes(large)
+					enc.Raw(Encode(List(String("b"))))
+				})
+			})
+			enc.Bytes(large)
+			enc.List(func() {
+				enc.List(func() {
+					enc.List(func() {})
+				})
+			})
+		})
+		if got := enc.Finish(); !bytes.Equal(want, got) {
+			t.Errorf("want:\n%x\ngot:\n%x", want, got)
+		}
+		enc.Reset()
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	var (
+		enc Encoder
+		id  = randBytes(64)
+	)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		enc.Reset()
+		enc.List(func() {
+			enc.List(func() {
+				for i := 0; i < 16; i++ {
+					enc.List(func() {
+						enc.Bytes([]byte{127, 0, 0, 1})
+						enc.Uint64(30303)
+						enc.Uint64(30303)
+						enc.Bytes(id)
+					})
+				}
+			})
+			enc.Uint64(1700000000)
+		})
+		enc.Finish()
+	}
+}
@@ -0,0 +1,62 @@
+// Differential testing of package rlp against go-ethereum's rlp.
+package rlpdiff
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/indexsupply/x/rlp"
+
+	gethrlp "github.com/ethereum/go-ethereum/rlp"
+)
+
+// Decodes b with rlp.DecodeStrict and go-ethereum's rlp.DecodeBytes
+// and returns an error if the results disagree. When b is valid,
+// the result of rlp.Encode is also compared with b.
+func Compare(b []byte) error {
+	var gv any
+	gerr := gethrlp.DecodeBytes(b, &gv)
+	it, err := rlp.DecodeStrict(b)
+	switch {
+	case gerr != nil && err != nil:
+		return nil
+	case gerr != nil:
+		return fmt.Errorf("geth rejected %x (%s). got: %v", b, gerr, it)
+	case err != nil:
+		return fmt.Errorf("geth accepted %x. got: %s", b, err)
+	}
+	if err := equal(it, gv); err != nil {
+		return fmt.Errorf("decoding %x: %w", b, err)
+	}
+	if got := rlp.Encode(it); !bytes.Equal(b, got) {
+		return fmt.Errorf("encoding want: %x got: %x", b, got)
+	}
+	return nil
+}
+
+func equal(it rlp.Item, gv any) error {
+	switch gv := gv.(type) {
+	case []byte:
+		if it.List() != nil {
+			return fmt.Errorf("want bytes %x got list", gv)
+		}
+		if !bytes.Equal(gv, it.Bytes()) {
+			return fmt.Errorf("want %x got %x", gv, it.Bytes())
+		}
+	case []any:
+		if it.List() == nil {
+			return fmt.Errorf("want list got bytes %x", it.Bytes())
+		}
+		if len(gv) != len(it.List()) {
+			return fmt.Errorf("want list of %d got %d", len(gv), len(it.List()))
+		}
+		for i := range gv {
+			if err := equal(it.At(i), gv[i]); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unexpected geth type %T", gv)
+	}
+	return nil
+}
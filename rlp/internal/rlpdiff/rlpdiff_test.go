@@ -0,0 +1,111 @@
+package rlpdiff
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/indexsupply/x/rlp"
+
+	gethrlp "github.com/ethereum/go-ethereum/rlp"
+)
+
+func seeds() [][]byte {
+	return [][]byte{
+		{0x00},
+		{0x7f},
+		{0x80},
+		{0x81, 0x00},
+		{0x81, 0x80},
+		{0xb8, 0x38},
+		{0xc0},
+		{0xc1, 0xc0},
+		{0xf8, 0x01, 0x80},
+		rlp.Encode(rlp.String("dog")),
+		rlp.Encode(rlp.Bytes(make([]byte, 56))),
+		rlp.Encode(rlp.List(rlp.String("cat"), rlp.List(rlp.Int(1024)))),
+		rlp.Encode(rlp.List(rlp.Bytes(make([]byte, 1024)))),
+	}
+}
+
+func TestCompare(t *testing.T) {
+	for _, b := range seeds() {
+		if err := Compare(b); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	n, _ := new(big.Int).SetString("83729609699884896815286331701780722", 10)
+	cases := []struct {
+		item rlp.Item
+		v    any
+	}{
+		{rlp.Uint64(0), uint64(0)},
+		{rlp.Uint64(1 << 40), uint64(1 << 40)},
+		{rlp.BigInt(n), n},
+		{rlp.String("dog"), "dog"},
+		{rlp.List(), []any{}},
+		{rlp.List(rlp.String("a"), rlp.List(rlp.Uint64(1))), []any{"a", []any{uint64(1)}}},
+	}
+	for _, c := range cases {
+		want, err := gethrlp.EncodeToBytes(c.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Compare(want); err != nil {
+			t.Error(err)
+		}
+		if got := rlp.Encode(c.item); string(want) != string(got) {
+			t.Errorf("%v want: %x got: %x", c.v, want, got)
+		}
+	}
+}
+
+func FuzzCompare(f *testing.F) {
+	for _, b := range seeds() {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		if err := Compare(b); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// Length headers for payloads longer than 55 bytes.
+// Widths above 3 bytes add no coverage.
+func TestLengthHeaders(t *testing.T) {
+	for _, n := range []int{55, 56, 255, 256, 1024, 65535, 65536} {
+		b := make([]byte, n)
+		want, err := gethrlp.EncodeToBytes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rlp.Encode(rlp.Bytes(b)); string(want) != string(got) {
+			t.Errorf("string of %d want header: %x got: %x", n, want[:5], got[:5])
+		}
+		if err := Compare(want); err != nil {
+			t.Error(err)
+		}
+
+		l := make([][]byte, n/2)
+		for i := range l {
+			l[i] = []byte{}
+		}
+		want, err = gethrlp.EncodeToBytes(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items := make([]rlp.Item, len(l))
+		for i := range items {
+			items[i] = rlp.Bytes(nil)
+		}
+		if got := rlp.Encode(rlp.List(items...)); string(want) != string(got) {
+			t.Errorf("list of %d want header: %x got: %x", len(l), want[:5], got[:5])
+		}
+		if err := Compare(want); err != nil {
+			t.Error(err)
+		}
+	}
+}
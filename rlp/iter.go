@@ -0,0 +1,69 @@
+package rlp
+
+import "errors"
+
+// Iter walks the top-level elements of an encoded list
+// one at a time. Only the current element is decoded
+// which is useful when a list is large (eg Neighbors packets
+// or block bodies) and the caller needs one element at a time.
+//
+//	it := rlp.NewIter(b)
+//	for it.Next() {
+//		it.Item()
+//	}
+//	if it.Err() != nil {
+//		...
+//	}
+type Iter struct {
+	rest []byte
+	item Item
+	err  error
+}
+
+// Returns an iterator over the list encoded in b.
+// Errors in the list's header are reported by [Iter.Err]
+// after the first call to [Iter.Next].
+func NewIter(b []byte) *Iter {
+	it := &Iter{}
+	hs, ps, err := header(b)
+	switch {
+	case err != nil:
+		it.err = err
+	case b[0] < list55L:
+		it.err = errors.New("input is not a list")
+	case len(b) < hs+ps:
+		it.err = errTooFewBytes
+	default:
+		it.rest = b[hs : hs+ps]
+	}
+	return it
+}
+
+// Decodes the next element in the list. Returns false when
+// the list is exhausted or when an error is encountered.
+func (it *Iter) Next() bool {
+	if it.err != nil || len(it.rest) == 0 {
+		return false
+	}
+	hs, ps, err := header(it.rest)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	if len(it.rest) < hs+ps {
+		it.err = errTooFewBytes
+		return false
+	}
+	it.item, it.err = Decode(it.rest[:hs+ps])
+	it.rest = it.rest[hs+ps:]
+	return it.err == nil
+}
+
+// Returns the element decoded by the most recent call to [Iter.Next]
+func (it *Iter) Item() Item {
+	return it.item
+}
+
+func (it *Iter) Err() error {
+	return it.err
+}
@@ -0,0 +1,51 @@
+package rlp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIter(t *testing.T) {
+	want := []Item{
+		String("foo"),
+		List(String("bar"), List()),
+		Bytes(randBytes(100)),
+	}
+	var (
+		it  = NewIter(Encode(List(want...)))
+		got []Item
+	)
+	for it.Next() {
+		got = append(got, it.Item())
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if d := Diff(List(want...), List(got...)); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestIter_Errors(t *testing.T) {
+	cases := []struct {
+		desc  string
+		input []byte
+		err   error
+	}{
+		{"empty", nil, errNoBytes},
+		{"truncated list", Encode(List(String("foo")))[:3], errTooFewBytes},
+		{"truncated element", []byte{0xc2, 0x83, 0x01}, errTooFewBytes},
+	}
+	for _, c := range cases {
+		it := NewIter(c.input)
+		for it.Next() {
+		}
+		if !errors.Is(it.Err(), c.err) {
+			t.Errorf("%s: want: %v got: %v", c.desc, c.err, it.Err())
+		}
+	}
+	it := NewIter(Encode(String("foo")))
+	if it.Next() || it.Err() == nil {
+		t.Error("expected error for non-list input")
+	}
+}
@@ -0,0 +1,74 @@
+package rlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Encodes i as nested JSON arrays of 0x-prefixed hex strings.
+// For example: ["0x636174", ["0x01"]]
+func (i Item) MarshalJSON() ([]byte, error) {
+	if i.l == nil && i.d != nil {
+		return json.Marshal("0x" + hex.EncodeToString(i.d))
+	}
+	l := i.l
+	if l == nil {
+		l = []Item{}
+	}
+	return json.Marshal(l)
+}
+
+// Decodes the format produced by [Item.MarshalJSON]. To load
+// the vectors in ethereum/tests the following are also accepted:
+//   - strings without a 0x prefix are decoded as text
+//   - strings prefixed with # are decoded as base 10 big integers
+//   - non-negative integers
+func (i *Item) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return errNoBytes
+	}
+	switch b[0] {
+	case '[':
+		var l []Item
+		if err := json.Unmarshal(b, &l); err != nil {
+			return err
+		}
+		*i = List(l...)
+		return nil
+	case '"':
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(s, "0x"):
+			d, err := hex.DecodeString(s[2:])
+			if err != nil {
+				return err
+			}
+			*i = Bytes(d)
+		case strings.HasPrefix(s, "#"):
+			n, ok := new(big.Int).SetString(s[1:], 10)
+			if !ok || n.Sign() < 0 {
+				return fmt.Errorf("invalid big integer: %s", s)
+			}
+			*i = BigInt(n)
+		default:
+			*i = String(s)
+		}
+		return nil
+	default:
+		n, ok := new(big.Int).SetString(string(b), 10)
+		if !ok || n.Sign() < 0 {
+			return errors.New("item must be a list, string, or non-negative integer")
+		}
+		*i = BigInt(n)
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package rlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestJSON(t *testing.T) {
+	it := List(String("cat"), List(), List(Byte(1), Bytes(nil)))
+	b, err := json.Marshal(it)
+	tc.NoErr(t, err)
+	const want = `["0x636174",[],["0x01","0x"]]`
+	if string(b) != want {
+		t.Errorf("want: %s got: %s", want, b)
+	}
+	var got Item
+	tc.NoErr(t, json.Unmarshal(b, &got))
+	if !reflect.DeepEqual(it, got) {
+		t.Errorf("want: %v got: %v", it, got)
+	}
+}
+
+// A subset of ethereum/tests RLPTests/rlptest.json
+const ethTests = `{
+	"emptystring": {"in": "", "out": "0x80"},
+	"bytestring00": {"in": "\u0000", "out": "0x00"},
+	"shortstring": {"in": "dog", "out": "0x83646f67"},
+	"zero": {"in": 0, "out": "0x80"},
+	"mediumint1": {"in": 100000, "out": "0x830186a0"},
+	"bigint": {"in": "#83729609699884896815286331701780722", "out": "0x8f102030405060708090a0b0c0d0e0f2"},
+	"emptylist": {"in": [], "out": "0xc0"},
+	"stringlist": {"in": ["dog", "god", "cat"], "out": "0xcc83646f6783676f6483636174"},
+	"multilist": {"in": ["zw", [4], 1], "out": "0xc6827a77c10401"},
+	"listsoflists2": {"in": [[], [[]], [[], [[]]]], "out": "0xc7c0c1c0c3c0c1c0"}
+}`
+
+func TestJSON_EthereumTests(t *testing.T) {
+	var tests map[string]struct {
+		In  Item   `json:"in"`
+		Out string `json:"out"`
+	}
+	tc.NoErr(t, json.Unmarshal([]byte(ethTests), &tests))
+	for name, c := range tests {
+		want, err := hex.DecodeString(c.Out[2:])
+		tc.NoErr(t, err)
+		if got := Encode(c.In); !bytes.Equal(want, got) {
+			t.Errorf("%s want: %x got: %x", name, want, got)
+		}
+	}
+}
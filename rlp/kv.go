@@ -0,0 +1,21 @@
+package rlp
+
+import "sort"
+
+// Returns m as a flat list of key/value pairs
+// sorted by key: [k1, v1, k2, v2, ...]
+// The result is deterministic regardless of map
+// ordering, as required by ENR records. Callers
+// prepend any fixed items (eg seq) to the result.
+func KV(m map[string]Item) []Item {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]Item, 0, 2*len(m))
+	for _, k := range keys {
+		items = append(items, String(k), m[k])
+	}
+	return items
+}
@@ -0,0 +1,24 @@
+package rlp
+
+import "testing"
+
+func TestKV(t *testing.T) {
+	got := List(KV(map[string]Item{
+		"udp":       Uint16(30303),
+		"id":        String("v4"),
+		"secp256k1": Bytes([]byte{0x02}),
+		"ip":        Bytes([]byte{127, 0, 0, 1}),
+	})...)
+	want := List(
+		String("id"), String("v4"),
+		String("ip"), Bytes([]byte{127, 0, 0, 1}),
+		String("secp256k1"), Bytes([]byte{0x02}),
+		String("udp"), Uint16(30303),
+	)
+	if d := Diff(want, got); d != "" {
+		t.Error(d)
+	}
+	if len(KV(nil)) != 0 {
+		t.Error("expected empty list for nil map")
+	}
+}
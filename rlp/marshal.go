@@ -0,0 +1,311 @@
+package rlp
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/indexsupply/x/bint"
+	"github.com/indexsupply/x/isxerrors"
+)
+
+var errLeadingZero = errors.New("rlp: non-canonical integer with leading zero")
+
+var (
+	bigIntType = reflect.TypeOf(big.Int{})
+	itemType   = reflect.TypeOf(Item{})
+)
+
+// Marshal encodes v using reflection. It is a convenience
+// for types like block headers and transactions where
+// building an [Item] tree by hand is verbose.
+//
+// Types are mapped as follows:
+//   - struct: list of exported fields in declaration order
+//   - slice, array: list of elements
+//   - []byte, [N]byte: bytes
+//   - string: bytes
+//   - bool: 0x01 or empty bytes
+//   - uint8 - uint64, big.Int: big-endian bytes with no leading zeros
+//   - pointer: the value it points to. nil pointers to structs
+//     encode as an empty list, other nil pointers encode the zero value
+//   - Item: encoded as-is
+//
+// Struct fields can be skipped with the tag `rlp:"-"`.
+// Trailing fields tagged with `rlp:"optional"` are omitted
+// when they, and all the fields that follow them, are zero.
+func Marshal(v any) ([]byte, error) {
+	it, err := marshal(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return Encode(it), nil
+}
+
+func marshal(v reflect.Value) (Item, error) {
+	if !v.IsValid() {
+		return Bytes(nil), nil
+	}
+	switch v.Type() {
+	case itemType:
+		return v.Interface().(Item), nil
+	case bigIntType:
+		n := v.Interface().(big.Int)
+		if n.Sign() < 0 {
+			return Item{}, errors.New("rlp: cannot marshal negative big.Int")
+		}
+		return Bytes(n.Bytes()), nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		switch {
+		case v.IsNil() && isStructPtr(v.Type()):
+			return List(), nil
+		case v.IsNil():
+			return marshal(reflect.Zero(v.Type().Elem()))
+		}
+		return marshal(v.Elem())
+	case reflect.Interface:
+		return marshal(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			return Byte(1), nil
+		}
+		return Byte(0), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return Uint64(v.Uint()), nil
+	case reflect.String:
+		return String(v.String()), nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return Bytes(b), nil
+		}
+		items := make([]Item, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			it, err := marshal(v.Index(i))
+			if err != nil {
+				return Item{}, isxerrors.Errorf("index %d: %w", i, err)
+			}
+			items[i] = it
+		}
+		return List(items...), nil
+	case reflect.Struct:
+		fs, err := fields(v.Type())
+		if err != nil {
+			return Item{}, err
+		}
+		// omit trailing optional fields with zero values
+		for len(fs) > 0 && fs[len(fs)-1].optional && v.Field(fs[len(fs)-1].index).IsZero() {
+			fs = fs[:len(fs)-1]
+		}
+		var items []Item
+		for _, f := range fs {
+			it, err := marshal(v.Field(f.index))
+			if err != nil {
+				return Item{}, isxerrors.Errorf("field %s: %w", f.name, err)
+			}
+			items = append(items, it)
+		}
+		return List(items...), nil
+	default:
+		return Item{}, fmt.Errorf("rlp: unsupported type %s", v.Type())
+	}
+}
+
+// Unmarshal decodes b into v, which must be a non-nil pointer.
+// See [Marshal] for how types are mapped. Integers
+// with leading zeros are rejected. Optional fields missing
+// from the end of a list are set to their zero value.
+//
+// Decoded byte slices are copied and therefore do
+// not reference b.
+func Unmarshal(b []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("rlp: unmarshal requires a non-nil pointer")
+	}
+	it, err := Decode(b)
+	if err != nil {
+		return err
+	}
+	return unmarshal(it, rv.Elem())
+}
+
+func unmarshal(it Item, v reflect.Value) error {
+	switch v.Type() {
+	case itemType:
+		v.Set(reflect.ValueOf(it))
+		return nil
+	case bigIntType:
+		if it.l != nil {
+			return errors.New("rlp: expected bytes for big.Int. got list")
+		}
+		if len(it.d) > 0 && it.d[0] == 0 {
+			return errLeadingZero
+		}
+		v.Addr().Interface().(*big.Int).SetBytes(it.d)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if isStructPtr(v.Type()) && it.l != nil && len(it.l) == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshal(it, v.Elem())
+	case reflect.Bool:
+		switch {
+		case it.l != nil:
+			return errors.New("rlp: expected bytes for bool. got list")
+		case len(it.d) == 0:
+			v.SetBool(false)
+		case len(it.d) == 1 && it.d[0] == 1:
+			v.SetBool(true)
+		default:
+			return fmt.Errorf("rlp: invalid bool %x", it.d)
+		}
+		return nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		if it.l != nil {
+			return errors.New("rlp: expected bytes for uint. got list")
+		}
+		if len(it.d) > int(v.Type().Size()) {
+			return fmt.Errorf("rlp: %d bytes overflows %s", len(it.d), v.Type())
+		}
+		if len(it.d) > 0 && it.d[0] == 0 {
+			return errLeadingZero
+		}
+		v.SetUint(bint.Decode(it.d))
+		return nil
+	case reflect.String:
+		if it.l != nil {
+			return errors.New("rlp: expected bytes for string. got list")
+		}
+		v.SetString(string(it.d))
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if it.l != nil {
+				return errors.New("rlp: expected bytes for []byte. got list")
+			}
+			v.SetBytes(append([]byte{}, it.d...))
+			return nil
+		}
+		if it.l == nil {
+			return fmt.Errorf("rlp: expected list for %s. got bytes", v.Type())
+		}
+		s := reflect.MakeSlice(v.Type(), len(it.l), len(it.l))
+		for i := range it.l {
+			if err := unmarshal(it.l[i], s.Index(i)); err != nil {
+				return isxerrors.Errorf("index %d: %w", i, err)
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if it.l != nil {
+				return fmt.Errorf("rlp: expected bytes for %s. got list", v.Type())
+			}
+			if len(it.d) != v.Len() {
+				return fmt.Errorf("rlp: expected %d bytes for %s. got %d", v.Len(), v.Type(), len(it.d))
+			}
+			reflect.Copy(v, reflect.ValueOf(it.d))
+			return nil
+		}
+		if len(it.l) != v.Len() {
+			return fmt.Errorf("rlp: expected list of %d for %s. got %d", v.Len(), v.Type(), len(it.l))
+		}
+		for i := range it.l {
+			if err := unmarshal(it.l[i], v.Index(i)); err != nil {
+				return isxerrors.Errorf("index %d: %w", i, err)
+			}
+		}
+		return nil
+	case reflect.Struct:
+		if it.l == nil {
+			return fmt.Errorf("rlp: expected list for %s. got bytes", v.Type())
+		}
+		fs, err := fields(v.Type())
+		if err != nil {
+			return err
+		}
+		var required int
+		for _, f := range fs {
+			if !f.optional {
+				required++
+			}
+		}
+		if len(it.l) < required || len(it.l) > len(fs) {
+			return fmt.Errorf("rlp: expected list of %d for %s. got %d", len(fs), v.Type(), len(it.l))
+		}
+		for i, f := range fs {
+			if i >= len(it.l) {
+				v.Field(f.index).Set(reflect.Zero(f.typ))
+				continue
+			}
+			if err := unmarshal(it.l[i], v.Field(f.index)); err != nil {
+				return isxerrors.Errorf("field %s: %w", f.name, err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("rlp: unsupported type %s", v.Type())
+	}
+}
+
+// big.Int is a struct but it is encoded as bytes
+func isStructPtr(t reflect.Type) bool {
+	return t.Elem().Kind() == reflect.Struct && t.Elem() != bigIntType
+}
+
+type field struct {
+	name     string
+	index    int
+	typ      reflect.Type
+	optional bool
+}
+
+// Returns the exported fields of t that are
+// not tagged with `rlp:"-"`. Fields tagged with
+// `rlp:"optional"` must not precede required fields.
+func fields(t reflect.Type) ([]field, error) {
+	var (
+		res      []field
+		optional bool
+	)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("rlp")
+		if hasTag(tag, "-") {
+			continue
+		}
+		switch {
+		case hasTag(tag, "optional"):
+			optional = true
+		case optional:
+			return nil, fmt.Errorf("rlp: %s.%s must be optional since it follows an optional field", t, f.Name)
+		}
+		res = append(res, field{name: f.Name, index: i, typ: f.Type, optional: optional})
+	}
+	return res, nil
+}
+
+func hasTag(tag, opt string) bool {
+	for _, s := range strings.Split(tag, ",") {
+		if strings.TrimSpace(s) == opt {
+			return true
+		}
+	}
+	return false
+}
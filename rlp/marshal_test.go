@@ -0,0 +1,186 @@
+package rlp
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+type testHeader struct {
+	Parent  [32]byte
+	Number  uint64
+	Gas     uint32
+	Extra   []byte
+	Diff    *big.Int
+	Final   bool
+	Name    string
+	Nested  testNested
+	Nums    []uint16
+	Ignored string `rlp:"-"`
+	private int
+}
+
+type testNested struct {
+	A [2]uint8
+	B *testNested
+}
+
+func TestMarshal(t *testing.T) {
+	h := testHeader{
+		Parent: [32]byte{1},
+		Number: 1024,
+		Gas:    0,
+		Extra:  []byte("extra"),
+		Diff:   big.NewInt(17179869184),
+		Final:  true,
+		Name:   "foo",
+		Nested: testNested{A: [2]uint8{1, 2}},
+		Nums:   []uint16{1, 2, 3},
+	}
+	got, err := Marshal(h)
+	tc.NoErr(t, err)
+	want := Encode(List(
+		Bytes(h.Parent[:]),
+		Uint64(1024),
+		Uint64(0),
+		String("extra"),
+		Bytes(big.NewInt(17179869184).Bytes()),
+		Byte(1),
+		String("foo"),
+		List(Bytes([]byte{1, 2}), List()),
+		List(Uint64(1), Uint64(2), Uint64(3)),
+	))
+	if !bytes.Equal(want, got) {
+		t.Errorf("want:\n%x\ngot:\n%x\n", want, got)
+	}
+}
+
+func TestMarshal_NilPointer(t *testing.T) {
+	got, err := Marshal(struct{ N *testNested }{})
+	tc.NoErr(t, err)
+	want := Encode(List(List()))
+	if !bytes.Equal(want, got) {
+		t.Errorf("want: %x got: %x", want, got)
+	}
+}
+
+func TestMarshal_NilBigInt(t *testing.T) {
+	got, err := Marshal(struct{ N *big.Int }{})
+	tc.NoErr(t, err)
+	want := Encode(List(Uint64(0)))
+	if !bytes.Equal(want, got) {
+		t.Errorf("want: %x got: %x", want, got)
+	}
+	var v struct{ N *big.Int }
+	tc.NoErr(t, Unmarshal(got, &v))
+	if v.N == nil || v.N.Sign() != 0 {
+		t.Errorf("want 0 got: %v", v.N)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	want := testHeader{
+		Parent: [32]byte{1},
+		Number: 1024,
+		Extra:  []byte("extra"),
+		Diff:   big.NewInt(17179869184),
+		Final:  true,
+		Name:   "foo",
+		Nested: testNested{A: [2]uint8{1, 2}, B: &testNested{A: [2]uint8{3, 4}}},
+		Nums:   []uint16{1, 2, 3},
+	}
+	b, err := Marshal(want)
+	tc.NoErr(t, err)
+	var got testHeader
+	tc.NoErr(t, Unmarshal(b, &got))
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%#v\ngot:\n%#v\n", want, got)
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	cases := []struct {
+		desc  string
+		input []byte
+		dest  any
+	}{
+		{
+			"uint overflow",
+			Encode(Uint64(1 << 16)),
+			new(uint8),
+		},
+		{
+			"list into bytes",
+			Encode(List()),
+			new([]byte),
+		},
+		{
+			"short array",
+			Encode(Bytes([]byte{1})),
+			new([2]byte),
+		},
+		{
+			"too few fields",
+			Encode(List(Uint64(1))),
+			new(struct{ A, B uint64 }),
+		},
+		{
+			"invalid bool",
+			Encode(Uint64(2)),
+			new(bool),
+		},
+		{
+			"too many fields for optional",
+			Encode(List(Uint64(1), Uint64(2), Uint64(3))),
+			new(testOptional),
+		},
+		{
+			"required after optional",
+			Encode(List(Uint64(1))),
+			new(struct {
+				A uint64 `rlp:"optional"`
+				B uint64
+			}),
+		},
+		{
+			"non-pointer",
+			Encode(Uint64(1)),
+			uint64(0),
+		},
+	}
+	for _, c := range cases {
+		if err := Unmarshal(c.input, c.dest); err == nil {
+			t.Errorf("%s: expected error", c.desc)
+		}
+	}
+}
+
+type testOptional struct {
+	A uint64
+	B *big.Int `rlp:"optional"`
+}
+
+func TestOptional(t *testing.T) {
+	cases := []struct {
+		v    testOptional
+		want Item
+	}{
+		{testOptional{A: 1}, List(Uint64(1))},
+		{testOptional{A: 1, B: big.NewInt(2)}, List(Uint64(1), Uint64(2))},
+	}
+	for _, c := range cases {
+		b, err := Marshal(c.v)
+		tc.NoErr(t, err)
+		if !bytes.Equal(Encode(c.want), b) {
+			t.Errorf("want: %x got: %x", Encode(c.want), b)
+		}
+		got := testOptional{B: big.NewInt(42)}
+		tc.NoErr(t, Unmarshal(b, &got))
+		if !reflect.DeepEqual(c.v, got) {
+			t.Errorf("want: %#v got: %#v", c.v, got)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package rlp
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errNotList = errors.New("path traverses a non-list item")
+
+// Decodes the element of b at path without decoding its
+// siblings. Each element of path is an index into a list.
+// For example, the logs of a receipt [status, gas, bloom, logs]
+// are at DecodeAt(b, 3). An empty path decodes all of b.
+func DecodeAt(b []byte, path ...int) (Item, error) {
+	for depth, idx := range path {
+		hs, ps, err := header(b)
+		if err != nil {
+			return Item{}, err
+		}
+		if len(b) < hs+ps {
+			return Item{}, errTooFewBytes
+		}
+		if b[0] < list55L {
+			return Item{}, fmt.Errorf("%w at depth %d", errNotList, depth)
+		}
+		b = b[hs : hs+ps]
+		for i := 0; ; i++ {
+			if len(b) == 0 {
+				return Item{}, fmt.Errorf("index %d out of range at depth %d", idx, depth)
+			}
+			hs, ps, err = header(b)
+			if err != nil {
+				return Item{}, err
+			}
+			if len(b) < hs+ps {
+				return Item{}, errTooFewBytes
+			}
+			if i == idx {
+				b = b[:hs+ps]
+				break
+			}
+			b = b[hs+ps:]
+		}
+	}
+	return Decode(b)
+}
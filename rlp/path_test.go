@@ -0,0 +1,37 @@
+package rlp
+
+import (
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestDecodeAt(t *testing.T) {
+	var (
+		logs    = List(List(String("a")), List(String("b"), String("c")))
+		receipt = List(Uint64(1), Uint64(21000), Bytes(randBytes(256)), logs)
+		b       = Encode(receipt)
+	)
+	cases := []struct {
+		path []int
+		want Item
+	}{
+		{nil, receipt},
+		{[]int{0}, Uint64(1)},
+		{[]int{3}, logs},
+		{[]int{3, 1}, List(String("b"), String("c"))},
+		{[]int{3, 1, 1}, String("c")},
+	}
+	for _, c := range cases {
+		got, err := DecodeAt(b, c.path...)
+		tc.NoErr(t, err)
+		if d := Diff(c.want, got); d != "" {
+			t.Errorf("path %v %s", c.path, d)
+		}
+	}
+	for _, path := range [][]int{{4}, {-1}, {0, 0}, {3, 2}} {
+		if _, err := DecodeAt(b, path...); err == nil {
+			t.Errorf("path %v expected error", path)
+		}
+	}
+}
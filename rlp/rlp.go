@@ -5,9 +5,13 @@
 package rlp
 
 import (
+	"bytes"
 	"errors"
+	"io"
+	"sync"
 
 	"github.com/indexsupply/x/bint"
+	"github.com/indexsupply/x/isxhash"
 )
 
 const (
@@ -25,8 +29,10 @@ func List(items ...Item) Item {
 	return Item{l: items}
 }
 
+// Returns the item at pos in i's list or the
+// zero Item when pos is out of range.
 func (i Item) At(pos int) Item {
-	if len(i.l) < pos {
+	if pos < 0 || pos >= len(i.l) {
 		return Item{}
 	}
 	return i.l[pos]
@@ -36,65 +42,217 @@ func (i Item) List() []Item {
 	return i.l
 }
 
+// Returns the bytes that i was decoded from, including
+// the header. Use this to hash or forward a nested
+// encoding exactly as it was received rather than
+// re-encoding it. Returns nil if i wasn't decoded.
+// The returned slice shares memory with i.
+func (i Item) Raw() []byte {
+	return i.r
+}
+
+// Returns a deep copy of i that shares no
+// memory with i.
+func (i Item) Copy() Item {
+	var r []byte
+	if i.r != nil {
+		r = append([]byte{}, i.r...)
+	}
+	if i.l == nil {
+		if i.d == nil {
+			return Item{}
+		}
+		if r != nil {
+			return Item{d: r[len(r)-len(i.d):], r: r}
+		}
+		return Item{d: append([]byte{}, i.d...)}
+	}
+	l := make([]Item, len(i.l))
+	for j := range i.l {
+		l[j] = i.l[j].Copy()
+	}
+	return Item{l: l, r: r}
+}
+
+// Reports whether i and j contain the same data without
+// encoding them. A zero Item is equal to an empty list.
+func (i Item) Equal(j Item) bool {
+	iList := i.l != nil || i.d == nil
+	jList := j.l != nil || j.d == nil
+	switch {
+	case iList != jList:
+		return false
+	case !iList:
+		return bytes.Equal(i.d, j.d)
+	case len(i.l) != len(j.l):
+		return false
+	}
+	for k := range i.l {
+		if !i.l[k].Equal(j.l[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the keccak hash of i's encoding.
+// Not to be confused with [Item.Hash] which
+// returns i's data as a 32 byte array.
+func (i Item) Keccak32() [32]byte {
+	return isxhash.Keccak32(AppendEncode(make([]byte, 0, EncodedLen(i)), i))
+}
+
 // Instead of using standard data types and reflection
 // this package chooses to encode Items.
 // Set d or l but not both.
 // l is a list of Item for arbitrarily nested lists.
 // d is the data payload for the item.
+//
+// d may alias the slice that an Item was built from.
+// For example: Bytes(b) and [DecodeZeroCopy] reference b.
+// Use [Item.Copy] for an Item that references no external memory.
 type Item struct {
 	d []byte
 	l []Item
+	r []byte // encoding of a decoded Item
 }
 
+// Encodes input into a single allocation. List payload
+// sizes are computed in one pass and the encoding is
+// written in a second pass so that nested lists are
+// never copied into their parents.
 func Encode(input Item) []byte {
-	if input.d != nil && input.l != nil {
+	var buf [16]int // avoids allocating sizes for most items
+	n, sizes := listSizes(input, buf[:0])
+	b, _ := appendEncode(make([]byte, 0, n), input, sizes)
+	return b
+}
+
+// Appends the encoding of it to dst and returns the
+// extended slice. Use this to reuse buffers across calls.
+func AppendEncode(dst []byte, it Item) []byte {
+	var buf [16]int
+	_, sizes := listSizes(it, buf[:0])
+	dst, _ = appendEncode(dst, it, sizes)
+	return dst
+}
+
+// Returns the encoded length of it and appends the
+// payload size of it and each nested list (depth first)
+// to sizes.
+func listSizes(it Item, sizes []int) (int, []int) {
+	if it.d != nil && it.l != nil {
 		panic("must set d xor l")
 	}
-	if input.d != nil {
-		switch n := len(input.d); {
-		case n == 1 && input.d[0] == 0:
-			return []byte{0x80}
-		case n == 1 && input.d[0] <= str1H:
-			return input.d
+	if it.d != nil {
+		return EncodedLen(it), sizes
+	}
+	var (
+		n   int
+		pos = len(sizes)
+	)
+	sizes = append(sizes, 0)
+	for i := range it.l {
+		var m int
+		m, sizes = listSizes(it.l[i], sizes)
+		n += m
+	}
+	sizes[pos] = n
+	if n <= 55 {
+		return 1 + n, sizes
+	}
+	return 1 + lengthSize(n) + n, sizes
+}
+
+// Uses the list sizes computed by [listSizes]
+// and returns the sizes that weren't consumed.
+func appendEncode(dst []byte, it Item, sizes []int) ([]byte, []int) {
+	if it.d != nil {
+		switch n := len(it.d); {
+		case n == 1 && it.d[0] <= str1H:
+			return append(dst, it.d[0]), sizes
 		case n <= 55:
-			return append(
-				[]byte{str55L + byte(n)},
-				input.d...,
-			)
+			dst = append(dst, str55L+byte(n))
+			return append(dst, it.d...), sizes
 		default:
-			length, lengthSize := encodeLength(len(input.d))
-			header := append(
-				[]byte{str55H + lengthSize},
-				length...,
-			)
-			return append(header, input.d...)
+			dst = append(dst, str55H+byte(lengthSize(n)))
+			dst = appendLength(dst, n)
+			return append(dst, it.d...), sizes
 		}
 	}
+	n := sizes[0]
+	sizes = sizes[1:]
+	if n <= 55 {
+		dst = append(dst, list55L+byte(n))
+	} else {
+		dst = append(dst, list55H+byte(lengthSize(n)))
+		dst = appendLength(dst, n)
+	}
+	for i := range it.l {
+		dst, sizes = appendEncode(dst, it.l[i], sizes)
+	}
+	return dst, sizes
+}
 
-	var out []byte
-	for _, l := range input.l {
-		out = append(out, Encode(l)...)
+// Appends the big-endian encoding of n
+// without leading zeros
+func appendLength(dst []byte, n int) []byte {
+	for s := lengthSize(n) - 1; s >= 0; s-- {
+		dst = append(dst, byte(n>>(8*s)))
 	}
-	if len(out) <= 55 {
-		return append(
-			[]byte{list55L + byte(len(out))},
-			out...,
-		)
+	return dst
+}
+
+// Buffers used by EncodeTo. Buffers larger
+// than maxPooled aren't returned to the pool
+// so that one large item doesn't pin memory.
+var bufPool = sync.Pool{New: func() any { return new([]byte) }}
+
+const maxPooled = 64 << 10
+
+// Writes the encoding of it to w using a pooled buffer.
+// Use [AppendEncode] to supply the buffer instead.
+func EncodeTo(w io.Writer, it Item) error {
+	bp := bufPool.Get().(*[]byte)
+	*bp = AppendEncode((*bp)[:0], it)
+	_, err := w.Write(*bp)
+	if cap(*bp) <= maxPooled {
+		bufPool.Put(bp)
 	}
-	length, lengthSize := encodeLength(len(out))
-	header := append(
-		[]byte{list55H + lengthSize},
-		length...,
-	)
-	return append(header, out...)
+	return err
 }
 
-func encodeLength(n int) ([]byte, uint8) {
-	if n == 0 {
-		return []byte{}, 0
+// Returns the size of the encoding of it without encoding it.
+// Use this to allocate a buffer for [AppendEncode].
+func EncodedLen(it Item) int {
+	if it.d != nil {
+		switch n := len(it.d); {
+		case n == 1 && it.d[0] <= str1H:
+			return 1
+		case n <= 55:
+			return 1 + n
+		default:
+			return 1 + lengthSize(n) + n
+		}
+	}
+	var n int
+	for i := range it.l {
+		n += EncodedLen(it.l[i])
 	}
-	b := bint.Encode(nil, uint64(n))
-	return b, uint8(len(b))
+	if n <= 55 {
+		return 1 + n
+	}
+	return 1 + lengthSize(n) + n
+}
+
+// number of bytes required for the
+// big-endian encoding of n
+func lengthSize(n int) int {
+	var s int
+	for ; n > 0; n >>= 8 {
+		s++
+	}
+	return s
 }
 
 // Returns two values representing the length of the
@@ -110,84 +268,142 @@ var (
 	errTooFewBytes = errors.New("input has fewer bytes than specified by header")
 )
 
-func Decode(input []byte) (Item, error) {
-	if len(input) == 0 {
-		return Item{}, errNoBytes
+// Returns the size of the header and payload
+// of the first item in input. Returns errTooFewBytes
+// when input is shorter than the header specifies.
+func header(input []byte) (int, int, error) {
+	hs, ps, err := parseHeader(input)
+	if err != nil {
+		return 0, 0, err
 	}
+	// hs+ps could overflow
+	if ps > len(input)-hs {
+		return 0, 0, errTooFewBytes
+	}
+	return hs, ps, nil
+}
+
+// Like header but input only needs to contain the header
+func parseHeader(input []byte) (int, int, error) {
 	switch {
+	case len(input) == 0:
+		return 0, 0, errNoBytes
 	case input[0] <= str1H:
-		return Item{d: []byte{input[0]}}, nil
+		return 0, 1, nil
 	case input[0] <= str55H:
-		i, n := 1, int(input[0]-str55L)
-		if len(input) < i+n {
-			return Item{}, errTooFewBytes
-		}
-		return Item{d: input[i : i+n]}, nil
+		return 1, int(input[0] - str55L), nil
 	case input[0] <= strNH:
-		i, n := decodeLength(str55H, input)
-		if len(input) < i+n {
-			return Item{}, errTooFewBytes
+		if len(input) <= int(input[0]-str55H) {
+			return 0, 0, errTooFewBytes
+		}
+		hs, ps := decodeLength(str55H, input)
+		if ps < 0 {
+			return 0, 0, errTooFewBytes
 		}
-		return Item{d: input[i : i+n]}, nil
+		return hs, ps, nil
+	case input[0] <= list55H:
+		return 1, int(input[0] - list55L), nil
 	default:
-		// The first byte indicates a list
-		// and if the first byte is >= 248 (listNL)
-		// then the list has a length > 55 and
-		// therefore the next (input[0]-247 (list55H))
-		// bytes will describe the length of the list.
-		// We advance the cursor i past the length description.
-		// We also compute the size of the list and check the input
-		// to ensure the size matches the header's length description.
-		var i, listSize int
-		switch {
-		case input[0] <= list55H:
-			i, listSize = 1, int(input[0]-list55L)
-		case input[0] <= listNH:
-			i, listSize = decodeLength(list55H, input)
+		if len(input) <= int(input[0]-list55H) {
+			return 0, 0, errTooFewBytes
+		}
+		hs, ps := decodeLength(list55H, input)
+		if ps < 0 {
+			return 0, 0, errTooFewBytes
 		}
+		return hs, ps, nil
+	}
+}
+
+// Decodes input into an Item. The input is copied
+// so that the returned Item doesn't reference input
+// and therefore input may be reused by the caller.
+// See [DecodeZeroCopy] for avoiding the copy.
+func Decode(input []byte) (Item, error) {
+	return new(decoder).decode(append([]byte(nil), input...), 0)
+}
+
+// Like [Decode] except the returned Item's data references input.
+// The caller must not modify input while the Item is in use.
+// Use [Item.Copy] to detach the Item from input.
+func DecodeZeroCopy(input []byte) (Item, error) {
+	return new(decoder).decode(input, 0)
+}
+
+// Bounds the work done when decoding untrusted input.
+// Zero values indicate no limit.
+type Limits struct {
+	// Maximum nesting of lists. A list that
+	// contains no lists has a depth of 1.
+	MaxDepth int
+
+	// Maximum sum of the lengths of
+	// all the data in the decoded Items.
+	MaxPayload int
+}
+
+var (
+	errMaxDepth   = errors.New("input exceeds max list depth")
+	errMaxPayload = errors.New("input exceeds max payload size")
+)
 
-		switch {
-		case len(input[i:]) < listSize:
+// Like [Decode] but returns an error when input exceeds l.
+// Network facing code should use this to prevent
+// stack exhaustion and memory DoS from hostile peers.
+func DecodeWithLimits(input []byte, l Limits) (Item, error) {
+	dec := &decoder{Limits: l}
+	return dec.decode(append([]byte(nil), input...), 0)
+}
+
+type decoder struct {
+	Limits
+	payload int
+	arena   *Arena
+}
+
+// depth is the number of lists that contain input
+func (dec *decoder) decode(input []byte, depth int) (Item, error) {
+	hs, ps, err := header(input)
+	if err != nil {
+		return Item{}, err
+	}
+	if len(input) < hs+ps {
+		return Item{}, errTooFewBytes
+	}
+	if input[0] < list55L {
+		dec.payload += ps
+		if dec.MaxPayload > 0 && dec.payload > dec.MaxPayload {
+			return Item{}, errMaxPayload
+		}
+		return Item{d: input[hs : hs+ps], r: input[:hs+ps]}, nil
+	}
+	if dec.MaxDepth > 0 && depth+1 > dec.MaxDepth {
+		return Item{}, errMaxDepth
+	}
+	// It's possible that the input contains
+	// more bytes that is specified by the
+	// header's length. In this case, instead
+	// of returning an error, we simply remove
+	// the extra bytes.
+	item := Item{l: []Item{}, r: input[:hs+ps]}
+	input = input[hs : hs+ps]
+	if dec.arena != nil {
+		item.l = dec.arena.alloc(count(input))
+	}
+	for len(input) > 0 {
+		hs, ps, err := header(input)
+		if err != nil {
+			return Item{}, err
+		}
+		if len(input) < hs+ps {
 			return Item{}, errTooFewBytes
-		case len(input[i:]) > listSize:
-			// It's possible that the input contains
-			// more bytes that is specified by the
-			// header's length. In this case, instead
-			// of returning an error, we simply remove
-			// the extra bytes.
-			input = input[:i+listSize]
 		}
-
-		item := Item{l: []Item{}}
-		for i < len(input) {
-			var headerSize, payloadSize int
-			switch {
-			case input[i] <= str1H:
-				headerSize = 0
-				payloadSize = 1
-			case input[i] <= str55H:
-				headerSize = 1
-				payloadSize = int(input[i] - str55L)
-			case input[i] <= strNH:
-				headerSize, payloadSize = decodeLength(str55H, input[i:])
-			case input[i] <= list55H:
-				headerSize = 1
-				payloadSize = int(input[i] - list55L)
-			default:
-				headerSize, payloadSize = decodeLength(list55H, input[i:])
-			}
-
-			if int(i+headerSize+payloadSize) > len(input) {
-				return Item{}, errTooFewBytes
-			}
-
-			d, err := Decode(input[i : i+headerSize+payloadSize])
-			if err != nil {
-				return Item{}, err
-			}
-			item.l = append(item.l, d)
-			i += headerSize + payloadSize
+		d, err := dec.decode(input[:hs+ps], depth+1)
+		if err != nil {
+			return Item{}, err
 		}
-		return item, nil
+		item.l = append(item.l, d)
+		input = input[hs+ps:]
 	}
+	return item, nil
 }
@@ -3,9 +3,10 @@ package rlp
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"reflect"
+	"io"
 	"testing"
 
 	"github.com/indexsupply/x/tc"
@@ -45,8 +46,8 @@ func FuzzEncode(f *testing.F) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if !reflect.DeepEqual(item, got) {
-			t.Errorf("want:\n%v\ngot:\n%v\n", item, got)
+		if d := Diff(item, got); d != "" {
+			t.Error(d)
 		}
 	})
 }
@@ -59,6 +60,62 @@ func BenchmarkEncode(b *testing.B) {
 	}
 }
 
+func BenchmarkEncodeTo(b *testing.B) {
+	it := headerItem()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		EncodeTo(io.Discard, it)
+	}
+}
+
+// Roughly the shape of a block header
+func headerItem() Item {
+	return List(
+		Bytes(randBytes(32)),
+		Bytes(randBytes(32)),
+		Bytes(randBytes(20)),
+		Bytes(randBytes(32)),
+		Bytes(randBytes(32)),
+		Bytes(randBytes(32)),
+		Bytes(randBytes(256)),
+		Uint64(0),
+		Uint64(17_000_000),
+		Uint64(30_000_000),
+		Uint64(12_345_678),
+		Uint64(1_700_000_000),
+		Bytes(randBytes(32)),
+		Bytes(randBytes(32)),
+		Bytes(randBytes(8)),
+		Uint64(7),
+		Bytes(randBytes(32)),
+	)
+}
+
+func BenchmarkEncode_Header(b *testing.B) {
+	it := headerItem()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		Encode(it)
+	}
+}
+
+func BenchmarkEncode_Block(b *testing.B) {
+	var txs []Item
+	for i := 0; i < 100; i++ {
+		txs = append(txs, List(
+			Uint64(uint64(i)),
+			Bytes(randBytes(20)),
+			Bytes(randBytes(100)),
+			List(List(Bytes(randBytes(20)), List(Bytes(randBytes(32))))),
+		))
+	}
+	it := List(headerItem(), List(txs...), List())
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		Encode(it)
+	}
+}
+
 func randBytes(n int) []byte {
 	res := make([]byte, n)
 	rand.Read(res)
@@ -113,6 +170,39 @@ func TestDecode_Errors(t *testing.T) {
 	}
 }
 
+// Headers declaring a length near MaxInt64 must not
+// overflow the bounds checks and panic.
+func TestDecode_HugeLength(t *testing.T) {
+	cases := [][]byte{
+		{0xff, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{0xbf, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{0xff, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xf8, 0x00},
+		{0xbf, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xf8, 0x00},
+		{0xc2, 0xbf, 0x7f},
+		{0xca, 0xbf, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	for _, b := range cases {
+		if _, err := Decode(b); !errors.Is(err, errTooFewBytes) {
+			t.Errorf("Decode(%x) want: %v got: %v", b, errTooFewBytes, err)
+		}
+		if _, err := DecodeWithLimits(b, Limits{8, 1 << 20}); !errors.Is(err, errTooFewBytes) {
+			t.Errorf("DecodeWithLimits(%x) want: %v got: %v", b, errTooFewBytes, err)
+		}
+		if _, err := DecodeAt(b, 0); err == nil {
+			t.Errorf("DecodeAt(%x) expected error", b)
+		}
+		if _, err := new(Arena).Decode(b); !errors.Is(err, errTooFewBytes) {
+			t.Errorf("Arena.Decode(%x) want: %v got: %v", b, errTooFewBytes, err)
+		}
+		it := NewIter(b)
+		for it.Next() {
+		}
+		if it.Err() == nil {
+			t.Errorf("Iter(%x) expected error", b)
+		}
+	}
+}
+
 func TestDecodeLength(t *testing.T) {
 	cases := []struct {
 		t              byte
@@ -165,11 +255,15 @@ func TestDecodeLength(t *testing.T) {
 func TestDecodeZero(t *testing.T) {
 	item, err := Decode([]byte{0x80})
 	tc.NoErr(t, err)
-	if item.Uint16() != 0 {
-		t.Errorf("want: 0 got: %d", item.Uint16())
+	n16, err := item.Uint16()
+	tc.NoErr(t, err)
+	if n16 != 0 {
+		t.Errorf("want: 0 got: %d", n16)
 	}
-	if item.Uint64() != 0 {
-		t.Errorf("want: 0 got: %d", item.Uint64())
+	n64, err := item.Uint64()
+	tc.NoErr(t, err)
+	if n64 != 0 {
+		t.Errorf("want: 0 got: %d", n64)
 	}
 }
 
@@ -219,9 +313,48 @@ func TestDecode(t *testing.T) {
 		if err != nil {
 			t.Errorf("error %s: %s", tc.desc, err)
 		}
-		if !reflect.DeepEqual(tc.item, got) {
-			t.Errorf("%s\nwant:\n%# v\ngot:\n%# v\n", tc.desc, tc.item, got)
+		if d := Diff(tc.item, got); d != "" {
+			t.Errorf("%s %s", tc.desc, d)
+		}
+	}
+}
+
+func TestAt_OutOfRange(t *testing.T) {
+	l := List(String("a"))
+	for _, pos := range []int{-1, 1, 2} {
+		if got := l.At(pos); !got.Equal(Item{}) {
+			t.Errorf("At(%d) want zero item got: %v", pos, got)
+		}
+	}
+	if got := List().At(0); !got.Equal(Item{}) {
+		t.Errorf("want zero item got: %v", got)
+	}
+	if got := l.At(0).String(); got != "a" {
+		t.Errorf("want: a got: %s", got)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	inner := List(String("dog"), Bytes(randBytes(60)))
+	b := Encode(List(Byte(1), inner, Byte(0x7f)))
+	for _, decode := range []func([]byte) (Item, error){Decode, DecodeZeroCopy} {
+		got, err := decode(b)
+		tc.NoErr(t, err)
+		if !bytes.Equal(b, got.Raw()) {
+			t.Errorf("want: %x got: %x", b, got.Raw())
+		}
+		if !bytes.Equal(Encode(inner), got.At(1).Raw()) {
+			t.Errorf("nested want: %x got: %x", Encode(inner), got.At(1).Raw())
+		}
+		if !bytes.Equal([]byte{0x7f}, got.At(2).Raw()) {
+			t.Errorf("single byte want: 7f got: %x", got.At(2).Raw())
 		}
+		if c := got.Copy(); !bytes.Equal(b, c.Raw()) || c.At(1).At(0).String() != "dog" {
+			t.Errorf("copy want: %x got: %x", b, c.Raw())
+		}
+	}
+	if String("dog").Raw() != nil {
+		t.Error("expected nil raw for constructed item")
 	}
 }
 
@@ -361,3 +494,157 @@ func TestEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestDecode_Copy(t *testing.T) {
+	b := Encode(List(String("foo"), String("bar")))
+	item, err := Decode(b)
+	tc.NoErr(t, err)
+	zc, err := DecodeZeroCopy(b)
+	tc.NoErr(t, err)
+	detached := zc.Copy()
+
+	for i := range b {
+		b[i] = 0
+	}
+	if got := item.At(0).String(); got != "foo" {
+		t.Errorf("expected decoded item to be unchanged. got: %q", got)
+	}
+	if got := detached.At(1).String(); got != "bar" {
+		t.Errorf("expected copied item to be unchanged. got: %q", got)
+	}
+	if got := zc.At(0).String(); got == "foo" {
+		t.Error("expected zero copy item to reference input")
+	}
+}
+
+func TestAppendEncode(t *testing.T) {
+	items := []Item{
+		Byte(0),
+		Byte(1),
+		Int(1024),
+		String(""),
+		String("Lorem ipsum dolor sit amet, consectetur adipisicing elit"),
+		Bytes(randBytes(1 << 10)),
+		List(),
+		List(String("cat"), String("dog")),
+		List(List(), List(List()), List(List(), List(List()))),
+		List(Bytes(randBytes(100)), List(Bytes(randBytes(200)))),
+	}
+	prefix := []byte{0xff, 0xfe}
+	for _, it := range items {
+		want := Encode(it)
+		got := AppendEncode(prefix, it)
+		if !bytes.Equal(prefix, got[:2]) {
+			t.Errorf("expected prefix to be retained. got: %x", got[:2])
+		}
+		if !bytes.Equal(want, got[2:]) {
+			t.Errorf("want:\n%x\ngot:\n%x\n", want, got[2:])
+		}
+		if EncodedLen(it) != len(want) {
+			t.Errorf("want len: %d got: %d", len(want), EncodedLen(it))
+		}
+		var buf bytes.Buffer
+		tc.NoErr(t, EncodeTo(&buf, it))
+		if !bytes.Equal(want, buf.Bytes()) {
+			t.Errorf("want:\n%x\ngot:\n%x\n", want, buf.Bytes())
+		}
+	}
+}
+
+func TestDecodeWithLimits(t *testing.T) {
+	nested := List(List(List(String("foo"))))
+	cases := []struct {
+		desc   string
+		input  Item
+		limits Limits
+		err    error
+	}{
+		{"no limits", nested, Limits{}, nil},
+		{"within depth", nested, Limits{MaxDepth: 3}, nil},
+		{"exceeds depth", nested, Limits{MaxDepth: 2}, errMaxDepth},
+		{"within payload", nested, Limits{MaxPayload: 3}, nil},
+		{"exceeds payload", List(String("foo"), String("bar")), Limits{MaxPayload: 5}, errMaxPayload},
+	}
+	for _, c := range cases {
+		_, err := DecodeWithLimits(Encode(c.input), c.limits)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: want: %v got: %v", c.desc, c.err, err)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	cases := []struct {
+		a, b Item
+		want bool
+	}{
+		{String("foo"), String("foo"), true},
+		{String("foo"), String("bar"), false},
+		{Bytes(nil), Bytes([]byte{}), true},
+		{Item{}, List(), true},
+		{Bytes(nil), List(), false},
+		{List(String("a"), List(Int(1))), List(String("a"), List(Int(1))), true},
+		{List(String("a"), List(Int(1))), List(String("a"), List(Int(2))), false},
+		{List(String("a")), List(String("a"), String("b")), false},
+	}
+	for _, c := range cases {
+		if got := c.a.Equal(c.b); got != c.want {
+			t.Errorf("%v.Equal(%v) want: %t got: %t", c.a, c.b, c.want, got)
+		}
+		if got := c.a.Keccak32() == c.b.Keccak32(); got != c.want {
+			t.Errorf("%v.Keccak32() == %v.Keccak32() want: %t", c.a, c.b, c.want)
+		}
+	}
+	it := List(String("foo"), List(Bytes(randBytes(64))))
+	if !it.Equal(it.Copy()) {
+		t.Error("expected copy to be equal")
+	}
+}
+
+// Examples from the spec:
+// https://ethereum.org/en/developers/docs/data-structures-and-encoding/rlp/#examples
+func TestSpecVectors(t *testing.T) {
+	var (
+		lorem = "Lorem ipsum dolor sit amet, consectetur adipisicing elit"
+		long  = bytes.Repeat([]byte{0xaa}, 1024)
+	)
+	cases := []struct {
+		desc string
+		item Item
+		want string
+	}{
+		{"dog", String("dog"), "83646f67"},
+		{"cat dog", List(String("cat"), String("dog")), "c88363617483646f67"},
+		{"empty string", String(""), "80"},
+		{"empty list", List(), "c0"},
+		{"integer 0", Uint64(0), "80"},
+		{"byte 0x00", Bytes([]byte{0x00}), "00"},
+		{"byte 0x0f", Bytes([]byte{0x0f}), "0f"},
+		{"bytes 0x0400", Bytes([]byte{0x04, 0x00}), "820400"},
+		{"integer 1024", Uint64(1024), "820400"},
+		{
+			"set theoretical three",
+			List(List(), List(List()), List(List(), List(List()))),
+			"c7c0c1c0c3c0c1c0",
+		},
+		{"lorem", String(lorem), "b838" + hex.EncodeToString([]byte(lorem))},
+		{"1024 bytes", Bytes(long), "b90400" + hex.EncodeToString(long)},
+		{
+			"long list",
+			List(String(lorem), String(lorem)),
+			"f874b838" + hex.EncodeToString([]byte(lorem)) + "b838" + hex.EncodeToString([]byte(lorem)),
+		},
+	}
+	for _, c := range cases {
+		want, err := hex.DecodeString(c.want)
+		tc.NoErr(t, err)
+		if got := Encode(c.item); !bytes.Equal(want, got) {
+			t.Errorf("%s encode want: %x got: %x", c.desc, want, got)
+		}
+		got, err := DecodeStrict(want)
+		tc.NoErr(t, err)
+		if !bytes.Equal(want, Encode(got)) {
+			t.Errorf("%s roundtrip want: %x got: %x", c.desc, want, Encode(got))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package rlp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+var errStreamNotList = errors.New("stream must contain a list")
+
+// Decodes the list in r (eg a multi-gigabyte list of
+// blocks) and sends each of its elements to ch.
+// One element is read at a time so memory is bounded by
+// the size of the largest element and a slow consumer
+// applies backpressure to the reader.
+//
+// ch is closed when DecodeStream returns. A nil error
+// indicates that the entire list was decoded and that
+// r contained nothing else.
+func DecodeStream(r io.Reader, ch chan<- *Item) error {
+	defer close(ch)
+	br := bufio.NewReader(r)
+	hdr, _, ps, err := readHeader(br)
+	switch {
+	case errors.Is(err, io.EOF):
+		return errNoBytes
+	case err != nil:
+		return err
+	case hdr[0] < list55L:
+		return errStreamNotList
+	}
+	lr := bufio.NewReader(io.LimitReader(br, int64(ps)))
+	for n := 0; n < ps; {
+		b, err := readItem(lr)
+		switch {
+		case errors.Is(err, io.EOF):
+			return errTooFewBytes
+		case err != nil:
+			return err
+		}
+		it, err := DecodeZeroCopy(b)
+		if err != nil {
+			return err
+		}
+		n += len(b)
+		ch <- &it
+	}
+	if _, err := br.ReadByte(); !errors.Is(err, io.EOF) {
+		return errors.New("stream has bytes after list")
+	}
+	return nil
+}
+
+// Returns the header of the next item in r along
+// with the sizes of its header and payload.
+// Returns io.EOF if r has no more bytes.
+func readHeader(r *bufio.Reader) ([]byte, int, int, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	hdr := []byte{first}
+	var n int
+	switch {
+	case first > list55H:
+		n = int(first - list55H)
+	case first > str55H && first < list55L:
+		n = int(first - str55H)
+	}
+	for i := 0; i < n; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, 0, 0, errTooFewBytes
+		}
+		hdr = append(hdr, b)
+	}
+	hs, ps, err := parseHeader(hdr)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return hdr, hs, ps, nil
+}
+
+// Returns the header and payload of the next item in r.
+// Returns io.EOF if r has no more bytes.
+func readItem(r *bufio.Reader) ([]byte, error) {
+	hdr, hs, ps, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if hs == 0 {
+		return hdr, nil
+	}
+	// Grow the buffer as data arrives rather than
+	// trusting the header's size for the allocation.
+	buf := bytes.NewBuffer(hdr[:hs])
+	if _, err := io.CopyN(buf, r, int64(ps)); err != nil {
+		return nil, errTooFewBytes
+	}
+	return buf.Bytes(), nil
+}
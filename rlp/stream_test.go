@@ -0,0 +1,54 @@
+package rlp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestDecodeStream(t *testing.T) {
+	want := []Item{
+		Byte(1),
+		String("dog"),
+		Bytes(randBytes(1024)),
+		List(),
+		List(String("cat"), List(Bytes(randBytes(256)))),
+	}
+	input := Encode(List(want...))
+	ch := make(chan *Item)
+	errc := make(chan error, 1)
+	go func() { errc <- DecodeStream(bytes.NewReader(input), ch) }()
+	var got []Item
+	for it := range ch {
+		got = append(got, *it)
+	}
+	tc.NoErr(t, <-errc)
+	if len(got) != len(want) {
+		t.Fatalf("want %d items got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !want[i].Equal(got[i]) {
+			t.Errorf("item %d want: %v got: %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestDecodeStream_Errors(t *testing.T) {
+	cases := [][]byte{
+		{},
+		Encode(String("dog")),
+		{0xc2, 0x83, 0x01},
+		{0xc3, 0x01},
+		{0xf9, 0x01},
+		{0xc1, 0xbf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f, 0x01},
+		append(Encode(List(String("dog"))), 0xc2, 0x01),
+	}
+	for _, c := range cases {
+		ch := make(chan *Item, 8)
+		if err := DecodeStream(bytes.NewReader(c), ch); err == nil {
+			t.Errorf("expected error for %x", c)
+		}
+	}
+}
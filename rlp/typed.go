@@ -0,0 +1,99 @@
+package rlp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Transaction types defined by EIP-2718, EIP-2930,
+// EIP-1559 and EIP-4844. Receipts share their
+// transaction's type. DepositTxType is used by
+// the OP stack for L1 to L2 deposits.
+const (
+	LegacyTxType     byte = 0x00
+	AccessListTxType byte = 0x01
+	DynamicFeeTxType byte = 0x02
+	BlobTxType       byte = 0x03
+	DepositTxType    byte = 0x7e
+)
+
+var errInvalidTxType = errors.New("invalid transaction type")
+
+// Returns the EIP-2718 envelope: typ || rlp(payload).
+// Legacy transactions have no envelope so
+// LegacyTxType returns the encoding of payload.
+func EncodeTyped(typ byte, payload Item) ([]byte, error) {
+	switch {
+	case typ == LegacyTxType:
+		return Encode(payload), nil
+	case typ > 0x7f:
+		return nil, fmt.Errorf("%w: %#x", errInvalidTxType, typ)
+	}
+	return AppendEncode([]byte{typ}, payload), nil
+}
+
+// Splits an EIP-2718 envelope into its type and opaque payload
+// without decoding the payload. Input beginning with a list
+// header is legacy and is returned whole with LegacyTxType.
+// The payload shares memory with b.
+func SplitTyped(b []byte) (byte, []byte, error) {
+	if len(b) == 0 {
+		return 0, nil, errNoBytes
+	}
+	switch {
+	case b[0] >= list55L:
+		return LegacyTxType, b, nil
+	case b[0] > 0x7f:
+		return 0, nil, fmt.Errorf("%w: %#x", errInvalidTxType, b[0])
+	case len(b) == 1:
+		return 0, nil, errors.New("missing typed payload")
+	}
+	return b[0], b[1:], nil
+}
+
+// The inverse of [SplitTyped]. Returns typ || payload
+// or payload when typ is LegacyTxType.
+func JoinTyped(typ byte, payload []byte) ([]byte, error) {
+	switch {
+	case typ == LegacyTxType:
+		return payload, nil
+	case typ > 0x7f:
+		return nil, fmt.Errorf("%w: %#x", errInvalidTxType, typ)
+	}
+	return append([]byte{typ}, payload...), nil
+}
+
+// Decodes an EIP-2718 envelope into its type and payload.
+// Input beginning with a list header is
+// a legacy transaction and returns LegacyTxType.
+func DecodeTyped(b []byte) (byte, Item, error) {
+	typ, payload, err := SplitTyped(b)
+	if err != nil {
+		return 0, Item{}, err
+	}
+	it, err := Decode(payload)
+	return typ, it, err
+}
+
+// Returns an Item that embeds a transaction in a list
+// (eg a block body). Typed transactions are embedded as
+// bytes containing the envelope whereas legacy transactions
+// are embedded as the payload itself.
+func Typed(typ byte, payload Item) (Item, error) {
+	if typ == LegacyTxType {
+		return payload, nil
+	}
+	b, err := EncodeTyped(typ, payload)
+	if err != nil {
+		return Item{}, err
+	}
+	return Bytes(b), nil
+}
+
+// The inverse of [Typed]
+func (i Item) Typed() (byte, Item, error) {
+	if i.l != nil {
+		return LegacyTxType, i, nil
+	}
+	return DecodeTyped(i.d)
+}
@@ -0,0 +1,75 @@
+package rlp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestTyped(t *testing.T) {
+	payload := List(Uint64(1), String("foo"))
+	cases := []struct {
+		typ  byte
+		want []byte
+	}{
+		{LegacyTxType, Encode(payload)},
+		{AccessListTxType, append([]byte{0x01}, Encode(payload)...)},
+		{DynamicFeeTxType, append([]byte{0x02}, Encode(payload)...)},
+		{BlobTxType, append([]byte{0x03}, Encode(payload)...)},
+		{DepositTxType, append([]byte{0x7e}, Encode(payload)...)},
+	}
+	for _, c := range cases {
+		got, err := EncodeTyped(c.typ, payload)
+		tc.NoErr(t, err)
+		if !bytes.Equal(c.want, got) {
+			t.Errorf("want: %x got: %x", c.want, got)
+		}
+		typ, raw, err := SplitTyped(got)
+		tc.NoErr(t, err)
+		if typ != c.typ || !bytes.Equal(raw, Encode(payload)) {
+			t.Errorf("split want: %d %x got: %d %x", c.typ, Encode(payload), typ, raw)
+		}
+		joined, err := JoinTyped(typ, raw)
+		tc.NoErr(t, err)
+		if !bytes.Equal(c.want, joined) {
+			t.Errorf("join want: %x got: %x", c.want, joined)
+		}
+
+		typ, it, err := DecodeTyped(got)
+		tc.NoErr(t, err)
+		if typ != c.typ || !payload.Equal(it) {
+			t.Errorf("want: %d %v got: %d %v", c.typ, payload, typ, it)
+		}
+
+		// embedded in a block body
+		emb, err := Typed(c.typ, payload)
+		tc.NoErr(t, err)
+		body, err := Decode(Encode(List(emb)))
+		tc.NoErr(t, err)
+		typ, it, err = body.At(0).Typed()
+		tc.NoErr(t, err)
+		if typ != c.typ || !payload.Equal(it) {
+			t.Errorf("embedded want: %d %v got: %d %v", c.typ, payload, typ, it)
+		}
+	}
+}
+
+func TestTyped_Errors(t *testing.T) {
+	if _, err := EncodeTyped(0x80, List()); err == nil {
+		t.Error("expected error for type 0x80")
+	}
+	if _, err := JoinTyped(0x80, nil); err == nil {
+		t.Error("expected error for type 0x80")
+	}
+	for _, b := range [][]byte{nil, {0x80}, {0x7e}} {
+		if _, _, err := SplitTyped(b); err == nil {
+			t.Errorf("expected error for %x", b)
+		}
+	}
+	for _, b := range [][]byte{nil, {0x80}, {0xbf, 0x00}, {0x02}} {
+		if _, _, err := DecodeTyped(b); err == nil {
+			t.Errorf("expected error for %x", b)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package rlp
 import (
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"net"
 	"time"
 
@@ -12,7 +14,22 @@ import (
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 )
 
-var errNoData = errors.New("requested item contains 0 bytes")
+var (
+	errNoData = errors.New("requested item contains 0 bytes")
+	errIsList = errors.New("requested bytes from a list item")
+)
+
+// Returns an error if i is a list or if i's
+// data is longer than max bytes.
+func (i Item) checkSize(max int) error {
+	if i.l != nil {
+		return errIsList
+	}
+	if len(i.d) > max {
+		return fmt.Errorf("must be at most %d bytes. got: %d", max, len(i.d))
+	}
+	return nil
+}
 
 func Bytes(b []byte) Item {
 	if b == nil {
@@ -25,20 +42,87 @@ func (i Item) Bytes() []byte {
 	return i.d
 }
 
+// RLP integers are big-endian with no leading
+// zeros. Therefore zero is the empty string.
+func uintBytes(n uint64) []byte {
+	if n == 0 {
+		return []byte{}
+	}
+	return bint.Encode(nil, n)
+}
+
 func Uint16(n uint16) Item {
-	return Item{d: bint.Encode(nil, uint64(n))}
+	return Item{d: uintBytes(uint64(n))}
 }
 
-func (i Item) Uint16() uint16 {
-	return uint16(bint.Decode(i.d))
+func (i Item) Uint16() (uint16, error) {
+	if err := i.checkSize(2); err != nil {
+		return 0, err
+	}
+	return uint16(bint.Decode(i.d)), nil
 }
 
 func Uint64(n uint64) Item {
-	return Item{d: bint.Encode(nil, n)}
+	return Item{d: uintBytes(n)}
+}
+
+func (i Item) Uint64() (uint64, error) {
+	if err := i.checkSize(8); err != nil {
+		return 0, err
+	}
+	return bint.Decode(i.d), nil
+}
+
+// Encodes the absolute value of n. A nil n is zero.
+func BigInt(n *big.Int) Item {
+	if n == nil {
+		return Item{d: []byte{}}
+	}
+	return Bytes(n.Bytes())
+}
+
+func (i Item) BigInt() (*big.Int, error) {
+	if i.l != nil {
+		return nil, errIsList
+	}
+	return new(big.Int).SetBytes(i.d), nil
+}
+
+func Bool(b bool) Item {
+	if b {
+		return Byte(1)
+	}
+	return Byte(0)
+}
+
+// Empty data decodes as false and 0x01 as true.
+// All other values return an error.
+func (i Item) Bool() (bool, error) {
+	if err := i.checkSize(1); err != nil {
+		return false, err
+	}
+	switch {
+	case len(i.d) == 0:
+		return false, nil
+	case i.d[0] == 1:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid bool: %x", i.d)
+	}
+}
+
+func Address(a [20]byte) Item {
+	return Item{d: a[:]}
 }
 
-func (i Item) Uint64() uint64 {
-	return bint.Decode(i.d)
+func (i Item) Address() ([20]byte, error) {
+	if i.l != nil {
+		return [20]byte{}, errIsList
+	}
+	if len(i.d) != 20 {
+		return [20]byte{}, fmt.Errorf("address must be exactly 20 bytes. got: %d", len(i.d))
+	}
+	return *(*[20]byte)(i.d), nil
 }
 
 func String(s string) Item {
@@ -53,8 +137,12 @@ func Time(t time.Time) Item {
 	return Uint64(uint64(t.Unix()))
 }
 
-func (i Item) Time() time.Time {
-	return time.Unix(int64(i.Uint64()), 0)
+func (i Item) Time() (time.Time, error) {
+	n, err := i.Uint64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(n), 0), nil
 }
 
 // Uncompressed secpk256k1 public key
@@ -92,6 +180,15 @@ func (i Item) Hash() ([32]byte, error) {
 	return h, nil
 }
 
+// IPv4 addresses are encoded as 4 bytes
+// and IPv6 addresses as 16 bytes.
+func IP(ip net.IP) Item {
+	if ip4 := ip.To4(); ip4 != nil {
+		return Item{d: ip4}
+	}
+	return Bytes(ip)
+}
+
 func (i Item) IP() (net.IP, error) {
 	switch len(i.d) {
 	case 0:
@@ -103,6 +200,10 @@ func (i Item) IP() (net.IP, error) {
 	}
 }
 
+func Bytes32(b [32]byte) Item {
+	return Item{d: b[:]}
+}
+
 func (i Item) Bytes32() ([32]byte, error) {
 	if len(i.d) != 32 {
 		return [32]byte{}, errors.New("must be exactly 32 bytes")
@@ -124,6 +225,29 @@ func Byte(b byte) Item {
 	return Item{d: []byte{b}}
 }
 
+func (i Item) Byte() (byte, error) {
+	if err := i.checkSize(1); err != nil {
+		return 0, err
+	}
+	if len(i.d) == 0 {
+		return 0, nil
+	}
+	return i.d[0], nil
+}
+
+// Negative values are not supported
+// and are encoded as their uint64 value.
 func Int(n int) Item {
-	return Item{d: bint.Encode(nil, uint64(n))}
+	return Item{d: uintBytes(uint64(n))}
+}
+
+func (i Item) Int() (int, error) {
+	n, err := i.Uint64()
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt {
+		return 0, fmt.Errorf("int overflow: %d", n)
+	}
+	return int(n), nil
 }
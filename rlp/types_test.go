@@ -0,0 +1,126 @@
+package rlp
+
+import (
+	"math/big"
+	"net"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestAccessors(t *testing.T) {
+	n, err := Uint64(1 << 40).Uint64()
+	tc.NoErr(t, err)
+	if n != 1<<40 {
+		t.Errorf("want: %d got: %d", uint64(1<<40), n)
+	}
+	b, err := Bool(true).Bool()
+	tc.NoErr(t, err)
+	if !b {
+		t.Error("want: true got: false")
+	}
+	bi, err := BigInt(big.NewInt(1024)).BigInt()
+	tc.NoErr(t, err)
+	if bi.Int64() != 1024 {
+		t.Errorf("want: 1024 got: %s", bi)
+	}
+	addr, err := Address([20]byte{0xaa}).Address()
+	tc.NoErr(t, err)
+	if addr != [20]byte{0xaa} {
+		t.Errorf("want: aa00.. got: %x", addr)
+	}
+	h, err := Bytes32([32]byte{0xbb}).Bytes32()
+	tc.NoErr(t, err)
+	if h != [32]byte{0xbb} {
+		t.Errorf("want: bb00.. got: %x", h)
+	}
+	s := String("hello").String()
+	if s != "hello" {
+		t.Errorf("want: hello got: %s", s)
+	}
+	by, err := Byte(0x7f).Byte()
+	tc.NoErr(t, err)
+	if by != 0x7f {
+		t.Errorf("want: 7f got: %x", by)
+	}
+	in, err := Int(1 << 20).Int()
+	tc.NoErr(t, err)
+	if in != 1<<20 {
+		t.Errorf("want: %d got: %d", 1<<20, in)
+	}
+	zero, err := BigInt(nil).BigInt()
+	tc.NoErr(t, err)
+	if zero.Sign() != 0 {
+		t.Errorf("want: 0 got: %s", zero)
+	}
+}
+
+func TestIP(t *testing.T) {
+	cases := []struct {
+		ip   net.IP
+		size int
+	}{
+		{net.IPv4(127, 0, 0, 1), 4},
+		{net.ParseIP("::1"), 16},
+	}
+	for _, c := range cases {
+		it := IP(c.ip)
+		if len(it.Bytes()) != c.size {
+			t.Errorf("%s want: %d bytes got: %d", c.ip, c.size, len(it.Bytes()))
+		}
+		got, err := it.IP()
+		tc.NoErr(t, err)
+		if !got.Equal(c.ip) {
+			t.Errorf("want: %s got: %s", c.ip, got)
+		}
+	}
+}
+
+func TestAccessors_Errors(t *testing.T) {
+	cases := []struct {
+		desc string
+		f    func() error
+	}{
+		{
+			"uint16 overflow",
+			func() error { _, err := Uint64(1 << 16).Uint16(); return err },
+		},
+		{
+			"uint64 overflow",
+			func() error { _, err := Bytes(make([]byte, 9)).Uint64(); return err },
+		},
+		{
+			"uint64 from list",
+			func() error { _, err := List().Uint64(); return err },
+		},
+		{
+			"invalid bool",
+			func() error { _, err := Byte(2).Bool(); return err },
+		},
+		{
+			"short address",
+			func() error { _, err := Bytes(make([]byte, 19)).Address(); return err },
+		},
+		{
+			"big int from list",
+			func() error { _, err := List().BigInt(); return err },
+		},
+		{
+			"byte overflow",
+			func() error { _, err := Uint16(256).Byte(); return err },
+		},
+		{
+			"int overflow",
+			func() error { _, err := Uint64(1 << 63).Int(); return err },
+		},
+		{
+			"short hash",
+			func() error { _, err := Bytes(make([]byte, 31)).Hash(); return err },
+		},
+	}
+	for _, c := range cases {
+		if c.f() == nil {
+			t.Errorf("%s: expected error", c.desc)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package rlp
+
+import "errors"
+
+var (
+	errNonCanonicalSize = errors.New("non-canonical size information")
+	errNonCanonicalByte = errors.New("single byte below 0x80 must not have a header")
+	errTrailingBytes    = errors.New("input has bytes after the encoded item")
+)
+
+// Reports an error if b is not the canonical encoding of
+// a single item. Encodings are canonical when:
+//   - single bytes below 0x80 are not wrapped in a header
+//   - the short form is used for payloads of 55 bytes or less
+//   - long form lengths have no leading zeros
+//   - there are no bytes after the item
+//
+// Since RLP has no notion of integers, leading zeros in
+// integers are rejected by [Unmarshal] rather than here.
+func Validate(b []byte) error {
+	n, err := validate(b)
+	if err != nil {
+		return err
+	}
+	if n != len(b) {
+		return errTrailingBytes
+	}
+	return nil
+}
+
+// Like [Decode] but returns an error if input
+// is not canonical. See [Validate].
+func DecodeStrict(input []byte) (Item, error) {
+	if err := Validate(input); err != nil {
+		return Item{}, err
+	}
+	return Decode(input)
+}
+
+// Validates the first item in b and returns its size
+func validate(b []byte) (int, error) {
+	hs, ps, err := header(b)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) < hs+ps {
+		return 0, errTooFewBytes
+	}
+	switch {
+	case b[0] <= str1H:
+	case b[0] <= str55H:
+		if ps == 1 && b[1] <= str1H {
+			return 0, errNonCanonicalByte
+		}
+	case b[0] >= strNL && b[0] <= strNH, b[0] >= listNL:
+		if b[1] == 0 || ps <= 55 {
+			return 0, errNonCanonicalSize
+		}
+	}
+	if b[0] >= list55L {
+		for i := hs; i < hs+ps; {
+			n, err := validate(b[i : hs+ps])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	return hs + ps, nil
+}
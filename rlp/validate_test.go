@@ -0,0 +1,41 @@
+package rlp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		desc  string
+		input []byte
+		err   error
+	}{
+		{"single byte", []byte{0x00}, nil},
+		{"empty string", []byte{0x80}, nil},
+		{"short string", Encode(String("dog")), nil},
+		{"long string", Encode(Bytes(randBytes(56))), nil},
+		{"nested list", Encode(List(List(), List(String("cat")))), nil},
+		{"long list", Encode(List(Bytes(randBytes(60)))), nil},
+		{"wrapped single byte", []byte{0x81, 0x7f}, errNonCanonicalByte},
+		{"wrapped single byte in list", []byte{0xc2, 0x81, 0x01}, errNonCanonicalByte},
+		{"long form short string", append([]byte{0xb8, 0x03}, "dog"...), errNonCanonicalSize},
+		{"long form short list", []byte{0xf8, 0x01, 0x80}, errNonCanonicalSize},
+		{"leading zero in length", append([]byte{0xb9, 0x00, 0x38}, randBytes(56)...), errNonCanonicalSize},
+		{"trailing bytes", []byte{0xc0, 0x00}, errTrailingBytes},
+		{"truncated", []byte{0x83, 0x01}, errTooFewBytes},
+	}
+	for _, c := range cases {
+		err := Validate(c.input)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: want: %v got: %v", c.desc, c.err, err)
+		}
+	}
+}
+
+func TestUnmarshal_LeadingZeros(t *testing.T) {
+	var n uint64
+	if err := Unmarshal([]byte{0x82, 0x00, 0x01}, &n); err == nil {
+		t.Error("expected error for integer with leading zeros")
+	}
+}
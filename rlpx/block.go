@@ -0,0 +1,128 @@
+package rlpx
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/indexsupply/x/isxerrors"
+	"github.com/indexsupply/x/isxhash"
+	"github.com/indexsupply/x/rlp"
+)
+
+// An execution layer block header. Fields added
+// by forks after London are optional.
+type Header struct {
+	ParentHash  [32]byte
+	OmmersHash  [32]byte
+	Coinbase    [20]byte
+	StateRoot   [32]byte
+	TxRoot      [32]byte
+	ReceiptRoot [32]byte
+	Bloom       [256]byte
+	Difficulty  *big.Int
+	Number      uint64
+	GasLimit    uint64
+	GasUsed     uint64
+	Time        uint64
+	Extra       []byte
+	MixHash     [32]byte
+	Nonce       [8]byte
+
+	BaseFee          *big.Int `rlp:"optional"` // London
+	WithdrawalsRoot  [32]byte `rlp:"optional"` // Shanghai
+	BlobGasUsed      uint64   `rlp:"optional"` // Cancun
+	ExcessBlobGas    uint64   `rlp:"optional"` // Cancun
+	ParentBeaconRoot [32]byte `rlp:"optional"` // Cancun
+	RequestsHash     [32]byte `rlp:"optional"` // Prague
+}
+
+// A block announced by a peer in a NewBlock message
+type Block struct {
+	Header
+	Hash         [32]byte
+	TD           *big.Int // total difficulty claimed by the peer
+	Transactions int
+	Ommers       int
+}
+
+// Sent to [session.Blocks] for each valid NewBlock message
+type BlockObservation struct {
+	Peer  [32]byte // node id of the remote
+	Time  time.Time
+	Block Block
+}
+
+// keccak256(rlp([]))
+var emptyOmmersHash = isxhash.Keccak32(rlp.Encode(rlp.List()))
+
+var errInvalidBlock = errors.New("invalid block")
+
+// Checks fields that can be verified without chain state.
+// This doesn't verify PoW seals or consensus layer signatures.
+func (b *Block) validate() error {
+	h := b.Header
+	switch {
+	case h.GasUsed > h.GasLimit:
+		return fmt.Errorf("%w: gas used %d exceeds limit %d", errInvalidBlock, h.GasUsed, h.GasLimit)
+	case len(h.Extra) > 32:
+		return fmt.Errorf("%w: extra data is %d bytes", errInvalidBlock, len(h.Extra))
+	case h.Difficulty.Sign() != 0:
+		// PoW
+		if b.TD == nil || b.TD.Cmp(h.Difficulty) < 0 {
+			return fmt.Errorf("%w: total difficulty is less than difficulty", errInvalidBlock)
+		}
+		return nil
+	}
+	// PoS. See: https://eips.ethereum.org/EIPS/eip-3675#block-structure
+	switch {
+	case h.Nonce != [8]byte{}:
+		return fmt.Errorf("%w: post-merge nonce must be zero", errInvalidBlock)
+	case h.OmmersHash != emptyOmmersHash || b.Ommers != 0:
+		return fmt.Errorf("%w: post-merge block has ommers", errInvalidBlock)
+	}
+	return nil
+}
+
+// NewBlock = [[header, transactions, ommers, ...], td]
+func decodeNewBlock(item rlp.Item) (Block, error) {
+	var b Block
+	if len(item.List()) != 2 || len(item.At(0).List()) < 3 {
+		return b, errors.New("NewBlock must be [[header, txs, ommers, ...], td]")
+	}
+	// hash the header as received since a
+	// non-canonical encoding changes the hash
+	hb := item.At(0).At(0).Raw()
+	if err := rlp.Unmarshal(hb, &b.Header); err != nil {
+		return b, isxerrors.Errorf("decoding header: %w", err)
+	}
+	b.Hash = isxhash.Keccak32(hb)
+	b.Transactions = len(item.At(0).At(1).List())
+	b.Ommers = len(item.At(0).At(2).List())
+	td, err := item.At(1).BigInt()
+	if err != nil {
+		return b, isxerrors.Errorf("decoding td: %w", err)
+	}
+	b.TD = td
+	return b, b.validate()
+}
+
+// Observations are dropped when s.Blocks is full
+// so that a slow consumer doesn't stall the session.
+func (s *session) HandleNewBlock(item rlp.Item) error {
+	b, err := decodeNewBlock(item)
+	if err != nil {
+		return isxerrors.Errorf("decoding NewBlock: %w", err)
+	}
+	s.log("<new-block number=%d hash=%x txs=%d\n", b.Number, b.Hash[:4], b.Transactions)
+	if s.Blocks == nil {
+		return nil
+	}
+	select {
+	case s.Blocks <- BlockObservation{Peer: s.peer, Time: time.Now(), Block: b}:
+	default:
+		s.log("dropping block observation %d\n", b.Number)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package rlpx
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/indexsupply/x/isxhash"
+	"github.com/indexsupply/x/isxsecp256k1"
+	"github.com/indexsupply/x/rlp"
+	"github.com/indexsupply/x/tc"
+)
+
+func newBlock(t *testing.T, h Header, td *big.Int, ommers ...rlp.Item) rlp.Item {
+	hb, err := rlp.Marshal(h)
+	tc.NoErr(t, err)
+	hi, err := rlp.Decode(hb)
+	tc.NoErr(t, err)
+	return rlp.List(
+		rlp.List(hi, rlp.List(rlp.Bytes([]byte{0x02, 0xc0})), rlp.List(ommers...)),
+		rlp.BigInt(td),
+	)
+}
+
+func TestNewBlock(t *testing.T) {
+	s1, s2 := testSessions(t)
+	s1.EthVersions = []uint64{68}
+	hello(t, s1, s2)
+	blocks := make(chan BlockObservation, 1)
+	s2.Blocks = blocks
+
+	h := Header{
+		OmmersHash: emptyOmmersHash,
+		Difficulty: new(big.Int),
+		Number:     17034870,
+		GasLimit:   30000000,
+		GasUsed:    21000,
+		BaseFee:    big.NewInt(7),
+	}
+	item := newBlock(t, h, big.NewInt(1))
+	tc.NoErr(t, s2.HandleMessage(s1.encode(ethOffset+0x07, rlp.Encode(item))))
+
+	obs := <-blocks
+	pkb := isxsecp256k1.Encode(s1.local.PublicKey)
+	if obs.Peer != isxhash.Keccak32(pkb[:]) {
+		t.Errorf("unexpected peer id %x", obs.Peer)
+	}
+	if obs.Time.IsZero() {
+		t.Error("expected observation time")
+	}
+	b := obs.Block
+	if b.Number != h.Number || b.Transactions != 1 || b.BaseFee.Int64() != 7 {
+		t.Errorf("unexpected block: %+v", b)
+	}
+	if b.Hash != item.At(0).At(0).Keccak32() {
+		t.Errorf("unexpected hash %x", b.Hash)
+	}
+}
+
+func TestNewBlock_Invalid(t *testing.T) {
+	pos := Header{OmmersHash: emptyOmmersHash, Difficulty: new(big.Int), GasLimit: 10}
+	cases := []struct {
+		desc   string
+		h      func(Header) Header
+		ommers []rlp.Item
+	}{
+		{"gas used", func(h Header) Header { h.GasUsed = 11; return h }, nil},
+		{"extra", func(h Header) Header { h.Extra = make([]byte, 33); return h }, nil},
+		{"nonce", func(h Header) Header { h.Nonce = [8]byte{1}; return h }, nil},
+		{"ommers hash", func(h Header) Header { h.OmmersHash = [32]byte{}; return h }, nil},
+		{"ommers", func(h Header) Header { return h }, []rlp.Item{rlp.List()}},
+		{"pow td", func(h Header) Header { h.Difficulty = big.NewInt(2); return h }, nil},
+	}
+	for _, c := range cases {
+		_, err := decodeNewBlock(newBlock(t, c.h(pos), big.NewInt(1), c.ommers...))
+		if !errors.Is(err, errInvalidBlock) {
+			t.Errorf("%s: expected invalid block. got: %v", c.desc, err)
+		}
+	}
+}
+
+func TestNewBlock_NotValidForEth69(t *testing.T) {
+	s1, s2 := testSessions(t)
+	hello(t, s1, s2)
+	m := s1.encode(ethOffset+0x07, rlp.Encode(rlp.List()))
+	if err := s2.HandleMessage(m); err == nil {
+		t.Error("expected error for NewBlock on eth/69")
+	}
+}
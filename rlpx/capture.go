@@ -0,0 +1,149 @@
+package rlpx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/indexsupply/x/bint"
+	"github.com/indexsupply/x/isxerrors"
+	"github.com/indexsupply/x/rlp"
+)
+
+// A decrypted and decompressed message
+// recorded by [Capture]
+type Message struct {
+	Time   time.Time
+	Egress bool
+	ID     uint64
+	Data   []byte
+}
+
+// Capture records a session's messages to a sequence of files
+// in dir named prefix.N.rlpx. When a file exceeds max bytes
+// the next file in the sequence is opened.
+//
+// Each message is stored as a 4 byte big-endian length
+// followed by the RLP encoding of:
+//
+//	[time-unix-nano, egress, msg-id, msg-data]
+//
+// Use [ReadMessage] to read the files.
+type Capture struct {
+	mu     sync.Mutex
+	dir    string
+	prefix string
+	max    int64
+
+	n    int
+	f    *os.File
+	size int64
+}
+
+func NewCapture(dir, prefix string, max int64) (*Capture, error) {
+	c := &Capture{dir: dir, prefix: prefix, max: max}
+	if err := c.rotate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Capture) rotate() error {
+	if c.f != nil {
+		if err := c.f.Close(); err != nil {
+			return err
+		}
+		c.n++
+	}
+	name := filepath.Join(c.dir, fmt.Sprintf("%s.%d.rlpx", c.prefix, c.n))
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return isxerrors.Errorf("opening capture file: %w", err)
+	}
+	c.f, c.size = f, 0
+	return nil
+}
+
+func (c *Capture) Write(m Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size >= c.max {
+		if err := c.rotate(); err != nil {
+			return err
+		}
+	}
+	rec := rlp.Encode(rlp.List(
+		rlp.Uint64(uint64(m.Time.UnixNano())),
+		rlp.Bool(m.Egress),
+		rlp.Uint64(m.ID),
+		rlp.Bytes(m.Data),
+	))
+	var size [4]byte
+	bint.Encode(size[:], uint64(len(rec)))
+	n, err := c.f.Write(append(size[:], rec...))
+	c.size += int64(n)
+	return err
+}
+
+func (c *Capture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.f.Close()
+}
+
+// Reads the next message from a file written by [Capture].
+// Returns io.EOF when there are no more messages.
+func ReadMessage(r io.Reader) (Message, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return Message{}, err
+	}
+	rec := make([]byte, bint.Decode(size[:]))
+	if _, err := io.ReadFull(r, rec); err != nil {
+		return Message{}, isxerrors.Errorf("reading message: %w", err)
+	}
+	item, err := rlp.Decode(rec)
+	if err != nil {
+		return Message{}, isxerrors.Errorf("decoding message: %w", err)
+	}
+	if len(item.List()) != 4 {
+		return Message{}, errors.New("capture message must contain 4 items")
+	}
+	ns, err := item.At(0).Uint64()
+	if err != nil {
+		return Message{}, isxerrors.Errorf("decoding time: %w", err)
+	}
+	egress, err := item.At(1).Bool()
+	if err != nil {
+		return Message{}, isxerrors.Errorf("decoding egress: %w", err)
+	}
+	id, err := item.At(2).Uint64()
+	if err != nil {
+		return Message{}, isxerrors.Errorf("decoding id: %w", err)
+	}
+	return Message{
+		Time:   time.Unix(0, int64(ns)),
+		Egress: egress,
+		ID:     id,
+		Data:   item.At(3).Bytes(),
+	}, nil
+}
+
+func (s *session) capture(egress bool, id uint64, data []byte) {
+	if s.Capture == nil {
+		return
+	}
+	err := s.Capture.Write(Message{
+		Time:   time.Now(),
+		Egress: egress,
+		ID:     id,
+		Data:   data,
+	})
+	if err != nil {
+		s.log("capture error: %s\n", err)
+	}
+}
@@ -0,0 +1,200 @@
+package rlpx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"sync"
+)
+
+// The chain a session is for. Used to build the local
+// Status and to validate the remote's Status.
+type Chain struct {
+	Network uint64
+	Genesis [32]byte
+
+	// Activation blocks and then activation
+	// timestamps of the chain's forks. In order
+	// and without duplicates. See EIP-2124.
+	BlockForks []uint64
+	TimeForks  []uint64
+}
+
+// The fork lists must be updated for every hard fork.
+// A missing fork makes Validate reject peers that have
+// activated it and makes EthStatus advertise a stale
+// fork id.
+var Mainnet = Chain{
+	Network: 1,
+	Genesis: [32]byte{
+		0xd4, 0xe5, 0x67, 0x40, 0xf8, 0x76, 0xae, 0xf8,
+		0xc0, 0x10, 0xb8, 0x6a, 0x40, 0xd5, 0xf5, 0x67,
+		0x45, 0xa1, 0x18, 0xd0, 0x90, 0x6a, 0x34, 0xe6,
+		0x9a, 0xec, 0x8c, 0x0d, 0xb1, 0xcb, 0x8f, 0xa3,
+	},
+	BlockForks: []uint64{
+		1150000,  // homestead
+		1920000,  // dao
+		2463000,  // tangerine whistle
+		2675000,  // spurious dragon
+		4370000,  // byzantium
+		7280000,  // constantinople, petersburg
+		9069000,  // istanbul
+		9200000,  // muir glacier
+		12244000, // berlin
+		12965000, // london
+		13773000, // arrow glacier
+		15050000, // gray glacier
+	},
+	TimeForks: []uint64{
+		1681338455, // shanghai
+		1710338135, // cancun
+		1746612311, // prague
+		1764798551, // osaka
+		1765290071, // bpo1
+		1767747671, // bpo2
+	},
+}
+
+// Fork ids with a next value above this are
+// timestamps rather than block numbers
+const timestampThreshold = 1438269973
+
+// Returns the EIP-2124 fork id for a node whose head
+// is at block number head with timestamp time.
+func (c Chain) ForkID(head, time uint64) ([4]byte, uint64) {
+	sum := crc32.ChecksumIEEE(c.Genesis[:])
+	for _, f := range c.BlockForks {
+		if f > head {
+			return checksum(sum), f
+		}
+		sum = update(sum, f)
+	}
+	for _, f := range c.TimeForks {
+		if f > time {
+			return checksum(sum), f
+		}
+		sum = update(sum, f)
+	}
+	return checksum(sum), 0
+}
+
+func update(sum uint32, fork uint64) uint32 {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], fork)
+	return crc32.Update(sum, crc32.IEEETable, b[:])
+}
+
+func checksum(sum uint32) [4]byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], sum)
+	return b
+}
+
+// Returned by [Chain.Validate] when the remote's Status
+// is for a different chain or an incompatible fork.
+// [session.DisconnectFor] uses [DiscUselessPeer].
+var (
+	ErrIncompatibleChain = errors.New("incompatible chain")
+
+	ErrNetworkMismatch   = fmt.Errorf("%w: network mismatch", ErrIncompatibleChain)
+	ErrGenesisMismatch   = fmt.Errorf("%w: genesis mismatch", ErrIncompatibleChain)
+	ErrRemoteStale       = fmt.Errorf("%w: remote needs update", ErrIncompatibleChain)
+	ErrLocalIncompatible = fmt.Errorf("%w: local incompatible or needs update", ErrIncompatibleChain)
+)
+
+// Checks that remote is for c's network and genesis and that
+// remote's fork id is compatible with a local node whose head is
+// at block number head with timestamp time. See EIP-2124.
+func (c Chain) Validate(remote Status, head, time uint64) error {
+	if remote.Network != c.Network {
+		return ErrNetworkMismatch
+	}
+	if remote.Genesis != c.Genesis {
+		return ErrGenesisMismatch
+	}
+	// the last fork is never passed which
+	// simplifies the loop's checks
+	forks := make([]uint64, 0, len(c.BlockForks)+len(c.TimeForks)+1)
+	forks = append(forks, c.BlockForks...)
+	forks = append(forks, c.TimeForks...)
+	forks = append(forks, math.MaxUint64)
+
+	// sums[i] is the fork hash before forks[i]
+	sums := make([][4]byte, len(forks))
+	sum := crc32.ChecksumIEEE(c.Genesis[:])
+	for i := range forks {
+		sums[i] = checksum(sum)
+		sum = update(sum, forks[i])
+	}
+	for i, f := range forks {
+		if i < len(c.BlockForks) && head >= f {
+			continue
+		}
+		if i >= len(c.BlockForks) && time >= f {
+			continue
+		}
+		// forks[i] is the next local fork
+		if sums[i] == remote.ForkHash {
+			next := remote.ForkNext
+			if next > 0 && (head >= next || (next > timestampThreshold && time >= next)) {
+				return ErrLocalIncompatible
+			}
+			return nil
+		}
+		// the remote hasn't passed all of
+		// the forks that the local has
+		for j := 0; j < i; j++ {
+			if sums[j] == remote.ForkHash {
+				if forks[j] != remote.ForkNext {
+					return ErrRemoteStale
+				}
+				return nil
+			}
+		}
+		// the remote has passed forks that
+		// the local hasn't yet
+		for j := i + 1; j < len(sums); j++ {
+			if sums[j] == remote.ForkHash {
+				return nil
+			}
+		}
+		return ErrLocalIncompatible
+	}
+	return ErrLocalIncompatible
+}
+
+// Counts of remote Status messages rejected by [Chain.Validate]
+type StatusStats struct {
+	Network, Genesis, RemoteStale, LocalIncompatible uint64
+}
+
+// Collects [StatusStats] across sessions.
+// The zero value is ready to use.
+type StatusCounter struct {
+	mu sync.Mutex
+	s  StatusStats
+}
+
+func (c *StatusCounter) add(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case errors.Is(err, ErrNetworkMismatch):
+		c.s.Network++
+	case errors.Is(err, ErrGenesisMismatch):
+		c.s.Genesis++
+	case errors.Is(err, ErrRemoteStale):
+		c.s.RemoteStale++
+	case errors.Is(err, ErrLocalIncompatible):
+		c.s.LocalIncompatible++
+	}
+}
+
+func (c *StatusCounter) Stats() StatusStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.s
+}
@@ -0,0 +1,121 @@
+package rlpx
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+// Test vectors from EIP-2124 and go-ethereum's forkid package
+func TestForkID(t *testing.T) {
+	cases := []struct {
+		head, time uint64
+		hash       string
+		next       uint64
+	}{
+		{0, 0, "fc64ec04", 1150000},
+		{1149999, 0, "fc64ec04", 1150000},
+		{1150000, 0, "97c2c34c", 1920000},
+		{1920000, 0, "91d1f948", 2463000},
+		{2463000, 0, "7a64da13", 2675000},
+		{2675000, 0, "3edd5b10", 4370000},
+		{4370000, 0, "a00bc324", 7280000},
+		{7280000, 0, "668db0af", 9069000},
+		{9069000, 0, "879d6e30", 9200000},
+		{9200000, 0, "e029e991", 12244000},
+		{12244000, 0, "0eb440f6", 12965000},
+		{12965000, 0, "b715077d", 13773000},
+		{13773000, 0, "20c327fc", 15050000},
+		{15050000, 0, "f0afd0e3", 1681338455},
+		{20000000, 1681338455, "dce96c2d", 1710338135},
+		{20000000, 1710338135, "9f3d2254", 1746612311},
+		{20000000, 1746612311, "c376cf8b", 1764798551},
+		{30000000, 1764798551, "5167e2a6", 1765290071},
+		{30000000, 1765290071, "cba2a1c0", 1767747671},
+		{30000000, 1767747671, "07c9462e", 0},
+	}
+	for _, c := range cases {
+		hash, next := Mainnet.ForkID(c.head, c.time)
+		if got := hex.EncodeToString(hash[:]); got != c.hash || next != c.next {
+			t.Errorf("%d/%d want: %s %d got: %s %d", c.head, c.time, c.hash, c.next, got, next)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	fh := func(s string) [4]byte {
+		b, err := hex.DecodeString(s)
+		tc.NoErr(t, err)
+		return *(*[4]byte)(b)
+	}
+	cases := []struct {
+		desc string
+		head uint64
+		hash [4]byte
+		next uint64
+		want error
+	}{
+		{"same fork, no next", 7987396, fh("668db0af"), 0, nil},
+		{"same fork, unknown next", 7987396, fh("668db0af"), math.MaxUint64, nil},
+		{"remote syncing, knows next", 7279999, fh("a00bc324"), 7280000, nil},
+		{"remote behind, knows next", 7987396, fh("a00bc324"), 7280000, nil},
+		{"local syncing", 4370000, fh("668db0af"), 0, nil},
+		{"unsynced local", 0, fh("c376cf8b"), 1764798551, nil},
+		{"unsynced local, remote at bpo2", 0, fh("07c9462e"), 0, nil},
+		{"remote stale", 7987396, fh("a00bc324"), 0, ErrRemoteStale},
+		{"unknown fork", 7987396, fh("5cddc0e1"), 0, ErrLocalIncompatible},
+		{"passed remote next", 7987396, fh("668db0af"), 7987396, ErrLocalIncompatible},
+	}
+	for _, c := range cases {
+		remote := Status{
+			Network:  Mainnet.Network,
+			Genesis:  Mainnet.Genesis,
+			ForkHash: c.hash,
+			ForkNext: c.next,
+		}
+		if err := Mainnet.Validate(remote, c.head, 0); !errors.Is(err, c.want) || (c.want == nil && err != nil) {
+			t.Errorf("%s want: %v got: %v", c.desc, c.want, err)
+		}
+	}
+
+	remote := Status{Network: 5, Genesis: Mainnet.Genesis}
+	if err := Mainnet.Validate(remote, 0, 0); !errors.Is(err, ErrNetworkMismatch) {
+		t.Errorf("want network mismatch got: %v", err)
+	}
+	remote = Status{Network: 1, Genesis: [32]byte{1}}
+	if err := Mainnet.Validate(remote, 0, 0); !errors.Is(err, ErrGenesisMismatch) {
+		t.Errorf("want genesis mismatch got: %v", err)
+	}
+}
+
+func TestSession_WrongChain(t *testing.T) {
+	s1, s2 := testSessions(t)
+	hello(t, s1, s2)
+	other := Mainnet
+	other.Network = 5
+	s1.Chain = &other
+	s2.StatusCounter = &StatusCounter{}
+
+	m, err := s1.EthStatus()
+	tc.NoErr(t, err)
+	err = s2.HandleMessage(m)
+	if !errors.Is(err, ErrIncompatibleChain) || !errors.Is(err, ErrNetworkMismatch) {
+		t.Errorf("want network mismatch got: %v", err)
+	}
+	if got := s2.StatusCounter.Stats(); got != (StatusStats{Network: 1}) {
+		t.Errorf("want 1 network rejection got: %+v", got)
+	}
+
+	m, err = s2.DisconnectFor(err)
+	tc.NoErr(t, err)
+	var derr *DisconnectError
+	if err := s1.HandleMessage(m); !errors.As(err, &derr) || derr.Reason != DiscUselessPeer {
+		t.Errorf("want %s disconnect got: %v", DiscUselessPeer, err)
+	}
+	if m, err := s1.DisconnectFor(derr); m != nil || err != nil {
+		t.Errorf("expected no reply to a disconnect. got: %x %v", m, err)
+	}
+}
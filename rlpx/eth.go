@@ -0,0 +1,183 @@
+package rlpx
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/indexsupply/x/isxerrors"
+	"github.com/indexsupply/x/rlp"
+)
+
+// Versions of the eth subprotocol supported by this
+// package in order of preference. See:
+// https://github.com/ethereum/devp2p/blob/master/caps/eth.md
+var EthVersions = []uint64{69, 68, 67, 66}
+
+// eth messages are offset by the 16 message
+// codes reserved for the p2p protocol
+const ethOffset = 0x10
+
+// Returned by [session.HandleMessage] when the remote's Hello
+// contains no eth version that is also in the session's EthVersions.
+// [session.DisconnectFor] uses [DiscUselessPeer].
+var ErrNoSharedVersion = errors.New("no shared eth version")
+
+// Returns the highest version in local that is also in remote
+func negotiate(local, remote []uint64) (uint64, bool) {
+	var best uint64
+	for _, l := range local {
+		for _, r := range remote {
+			if l == r && l > best {
+				best = l
+			}
+		}
+	}
+	return best, best != 0
+}
+
+// eth message codes (without the offset) and the
+// versions in which they are valid
+var ethMessages = map[uint64]struct {
+	name     string
+	from, to uint64
+}{
+	0x00: {"Status", 66, 69},
+	0x01: {"NewBlockHashes", 66, 68},
+	0x02: {"Transactions", 66, 69},
+	0x03: {"GetBlockHeaders", 66, 69},
+	0x04: {"BlockHeaders", 66, 69},
+	0x05: {"GetBlockBodies", 66, 69},
+	0x06: {"BlockBodies", 66, 69},
+	0x07: {"NewBlock", 66, 68},
+	0x08: {"NewPooledTransactionHashes", 66, 69},
+	0x09: {"GetPooledTransactions", 66, 69},
+	0x0a: {"PooledTransactions", 66, 69},
+	0x0d: {"GetNodeData", 66, 66},
+	0x0e: {"NodeData", 66, 66},
+	0x0f: {"GetReceipts", 66, 69},
+	0x10: {"Receipts", 66, 69},
+	0x11: {"BlockRangeUpdate", 69, 69},
+}
+
+// Returns the name of the eth message code for version.
+// Returns false if the code isn't valid for version.
+func ethMessage(version, code uint64) (string, bool) {
+	m, ok := ethMessages[code]
+	if !ok || version < m.from || version > m.to {
+		return "", false
+	}
+	return m.name, true
+}
+
+// The eth Status message. The fields that are sent
+// depend on the version:
+//
+//	eth/66-68: [version, network, td, head, genesis, forkid]
+//	eth/69:    [version, network, genesis, forkid, earliest, latest, head]
+type Status struct {
+	Version  uint64
+	Network  uint64
+	Genesis  [32]byte
+	Head     [32]byte
+	ForkHash [4]byte
+	ForkNext uint64
+
+	TD *big.Int // eth/66-68
+
+	Earliest uint64 // eth/69
+	Latest   uint64 // eth/69
+}
+
+func (s Status) item() rlp.Item {
+	forkID := rlp.List(rlp.Bytes(s.ForkHash[:]), rlp.Uint64(s.ForkNext))
+	if s.Version >= 69 {
+		return rlp.List(
+			rlp.Uint64(s.Version),
+			rlp.Uint64(s.Network),
+			rlp.Bytes32(s.Genesis),
+			forkID,
+			rlp.Uint64(s.Earliest),
+			rlp.Uint64(s.Latest),
+			rlp.Bytes32(s.Head),
+		)
+	}
+	td := s.TD
+	if td == nil {
+		td = new(big.Int)
+	}
+	return rlp.List(
+		rlp.Uint64(s.Version),
+		rlp.Uint64(s.Network),
+		rlp.BigInt(td),
+		rlp.Bytes32(s.Head),
+		rlp.Bytes32(s.Genesis),
+		forkID,
+	)
+}
+
+func decodeStatus(item rlp.Item) (Status, error) {
+	var (
+		s   Status
+		err error
+	)
+	s.Version, err = item.At(0).Uint64()
+	if err != nil {
+		return s, isxerrors.Errorf("decoding version: %w", err)
+	}
+	var (
+		want = 6
+		fork = 5
+	)
+	if s.Version >= 69 {
+		want, fork = 7, 3
+	}
+	if len(item.List()) < want {
+		return s, fmt.Errorf("eth/%d status must contain %d items. got: %d", s.Version, want, len(item.List()))
+	}
+	s.Network, err = item.At(1).Uint64()
+	if err != nil {
+		return s, isxerrors.Errorf("decoding network: %w", err)
+	}
+	fh := item.At(fork).At(0).Bytes()
+	if len(fh) != 4 {
+		return s, fmt.Errorf("fork hash must be 4 bytes. got: %d", len(fh))
+	}
+	copy(s.ForkHash[:], fh)
+	s.ForkNext, err = item.At(fork).At(1).Uint64()
+	if err != nil {
+		return s, isxerrors.Errorf("decoding fork next: %w", err)
+	}
+	if s.Version >= 69 {
+		s.Genesis, err = item.At(2).Bytes32()
+		if err != nil {
+			return s, isxerrors.Errorf("decoding genesis: %w", err)
+		}
+		s.Earliest, err = item.At(4).Uint64()
+		if err != nil {
+			return s, isxerrors.Errorf("decoding earliest block: %w", err)
+		}
+		s.Latest, err = item.At(5).Uint64()
+		if err != nil {
+			return s, isxerrors.Errorf("decoding latest block: %w", err)
+		}
+		s.Head, err = item.At(6).Bytes32()
+		if err != nil {
+			return s, isxerrors.Errorf("decoding latest hash: %w", err)
+		}
+		return s, nil
+	}
+	s.TD, err = item.At(2).BigInt()
+	if err != nil {
+		return s, isxerrors.Errorf("decoding difficulty: %w", err)
+	}
+	s.Head, err = item.At(3).Bytes32()
+	if err != nil {
+		return s, isxerrors.Errorf("decoding head: %w", err)
+	}
+	s.Genesis, err = item.At(4).Bytes32()
+	if err != nil {
+		return s, isxerrors.Errorf("decoding genesis: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,117 @@
+package rlpx
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/indexsupply/x/tc"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		local, remote []uint64
+		want          uint64
+	}{
+		{EthVersions, []uint64{66}, 66},
+		{EthVersions, []uint64{66, 67}, 67},
+		{EthVersions, []uint64{68}, 68},
+		{EthVersions, []uint64{69, 70}, 69},
+		{EthVersions, []uint64{65}, 0},
+		{EthVersions, nil, 0},
+		{[]uint64{67}, []uint64{68, 69}, 0},
+		{[]uint64{66, 68}, []uint64{66, 67, 68, 69}, 68},
+	}
+	for _, c := range cases {
+		got, ok := negotiate(c.local, c.remote)
+		if got != c.want || ok != (c.want != 0) {
+			t.Errorf("negotiate(%v, %v) want: %d got: %d", c.local, c.remote, c.want, got)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	for _, v := range EthVersions {
+		want := Status{
+			Version:  v,
+			Network:  1,
+			Genesis:  [32]byte{1},
+			Head:     [32]byte{2},
+			ForkHash: [4]byte{3},
+			ForkNext: 4,
+		}
+		if v >= 69 {
+			want.Earliest, want.Latest = 5, 6
+		} else {
+			want.TD = big.NewInt(7)
+		}
+		got, err := decodeStatus(want.item())
+		tc.NoErr(t, err)
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("eth/%d want:\n%#v\ngot:\n%#v", v, want, got)
+		}
+	}
+}
+
+func TestEthMessage(t *testing.T) {
+	cases := []struct {
+		version, code uint64
+		want          bool
+	}{
+		{66, 0x0d, true},
+		{67, 0x0d, false},
+		{68, 0x07, true},
+		{69, 0x07, false},
+		{69, 0x01, false},
+		{68, 0x11, false},
+		{69, 0x11, true},
+		{69, 0x12, false},
+	}
+	for _, c := range cases {
+		if _, got := ethMessage(c.version, c.code); got != c.want {
+			t.Errorf("eth/%d %#x want: %t got: %t", c.version, c.code, c.want, got)
+		}
+	}
+}
+
+// Each pair of peers exchanges Hello and Status messages
+func TestSession_VersionMatrix(t *testing.T) {
+	sets := [][]uint64{
+		EthVersions,
+		{69},
+		{68, 67},
+		{67, 66},
+		{66},
+	}
+	for _, local := range sets {
+		for _, remote := range sets {
+			s1, s2 := testSessions(t)
+			s1.EthVersions, s2.EthVersions = local, remote
+			want, ok := negotiate(local, remote)
+
+			m1, err := s1.Hello()
+			tc.NoErr(t, err)
+			err = s2.HandleMessage(m1)
+			if !ok {
+				if !errors.Is(err, ErrNoSharedVersion) {
+					t.Errorf("%v %v expected no shared version. got: %v", local, remote, err)
+				}
+				continue
+			}
+			tc.NoErr(t, err)
+			m2, err := s2.Hello()
+			tc.NoErr(t, err)
+			tc.NoErr(t, s1.HandleMessage(m2))
+			if s1.Eth != want || s2.Eth != want {
+				t.Errorf("%v %v want: %d got: %d %d", local, remote, want, s1.Eth, s2.Eth)
+			}
+			m3, err := s1.EthStatus()
+			tc.NoErr(t, err)
+			tc.NoErr(t, s2.HandleMessage(m3))
+			m4, err := s2.EthStatus()
+			tc.NoErr(t, err)
+			tc.NoErr(t, s1.HandleMessage(m4))
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package rlpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/indexsupply/x/rlp"
+)
+
+const (
+	defaultMaxOutstanding = 8
+	defaultTimeout        = 5 * time.Second
+	defaultRetries        = 2
+)
+
+var (
+	ErrNoPeers     = errors.New("no peers available for request")
+	errTimeout     = errors.New("request timed out")
+	errPeerRemoved = errors.New("peer removed")
+)
+
+// Tracks eth/66+ requests and their responses. Each request
+// is assigned an id, sent to the peer with the fewest
+// outstanding requests and retried on a different
+// peer when it fails or times out.
+//
+// Use [Requests.Deliver] to route responses.
+// The zero value uses the defaults.
+type Requests struct {
+	MaxOutstanding int           // per peer. defaults to 8
+	Timeout        time.Duration // per attempt. defaults to 5s
+	Retries        int           // defaults to 2. negative disables retries
+
+	mu      sync.Mutex
+	nextID  uint64
+	peers   map[[32]byte]*reqPeer
+	pending map[uint64]*request
+
+	// closed and replaced when a peer may have capacity
+	changed chan struct{}
+}
+
+type reqPeer struct {
+	send        func(code uint64, data []byte) error
+	outstanding int
+	removed     chan struct{}
+}
+
+type request struct {
+	id   uint64
+	peer [32]byte
+	p    *reqPeer
+	done bool
+	resp chan rlp.Item
+}
+
+func (r *Requests) init() {
+	if r.peers == nil {
+		r.peers = map[[32]byte]*reqPeer{}
+		r.pending = map[uint64]*request{}
+		r.changed = make(chan struct{})
+	}
+}
+
+func (r *Requests) broadcast() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// Adds a peer that requests may be sent to. send
+// writes the message with code and the RLP encoded
+// data (eg [request-id, [...]]) to the peer.
+func (r *Requests) AddPeer(id [32]byte, send func(code uint64, data []byte) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.init()
+	if p, ok := r.peers[id]; ok {
+		close(p.removed)
+	}
+	r.peers[id] = &reqPeer{send: send, removed: make(chan struct{})}
+	r.broadcast()
+}
+
+// Requests that are outstanding on the
+// peer are retried on other peers.
+func (r *Requests) RemovePeer(id [32]byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.peers[id]; ok {
+		close(p.removed)
+		delete(r.peers, id)
+	}
+}
+
+// Number of requests waiting for a response from the peer
+func (r *Requests) Outstanding(id [32]byte) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.peers[id]; ok {
+		return p.outstanding
+	}
+	return 0
+}
+
+// Sends the eth message [request-id, payload] with code
+// and returns the payload of the response. Waits for a
+// peer to have capacity when all peers are at their limit.
+func (r *Requests) Do(ctx context.Context, code uint64, payload rlp.Item) (rlp.Item, error) {
+	var (
+		tried   = map[[32]byte]bool{}
+		lastErr = ErrNoPeers
+	)
+	for attempt := 0; attempt <= r.retries(); attempt++ {
+		req, p, err := r.start(ctx, tried)
+		if err != nil {
+			if errors.Is(err, ErrNoPeers) {
+				return rlp.Item{}, lastErr
+			}
+			return rlp.Item{}, err
+		}
+		tried[req.peer] = true
+		item, err := r.wait(ctx, req, p, code, payload)
+		r.finish(req)
+		if err == nil {
+			return item, nil
+		}
+		if ctx.Err() != nil {
+			return rlp.Item{}, ctx.Err()
+		}
+		lastErr = fmt.Errorf("request %d to %x: %w", req.id, req.peer[:4], err)
+	}
+	return rlp.Item{}, lastErr
+}
+
+func (r *Requests) wait(ctx context.Context, req *request, p *reqPeer, code uint64, payload rlp.Item) (rlp.Item, error) {
+	data := rlp.Encode(rlp.List(rlp.Uint64(req.id), payload))
+	if err := p.send(code, data); err != nil {
+		return rlp.Item{}, err
+	}
+	timer := time.NewTimer(r.timeout())
+	defer timer.Stop()
+	select {
+	case item := <-req.resp:
+		return item, nil
+	case <-timer.C:
+		return rlp.Item{}, errTimeout
+	case <-p.removed:
+		return rlp.Item{}, errPeerRemoved
+	case <-ctx.Done():
+		return rlp.Item{}, ctx.Err()
+	}
+}
+
+// Assigns an id and a peer that hasn't been tried.
+// Blocks until an untried peer has capacity.
+func (r *Requests) start(ctx context.Context, tried map[[32]byte]bool) (*request, *reqPeer, error) {
+	for {
+		r.mu.Lock()
+		r.init()
+		var (
+			best   *reqPeer
+			bestID [32]byte
+			busy   bool
+		)
+		for id, p := range r.peers {
+			switch {
+			case tried[id]:
+			case p.outstanding >= r.maxOutstanding():
+				busy = true
+			case best == nil || p.outstanding < best.outstanding:
+				best, bestID = p, id
+			}
+		}
+		if best != nil {
+			r.nextID++
+			req := &request{id: r.nextID, peer: bestID, p: best, resp: make(chan rlp.Item, 1)}
+			r.pending[req.id] = req
+			best.outstanding++
+			r.mu.Unlock()
+			return req, best, nil
+		}
+		changed := r.changed
+		r.mu.Unlock()
+		if !busy {
+			return nil, nil, ErrNoPeers
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+func (r *Requests) finish(req *request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if req.done {
+		return
+	}
+	req.done = true
+	delete(r.pending, req.id)
+	req.p.outstanding--
+	r.broadcast()
+}
+
+// Routes a response, [request-id, payload], from peer to
+// the waiting request. Returns an error when the id isn't
+// outstanding for peer (eg it timed out or wasn't requested).
+func (r *Requests) Deliver(peer [32]byte, item rlp.Item) error {
+	if len(item.List()) != 2 {
+		return fmt.Errorf("response must be [request-id, payload]. got %d items", len(item.List()))
+	}
+	id, err := item.At(0).Uint64()
+	if err != nil {
+		return fmt.Errorf("decoding request id: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	req, ok := r.pending[id]
+	if !ok || req.peer != peer {
+		return fmt.Errorf("unsolicited response %d", id)
+	}
+	delete(r.pending, id)
+	req.resp <- item.At(1)
+	return nil
+}
+
+func (r *Requests) maxOutstanding() int {
+	if r.MaxOutstanding <= 0 {
+		return defaultMaxOutstanding
+	}
+	return r.MaxOutstanding
+}
+
+func (r *Requests) timeout() time.Duration {
+	if r.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return r.Timeout
+}
+
+func (r *Requests) retries() int {
+	if r.Retries < 0 {
+		return 0
+	}
+	if r.Retries == 0 {
+		return defaultRetries
+	}
+	return r.Retries
+}
@@ -0,0 +1,189 @@
+package rlpx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/indexsupply/x/rlp"
+	"github.com/indexsupply/x/tc"
+)
+
+// Returns a send func that responds with the
+// request's payload unless respond is false.
+func echo(t *testing.T, r *Requests, id [32]byte, respond bool) func(uint64, []byte) error {
+	return func(code uint64, data []byte) error {
+		item, err := rlp.Decode(data)
+		tc.NoErr(t, err)
+		if respond {
+			go func() { tc.NoErr(t, r.Deliver(id, item)) }()
+		}
+		return nil
+	}
+}
+
+func TestRequests(t *testing.T) {
+	var r Requests
+	r.AddPeer([32]byte{1}, echo(t, &r, [32]byte{1}, true))
+	got, err := r.Do(context.Background(), ethOffset+0x03, rlp.String("hello"))
+	tc.NoErr(t, err)
+	if got.String() != "hello" {
+		t.Errorf("want: hello got: %s", got.String())
+	}
+	if n := r.Outstanding([32]byte{1}); n != 0 {
+		t.Errorf("want 0 outstanding got: %d", n)
+	}
+	if err := r.Deliver([32]byte{1}, rlp.List(rlp.Uint64(1), rlp.String("late"))); err == nil {
+		t.Error("expected error for unsolicited response")
+	}
+}
+
+func TestRequests_Malformed(t *testing.T) {
+	var (
+		r    Requests
+		peer = [32]byte{1}
+		ids  = make(chan uint64, 1)
+	)
+	r.AddPeer(peer, func(code uint64, data []byte) error {
+		item, err := rlp.Decode(data)
+		tc.NoErr(t, err)
+		id, err := item.At(0).Uint64()
+		tc.NoErr(t, err)
+		ids <- id
+		return nil
+	})
+	done := make(chan rlp.Item)
+	go func() {
+		got, err := r.Do(context.Background(), ethOffset+0x03, rlp.String("hello"))
+		tc.NoErr(t, err)
+		done <- got
+	}()
+	id := <-ids
+	for _, item := range []rlp.Item{
+		rlp.List(),
+		rlp.List(rlp.Uint64(id)),
+		rlp.List(rlp.Uint64(id), rlp.String("a"), rlp.String("b")),
+		rlp.String("foo"),
+	} {
+		if err := r.Deliver(peer, item); err == nil {
+			t.Errorf("expected error for %v", item)
+		}
+	}
+	tc.NoErr(t, r.Deliver(peer, rlp.List(rlp.Uint64(id), rlp.String("hello"))))
+	if got := <-done; got.String() != "hello" {
+		t.Errorf("want: hello got: %s", got.String())
+	}
+}
+
+func TestRequests_NoPeers(t *testing.T) {
+	var r Requests
+	if _, err := r.Do(context.Background(), 0, rlp.List()); !errors.Is(err, ErrNoPeers) {
+		t.Errorf("want ErrNoPeers got: %v", err)
+	}
+}
+
+func TestRequests_Retry(t *testing.T) {
+	r := Requests{Timeout: 20 * time.Millisecond, Retries: 1}
+	r.AddPeer([32]byte{1}, echo(t, &r, [32]byte{1}, false))
+	r.AddPeer([32]byte{2}, echo(t, &r, [32]byte{2}, false))
+	_, err := r.Do(context.Background(), 0, rlp.List())
+	if !errors.Is(err, errTimeout) {
+		t.Errorf("want timeout got: %v", err)
+	}
+
+	r.RemovePeer([32]byte{2})
+	r.AddPeer([32]byte{2}, echo(t, &r, [32]byte{2}, true))
+	for i := 0; i < 4; i++ {
+		_, err := r.Do(context.Background(), 0, rlp.List())
+		tc.NoErr(t, err)
+	}
+	for _, id := range [][32]byte{{1}, {2}} {
+		if n := r.Outstanding(id); n != 0 {
+			t.Errorf("%x want 0 outstanding got: %d", id[:1], n)
+		}
+	}
+}
+
+func TestRequests_RemovePeer(t *testing.T) {
+	var (
+		r    = Requests{Retries: -1}
+		sent = make(chan struct{})
+	)
+	r.AddPeer([32]byte{1}, func(uint64, []byte) error {
+		close(sent)
+		return nil
+	})
+	go func() {
+		<-sent
+		r.RemovePeer([32]byte{1})
+	}()
+	if _, err := r.Do(context.Background(), 0, rlp.List()); !errors.Is(err, errPeerRemoved) {
+		t.Errorf("want errPeerRemoved got: %v", err)
+	}
+}
+
+func TestRequests_MaxOutstanding(t *testing.T) {
+	var (
+		r    = Requests{MaxOutstanding: 2}
+		id   = [32]byte{1}
+		mu   sync.Mutex
+		held []rlp.Item
+		max  int
+	)
+	r.AddPeer(id, func(code uint64, data []byte) error {
+		item, err := rlp.Decode(data)
+		tc.NoErr(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		held = append(held, item)
+		if n := r.Outstanding(id); n > max {
+			max = n
+		}
+		return nil
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.Do(context.Background(), 0, rlp.List())
+			tc.NoErr(t, err)
+		}()
+	}
+	// respond to held requests until all are done
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	for {
+		select {
+		case <-done:
+			mu.Lock()
+			defer mu.Unlock()
+			if max > 2 {
+				t.Errorf("want at most 2 outstanding got: %d", max)
+			}
+			return
+		case <-time.After(time.Millisecond):
+		}
+		mu.Lock()
+		for _, item := range held {
+			tc.NoErr(t, r.Deliver(id, item))
+		}
+		held = nil
+		mu.Unlock()
+	}
+}
+
+func TestRequests_Cancel(t *testing.T) {
+	var r Requests
+	r.AddPeer([32]byte{1}, echo(t, &r, [32]byte{1}, false))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.Do(ctx, 0, rlp.List()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want deadline exceeded got: %v", err)
+	}
+	if n := r.Outstanding([32]byte{1}); n != 0 {
+		t.Errorf("want 0 outstanding got: %d", n)
+	}
+}
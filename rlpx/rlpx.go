@@ -7,12 +7,13 @@ import (
 	"crypto/cipher"
 	"crypto/hmac"
 	"crypto/rand"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"hash"
+	"math/big"
 	mrand "math/rand"
 	"net"
+	"os"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/golang/snappy"
@@ -27,12 +28,36 @@ import (
 	"github.com/indexsupply/x/rlp"
 )
 
+// Messages are at most 16MiB
+var limits = rlp.Limits{MaxDepth: 32, MaxPayload: 16 << 20}
+
 type session struct {
 	Verbose bool
+	Capture *Capture // optional. records all messages
+
+	// eth versions advertised in Hello. Defaults to EthVersions.
+	// Eth is the version negotiated when handling the remote's Hello.
+	EthVersions []uint64
+	Eth         uint64
+
+	// optional. receives blocks from NewBlock messages
+	Blocks chan<- BlockObservation
+
+	// The chain used for the local Status and for validating
+	// the remote's Status. Defaults to Mainnet.
+	Chain *Chain
+	// optional. counts rejected remote Status messages
+	StatusCounter *StatusCounter
 
 	conn   net.Conn
 	local  *enr.Record
+	peer   [32]byte // node id of the remote
 	ig, eg *mstate
+
+	// Reused for every message so that a long lived
+	// session doesn't allocate per frame. See: encode
+	// and HandleMessage
+	wbuf, zbuf, rbuf []byte
 }
 
 func (s *session) log(format string, args ...any) {
@@ -47,6 +72,8 @@ func Session(l *enr.Record, hs *handshake) (*session, error) {
 		return nil, isxerrors.Errorf("handshake incomplete: %w", err)
 	}
 	s := &session{local: l}
+	pkb := isxsecp256k1.Encode(hs.remotePubKey)
+	s.peer = isxhash.Keccak32(pkb[:])
 
 	//static-shared-secret = ecdh.agree(privkey, remote-pubk)
 	//ephemeral-key = ecdh.agree(ephemeral-privkey, remote-ephemeral-pubk)
@@ -81,6 +108,12 @@ func Session(l *enr.Record, hs *handshake) (*session, error) {
 		inonce[i] = macSecret[i] ^ hs.initNonce[i]
 		rnonce[i] = macSecret[i] ^ hs.recipientNonce[i]
 	}
+	// The ephemeral key and derived secrets are no longer
+	// needed once the cipher and mac states are initialized.
+	isxsecp256k1.Zero(hs.localEphPrvKey)
+	for _, b := range [][]byte{ephKey, sharedSecret, aesSecret, macSecret} {
+		isxsecp256k1.ZeroBytes(b)
+	}
 
 	if hs.initiator {
 		//egress-mac = keccak256.init((mac-secret ^ recipient-nonce) || auth)
@@ -104,6 +137,10 @@ type mstate struct {
 	block  cipher.Block
 	hash   hash.Hash
 	stream cipher.Stream
+
+	// scratch space for header and frame
+	sum  [32]byte
+	seed [16]byte
 }
 
 func newmstate(aesSecret, macSecret []byte) (*mstate, error) {
@@ -130,15 +167,16 @@ func newmstate(aesSecret, macSecret []byte) (*mstate, error) {
 // header-mac-seed = aes(mac-secret, keccak256.digest(egress-mac)[:16]) ^ header-ciphertext
 // egress-mac = keccak256.update(egress-mac, header-mac-seed)
 // header-mac = keccak256.digest(egress-mac)[:16]
+//
+// The returned mac is valid until the next call to header or frame.
 func (ms *mstate) header(h []byte) []byte {
-	prev := ms.hash.Sum(nil)
-	dest := make([]byte, 16)
-	ms.block.Encrypt(dest, prev[:16])
-	for i := range dest {
-		dest[i] ^= h[i]
-	}
-	ms.hash.Write(dest)
-	return ms.hash.Sum(nil)[:16]
+	prev := ms.hash.Sum(ms.sum[:0])
+	ms.block.Encrypt(ms.seed[:], prev[:16])
+	for i := range ms.seed {
+		ms.seed[i] ^= h[i]
+	}
+	ms.hash.Write(ms.seed[:])
+	return ms.hash.Sum(ms.sum[:0])[:16]
 }
 
 // updates hash using the following devp2p construction:
@@ -147,16 +185,17 @@ func (ms *mstate) header(h []byte) []byte {
 // frame-mac-seed = aes(mac-secret, keccak256.digest(egress-mac)[:16]) ^ keccak256.digest(egress-mac)[:16]
 // egress-mac = keccak256.update(egress-mac, frame-mac-seed)
 // frame-mac = keccak256.digest(egress-mac)[:16]
+//
+// The returned mac is valid until the next call to header or frame.
 func (ms *mstate) frame(fct []byte) []byte {
 	ms.hash.Write(fct)
-	prev := ms.hash.Sum(nil)
-	dest := make([]byte, 16)
-	ms.block.Encrypt(dest, prev[:16])
-	for i := range dest {
-		dest[i] ^= prev[i]
-	}
-	ms.hash.Write(dest)
-	return ms.hash.Sum(nil)[:16]
+	prev := ms.hash.Sum(ms.sum[:0])
+	ms.block.Encrypt(ms.seed[:], prev[:16])
+	for i := range ms.seed {
+		ms.seed[i] ^= prev[i]
+	}
+	ms.hash.Write(ms.seed[:])
+	return ms.hash.Sum(ms.sum[:0])[:16]
 }
 
 func (s *session) decode(buf []byte) ([]byte, error) {
@@ -192,26 +231,35 @@ func (s *session) decode(buf []byte) ([]byte, error) {
 // header-padding = zero-fill header to 16-byte boundary
 // frame-ciphertext = aes(aes-secret, frame-data || frame-padding)
 // frame-padding = zero-fill frame-data to 16-byte boundary
+//
+// The returned frame is reused by the next call to uencode.
 func (s *session) uencode(msgID uint64, msgData []byte) []byte {
 	// Per the spec, the header contains: size, data, and padding.
 	// However, the data (eg [capability-id, context-id]) is unused.
 	// Therefore, we leave the header-data as a list of zero bytes.
-	// Padding is addressed by pre-allocating a 16 byte header.
-	header := make([]byte, 16)
-	bint.Encode(header[:3], uint64(len(msgData)+1)) //include id
+	var (
+		id   = rlp.Uint64(msgID)
+		size = rlp.EncodedLen(id) + len(msgData)
+		pad  = (16 - size%16) % 16
+		n    = 16 + 16 + size + pad + 16
+	)
+	if cap(s.wbuf) < n {
+		s.wbuf = make([]byte, n)
+	}
+	frame := s.wbuf[:n]
+	for i := range frame {
+		frame[i] = 0
+	}
+	header := frame[:16]
+	bint.Encode(header[:3], uint64(size))
 	s.eg.stream.XORKeyStream(header, header)
-	msgData = append(rlp.Encode(rlp.Uint64(msgID)), msgData...)
-	padding := 16 - len(msgData)%16
-	if padding != 0 {
-		msgData = append(msgData, make([]byte, padding)...)
-	}
-	s.eg.stream.XORKeyStream(msgData, msgData)
-
-	var frame []byte
-	frame = append(frame, header...)
-	frame = append(frame, s.eg.header(header)...)
-	frame = append(frame, msgData...)
-	frame = append(frame, s.eg.frame(msgData)...)
+	copy(frame[16:32], s.eg.header(header))
+
+	body := frame[32 : 32+size+pad]
+	rlp.AppendEncode(body[:0], id)
+	copy(body[size-len(msgData):], msgData)
+	s.eg.stream.XORKeyStream(body, body)
+	copy(frame[32+size+pad:], s.eg.frame(body))
 	return frame
 }
 
@@ -220,40 +268,62 @@ func (s *session) uencode(msgID uint64, msgData []byte) []byte {
 //
 // This obscurity is to account for the fact that every message
 // but the Hello message is compressed.
+//
+// The returned frame is reused by the next call to encode.
 func (s *session) encode(msgID uint64, msgData []byte) []byte {
-	return s.uencode(msgID, snappy.Encode(nil, msgData))
+	s.capture(true, msgID, msgData)
+	s.zbuf = snappy.Encode(s.zbuf[:cap(s.zbuf)], msgData)
+	return s.uencode(msgID, s.zbuf)
+}
+
+func (s *session) ethVersions() []uint64 {
+	if len(s.EthVersions) == 0 {
+		return EthVersions
+	}
+	return s.EthVersions
 }
 
 func (s *session) Hello() ([]byte, error) {
-	return s.uencode(0x00, rlp.Encode(rlp.List(
+	caps := []rlp.Item{rlp.List(rlp.String("p2p"), rlp.Int(5))}
+	for _, v := range s.ethVersions() {
+		caps = append(caps, rlp.List(rlp.String("eth"), rlp.Uint64(v)))
+	}
+	hello := rlp.Encode(rlp.List(
 		rlp.Int(5),
 		rlp.String("indexsupply/0"),
-		rlp.List(
-			rlp.List(rlp.String("p2p"), rlp.Int(5)),
-			rlp.List(rlp.String("eth"), rlp.Int(67)),
-		),
+		rlp.List(caps...),
 		rlp.Uint16(s.local.TcpPort),
 		rlp.Secp256k1PublicKey(s.local.PublicKey),
-	))), nil
+	))
+	s.capture(true, 0x00, hello)
+	return s.uencode(0x00, hello), nil
 }
 
-func (s *session) EthStatus() ([]byte, error) {
-	gh, err := hex.DecodeString("d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3")
-	if err != nil {
-		return nil, err
+func (s *session) chain() Chain {
+	if s.Chain == nil {
+		return Mainnet
 	}
-	fh, err := hex.DecodeString("fc64ec04") // fork hash for unsynced mainnet from https://eips.ethereum.org/EIPS/eip-2124
-	if err != nil {
-		return nil, err
-	}
-	return s.encode(0x10, rlp.Encode(rlp.List(
-		rlp.Int(67),          // version
-		rlp.Int(1),           // network ID - 1 for mainnet
-		rlp.Int(17179869184), // Total difficulty of genesis block
-		rlp.Bytes(gh[:]),     // Genesis block hash
-		rlp.Bytes(gh[:]),     // Last known block - genesis block
-		rlp.List(rlp.Bytes(fh), rlp.Int(1150000)), // [fork-hash, fork-next] - unsynced mainnet from https://eips.ethereum.org/EIPS/eip-2124
-	))), nil
+	return *s.Chain
+}
+
+// Encodes a Status message for the negotiated eth version.
+// Must be called after handling the remote's Hello.
+//
+// The session doesn't sync so the head is the genesis block.
+func (s *session) EthStatus() ([]byte, error) {
+	if s.Eth == 0 {
+		return nil, errors.New("eth version not negotiated. handle remote hello first")
+	}
+	c := s.chain()
+	status := Status{
+		Version: s.Eth,
+		Network: c.Network,
+		TD:      big.NewInt(17179869184), // Total difficulty of genesis block
+		Genesis: c.Genesis,
+		Head:    c.Genesis,
+	}
+	status.ForkHash, status.ForkNext = c.ForkID(0, 0)
+	return s.encode(ethOffset, rlp.Encode(status.item())), nil
 }
 
 func (s *session) HandleMessage(d []byte) error {
@@ -261,30 +331,46 @@ func (s *session) HandleMessage(d []byte) error {
 	if err != nil {
 		return isxerrors.Errorf("decoding frame: %w", err)
 	}
-	msgID, err := rlp.Decode(frame[:1])
+	item, err := rlp.Decode(frame[:1])
 	if err != nil {
 		return isxerrors.Errorf("decoding frame msg id: %w", err)
 	}
-	if msgID.Uint64() == 0x00 {
-		item, err := rlp.Decode(frame[1:])
+	msgID, err := item.Uint64()
+	if err != nil {
+		return isxerrors.Errorf("decoding frame msg id: %w", err)
+	}
+	if msgID == 0x00 {
+		s.capture(false, 0x00, frame[1:])
+		item, err := rlp.DecodeWithLimits(frame[1:], limits)
 		if err != nil {
 			return isxerrors.Errorf("decoding hello msg: %w", err)
 		}
 		return s.HandleHello(item)
 	}
-	uframe, err := snappy.Decode(nil, frame[1:])
+	// items decoded from uframe don't reference
+	// it so the buffer is reused for the next message
+	uframe, err := snappy.Decode(s.rbuf[:cap(s.rbuf)], frame[1:])
 	if err != nil {
 		return isxerrors.Errorf("decoding snappy frame: %w", err)
 	}
-	item, err := rlp.Decode(uframe)
+	s.rbuf = uframe
+	s.capture(false, msgID, uframe)
+	item, err = rlp.DecodeWithLimits(uframe, limits)
 	if err != nil {
 		return isxerrors.Errorf("rlp decoding uncompressed frame: %w", err)
 	}
-	switch msgID.Uint64() {
+	if msgID > ethOffset {
+		if _, ok := ethMessage(s.Eth, msgID-ethOffset); !ok {
+			return fmt.Errorf("message %#x is not valid for eth/%d", msgID, s.Eth)
+		}
+	}
+	switch msgID {
 	case 0x01:
 		return s.HandleDisconnect(item)
-	case 0x10:
+	case ethOffset:
 		return s.HandleEthStatus(item)
+	case ethOffset + 0x07:
+		return s.HandleNewBlock(item)
 	}
 	return nil
 }
@@ -296,21 +382,156 @@ func (s *session) HandleHello(item rlp.Item) error {
 	var (
 		id   = item.At(1).String()
 		caps [][]string
+		eth  []uint64
 	)
 	for _, c := range item.At(2).List() {
 		caps = append(caps, []string{c.At(0).String(), c.At(1).String()})
+		if c.At(0).String() != "eth" {
+			continue
+		}
+		v, err := c.At(1).Uint64()
+		if err != nil {
+			return isxerrors.Errorf("decoding eth capability: %w", err)
+		}
+		eth = append(eth, v)
 	}
 	s.log("<hello id=%s caps=%v\n", id, caps)
+	v, ok := negotiate(s.ethVersions(), eth)
+	if !ok {
+		return ErrNoSharedVersion
+	}
+	s.Eth = v
 	return nil
 }
 
+// Reason codes sent in the p2p Disconnect message.
+// See: https://github.com/ethereum/devp2p/blob/master/rlpx.md#disconnect-0x01
+type DisconnectReason uint16
+
+const (
+	DiscRequested DisconnectReason = iota
+	DiscNetworkError
+	DiscProtocolError
+	DiscUselessPeer
+	DiscTooManyPeers
+	DiscAlreadyConnected
+	DiscIncompatibleVersion
+	DiscInvalidIdentity
+	DiscQuitting
+	DiscUnexpectedIdentity
+	DiscSelf
+	DiscReadTimeout
+	DiscSubprotocolError DisconnectReason = 0x10
+)
+
+func (r DisconnectReason) String() string {
+	switch r {
+	case DiscRequested:
+		return "disconnect requested"
+	case DiscNetworkError:
+		return "network error"
+	case DiscProtocolError:
+		return "breach of protocol"
+	case DiscUselessPeer:
+		return "useless peer"
+	case DiscTooManyPeers:
+		return "too many peers"
+	case DiscAlreadyConnected:
+		return "already connected"
+	case DiscIncompatibleVersion:
+		return "incompatible p2p protocol version"
+	case DiscInvalidIdentity:
+		return "invalid node identity"
+	case DiscQuitting:
+		return "client quitting"
+	case DiscUnexpectedIdentity:
+		return "unexpected identity"
+	case DiscSelf:
+		return "connected to self"
+	case DiscReadTimeout:
+		return "read timeout"
+	case DiscSubprotocolError:
+		return "subprotocol error"
+	default:
+		return fmt.Sprintf("unknown reason %d", uint16(r))
+	}
+}
+
+// Returned by [session.HandleMessage] when the remote
+// sends a Disconnect message. Callers can use errors.As
+// to inspect the remote's reason.
+type DisconnectError struct {
+	Reason DisconnectReason
+}
+
+func (e *DisconnectError) Error() string {
+	return "remote disconnected: " + e.Reason.String()
+}
+
+// Encodes a Disconnect message. Callers should send this
+// (or [session.DisconnectFor]) on every teardown path so
+// that the remote can distinguish a polite disconnect
+// from a network failure.
+//
+// disconnect = [reason]
+func (s *session) Disconnect(r DisconnectReason) ([]byte, error) {
+	return s.encode(0x01, rlp.Encode(rlp.List(rlp.Uint16(uint16(r))))), nil
+}
+
+// Encodes a Disconnect message with the reason for
+// ending the session with err. A nil err is a requested
+// disconnect. Returns nil when err is the remote's
+// Disconnect since nothing should be sent in reply.
+func (s *session) DisconnectFor(err error) ([]byte, error) {
+	var (
+		reason DisconnectReason
+		derr   *DisconnectError
+	)
+	switch {
+	case err == nil:
+		reason = DiscRequested
+	case errors.As(err, &derr):
+		return nil, nil
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		reason = DiscReadTimeout
+	case errors.Is(err, ErrNoSharedVersion),
+		errors.Is(err, ErrIncompatibleChain):
+		reason = DiscUselessPeer
+	default:
+		reason = DiscProtocolError
+	}
+	return s.Disconnect(reason)
+}
+
+// Some clients send the reason as a single value
+// instead of a list containing the value.
 func (s *session) HandleDisconnect(item rlp.Item) error {
-	s.log("<disconnect reason=%d\n", item.Uint16())
-	return nil
+	if len(item.List()) > 0 {
+		item = item.At(0)
+	}
+	r, err := item.Uint16()
+	if err != nil {
+		return isxerrors.Errorf("decoding disconnect reason: %w", err)
+	}
+	s.log("<disconnect reason=%d\n", r)
+	return &DisconnectError{Reason: DisconnectReason(r)}
 }
 
 func (s *session) HandleEthStatus(item rlp.Item) error {
-	s.log("<status version=%d network=%d difficulty=%d\n", item.At(0).Uint16(), item.At(1).Uint16(), item.At(2).Uint64())
+	status, err := decodeStatus(item)
+	if err != nil {
+		return isxerrors.Errorf("decoding status: %w", err)
+	}
+	if status.Version != s.Eth {
+		return fmt.Errorf("status version %d does not match negotiated eth/%d", status.Version, s.Eth)
+	}
+	if err := s.chain().Validate(status, 0, 0); err != nil {
+		if s.StatusCounter != nil {
+			s.StatusCounter.add(err)
+		}
+		return isxerrors.Errorf("validating status: %w", err)
+	}
+	s.log("<status version=%d network=%d head=%x\n", status.Version, status.Network, status.Head[:4])
 	return nil
 }
 
@@ -418,7 +639,7 @@ func (h *handshake) HandleAuth(d []byte) error {
 	if err != nil {
 		return isxerrors.Errorf("decrypting auth: %w", err)
 	}
-	authItem, err := rlp.Decode(authBody)
+	authItem, err := rlp.DecodeWithLimits(authBody, limits)
 	if err != nil {
 		return err
 	}
@@ -494,7 +715,7 @@ func (h *handshake) HandleAck(d []byte) error {
 	if err != nil {
 		return isxerrors.Errorf("decrypting ack: %w", err)
 	}
-	ackItem, err := rlp.Decode(ackBody)
+	ackItem, err := rlp.DecodeWithLimits(ackBody, limits)
 	if err != nil {
 		return err
 	}
@@ -1,7 +1,12 @@
 package rlpx
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/netip"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/indexsupply/x/enr"
@@ -54,32 +59,21 @@ func TestHandShake(t *testing.T) {
 }
 
 func TestSession(t *testing.T) {
-	n1 := testNode(t)
-	n2 := testNode(t)
-
-	k1 := n1.PrivateKey
-	k2 := n2.PrivateKey
-
-	h1 := Initiator(k1, k2.PubKey())
-	h2 := Recipient(k2)
-
-	auth, err := h1.Auth()
-	tc.NoErr(t, err)
-	tc.NoErr(t, h2.HandleAuth(auth))
-	ack, err := h2.Ack()
-	tc.NoErr(t, err)
-	tc.NoErr(t, h1.HandleAck(ack))
+	s1, s2 := testSessions(t)
+	hello(t, s1, s2)
 
-	s1, err := Session(n1, h1)
-	tc.NoErr(t, err)
-	s2, err := Session(n2, h2)
+	m2, err := s1.EthStatus()
 	tc.NoErr(t, err)
-
-	m1, _ := s1.Hello()
-	tc.NoErr(t, s2.HandleMessage(m1))
-
-	m2, _ := s1.EthStatus()
 	tc.NoErr(t, s2.HandleMessage(m2))
+
+	m3, _ := s1.Disconnect(DiscTooManyPeers)
+	var derr *DisconnectError
+	if err := s2.HandleMessage(m3); !errors.As(err, &derr) {
+		t.Fatalf("expected disconnect error. got: %v", err)
+	}
+	if derr.Reason != DiscTooManyPeers {
+		t.Errorf("want: %s got: %s", DiscTooManyPeers, derr.Reason)
+	}
 }
 
 func prv(t *testing.T) *secp256k1.PrivateKey {
@@ -88,7 +82,7 @@ func prv(t *testing.T) *secp256k1.PrivateKey {
 	return prv
 }
 
-func testNode(t *testing.T) *enr.Record {
+func testNode(t testing.TB) *enr.Record {
 	prv, err := secp256k1.GeneratePrivateKey()
 	tc.NoErr(t, err)
 	ap := netip.MustParseAddrPort("127.0.0.1:30303")
@@ -100,3 +94,90 @@ func testNode(t *testing.T) *enr.Record {
 		TcpPort:    ap.Port(),
 	}
 }
+
+func TestCapture(t *testing.T) {
+	s1, s2 := testSessions(t)
+	m0, _ := s2.Hello()
+	tc.NoErr(t, s1.HandleMessage(m0))
+
+	dir := t.TempDir()
+	c, err := NewCapture(dir, "test", 1)
+	tc.NoErr(t, err)
+	s2.Capture = c
+
+	m1, _ := s1.Hello()
+	tc.NoErr(t, s2.HandleMessage(m1))
+	m2, err := s1.EthStatus()
+	tc.NoErr(t, err)
+	tc.NoErr(t, s2.HandleMessage(m2))
+	tc.NoErr(t, c.Close())
+
+	for i, id := range []uint64{0x00, 0x10} {
+		f, err := os.Open(filepath.Join(dir, fmt.Sprintf("test.%d.rlpx", i)))
+		tc.NoErr(t, err)
+		defer f.Close()
+		m, err := ReadMessage(f)
+		tc.NoErr(t, err)
+		if m.ID != id || m.Egress {
+			t.Errorf("want ingress message %d got: %d egress: %t", id, m.ID, m.Egress)
+		}
+		if _, err := ReadMessage(f); err != io.EOF {
+			t.Errorf("expected 1 message per file. got: %v", err)
+		}
+	}
+}
+
+func testSessions(t testing.TB) (*session, *session) {
+	n1 := testNode(t)
+	n2 := testNode(t)
+	h1 := Initiator(n1.PrivateKey, n2.PrivateKey.PubKey())
+	h2 := Recipient(n2.PrivateKey)
+
+	auth, err := h1.Auth()
+	tc.NoErr(t, err)
+	tc.NoErr(t, h2.HandleAuth(auth))
+	ack, err := h2.Ack()
+	tc.NoErr(t, err)
+	tc.NoErr(t, h1.HandleAck(ack))
+
+	s1, err := Session(n1, h1)
+	tc.NoErr(t, err)
+	s2, err := Session(n2, h2)
+	tc.NoErr(t, err)
+	return s1, s2
+}
+
+// exchanges hello messages between s1 and s2
+func hello(t *testing.T, s1, s2 *session) {
+	t.Helper()
+	m1, err := s1.Hello()
+	tc.NoErr(t, err)
+	tc.NoErr(t, s2.HandleMessage(m1))
+	m2, err := s2.Hello()
+	tc.NoErr(t, err)
+	tc.NoErr(t, s1.HandleMessage(m2))
+}
+
+func BenchmarkEncode(b *testing.B) {
+	s1, _ := testSessions(b)
+	msg := make([]byte, 1024)
+	b.SetBytes(int64(len(msg)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s1.encode(ethOffset+0x07, msg)
+	}
+}
+
+func BenchmarkRoundTrip(b *testing.B) {
+	s1, s2 := testSessions(b)
+	msg := make([]byte, 1024)
+	b.SetBytes(int64(len(msg)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s2.decode(s1.encode(ethOffset+0x07, msg)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,7 +2,7 @@ package tc
 
 import "testing"
 
-func NoErr(t *testing.T, err error) {
+func NoErr(t testing.TB, err error) {
 	t.Helper()
 	if err != nil {
 		t.Errorf("expected no error. got: %s", err)
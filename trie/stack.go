@@ -0,0 +1,262 @@
+// Merkle Patricia Trie hashing.
+//
+// Implementation based on the Yellow Paper's Appendix D.
+package trie
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/indexsupply/x/isxhash"
+	"github.com/indexsupply/x/rlp"
+)
+
+// keccak(rlp(""))
+var EmptyRoot = isxhash.Keccak32(rlp.Encode(rlp.Bytes(nil)))
+
+type kind byte
+
+const (
+	empty kind = iota
+	leaf
+	ext
+	branch
+	hashed
+)
+
+type node struct {
+	kind kind
+	key  []byte // nibbles
+	val  []byte
+
+	// ext uses children[0]
+	children [16]*node
+
+	// set when kind is hashed. nodes
+	// whose encoding is less than 32 bytes
+	// are embedded in their parent
+	ref rlp.Item
+}
+
+// StackTrie computes the root of a trie whose keys
+// are inserted in increasing order. Subtrees to the left
+// of the inserted key can never change so they are
+// hashed and discarded. Memory is proportional to the
+// depth of the trie rather than the number of keys.
+//
+// The zero value is an empty trie.
+type StackTrie struct {
+	root *node
+	last []byte
+}
+
+var errKeyOrder = errors.New("keys must be inserted in increasing order")
+
+// Inserts key and val. key must be greater than the
+// previously inserted key and val must not be empty.
+func (st *StackTrie) Update(key, val []byte) error {
+	if len(val) == 0 {
+		return errors.New("value must not be empty")
+	}
+	if st.last != nil {
+		switch {
+		case bytes.Compare(key, st.last) <= 0:
+			return errKeyOrder
+		case bytes.HasPrefix(key, st.last):
+			return errors.New("keys must not be prefixes of other keys")
+		}
+	}
+	if len(key) == 0 {
+		return errors.New("key must not be empty")
+	}
+	st.last = append(st.last[:0], key...)
+	if st.root == nil {
+		st.root = &node{}
+	}
+	st.root.insert(nibbles(key), append([]byte(nil), val...))
+	return nil
+}
+
+// Returns the root hash. The trie is
+// reset and ready for reuse.
+func (st *StackTrie) Hash() [32]byte {
+	if st.root == nil {
+		return EmptyRoot
+	}
+	h := isxhash.Keccak32(rlp.Encode(st.root.item()))
+	st.root, st.last = nil, nil
+	return h
+}
+
+// Returns the root of the trie keyed by rlp(i) for
+// each i in [0, n) where value(i) is the i'th element.
+// This is the root used for a block's transactions,
+// receipts and withdrawals.
+func ListRoot(n int, value func(int) []byte) ([32]byte, error) {
+	var st StackTrie
+	// rlp(0) is 0x80 and sorts after rlp(1..127)
+	for i := 1; i < n && i <= 0x7f; i++ {
+		if err := st.Update(rlp.Encode(rlp.Int(i)), value(i)); err != nil {
+			return [32]byte{}, err
+		}
+	}
+	if n > 0 {
+		if err := st.Update(rlp.Encode(rlp.Int(0)), value(0)); err != nil {
+			return [32]byte{}, err
+		}
+	}
+	for i := 0x80; i < n; i++ {
+		if err := st.Update(rlp.Encode(rlp.Int(i)), value(i)); err != nil {
+			return [32]byte{}, err
+		}
+	}
+	return st.Hash(), nil
+}
+
+func nibbles(b []byte) []byte {
+	n := make([]byte, 2*len(b))
+	for i := range b {
+		n[2*i] = b[i] >> 4
+		n[2*i+1] = b[i] & 0x0f
+	}
+	return n
+}
+
+func prefixLen(a, b []byte) int {
+	var i int
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func (n *node) insert(key, val []byte) {
+	switch n.kind {
+	case empty:
+		n.kind, n.key, n.val = leaf, key, val
+	case branch:
+		idx := key[0]
+		// keys are sorted so the
+		// siblings to the left are complete
+		for i := int(idx) - 1; i >= 0; i-- {
+			if c := n.children[i]; c != nil {
+				c.hash()
+				break
+			}
+		}
+		if n.children[idx] == nil {
+			n.children[idx] = &node{kind: leaf, key: key[1:], val: val}
+			return
+		}
+		n.children[idx].insert(key[1:], val)
+	case ext:
+		d := prefixLen(n.key, key)
+		if d == len(n.key) {
+			n.children[0].insert(key[d:], val)
+			return
+		}
+		// the existing child is to the
+		// left of key and is complete
+		orig := n.children[0]
+		if d < len(n.key)-1 {
+			orig = &node{kind: ext, key: n.key[d+1:], children: [16]*node{orig}}
+		}
+		orig.hash()
+		p := n
+		if d == 0 {
+			n.kind = branch
+			n.children[0] = nil
+		} else {
+			p = &node{kind: branch}
+			n.children[0] = p
+		}
+		p.children[n.key[d]] = orig
+		p.children[key[d]] = &node{kind: leaf, key: key[d+1:], val: val}
+		n.key = n.key[:d]
+	case leaf:
+		d := prefixLen(n.key, key)
+		orig := &node{kind: leaf, key: n.key[d+1:], val: n.val}
+		orig.hash()
+		p := n
+		if d == 0 {
+			n.kind = branch
+		} else {
+			p = &node{kind: branch}
+			n.kind = ext
+			n.children[0] = p
+		}
+		p.children[n.key[d]] = orig
+		p.children[key[d]] = &node{kind: leaf, key: key[d+1:], val: val}
+		n.key, n.val = n.key[:d], nil
+	case hashed:
+		panic("trie: insert into hashed node")
+	}
+}
+
+// Replaces n's subtree with its reference
+func (n *node) hash() {
+	if n.kind == hashed {
+		return
+	}
+	it := n.item()
+	if rlp.EncodedLen(it) < 32 {
+		n.ref = it
+	} else {
+		h := it.Keccak32()
+		n.ref = rlp.Bytes(h[:])
+	}
+	n.kind, n.key, n.val = hashed, nil, nil
+	n.children = [16]*node{}
+}
+
+// Returns the node's item as it is
+// referenced by its parent
+func (n *node) refItem() rlp.Item {
+	if n == nil {
+		return rlp.Bytes(nil)
+	}
+	if n.kind != hashed {
+		n.hash()
+	}
+	return n.ref
+}
+
+func (n *node) item() rlp.Item {
+	switch n.kind {
+	case leaf:
+		return rlp.List(rlp.Bytes(hexPrefix(n.key, true)), rlp.Bytes(n.val))
+	case ext:
+		return rlp.List(rlp.Bytes(hexPrefix(n.key, false)), n.children[0].refItem())
+	case branch:
+		items := make([]rlp.Item, 17)
+		for i := range n.children {
+			items[i] = n.children[i].refItem()
+		}
+		items[16] = rlp.Bytes(nil)
+		return rlp.List(items...)
+	case hashed:
+		return n.ref
+	default:
+		return rlp.Bytes(nil)
+	}
+}
+
+// Compact encoding of nibbles. The high nibble of the
+// first byte contains the leaf flag and the parity.
+func hexPrefix(key []byte, isLeaf bool) []byte {
+	var flag byte
+	if isLeaf {
+		flag = 2
+	}
+	b := make([]byte, len(key)/2+1)
+	if len(key)%2 == 1 {
+		b[0] = (flag+1)<<4 | key[0]
+		key = key[1:]
+	} else {
+		b[0] = flag << 4
+	}
+	for i := 0; i < len(key); i += 2 {
+		b[i/2+1] = key[i]<<4 | key[i+1]
+	}
+	return b
+}
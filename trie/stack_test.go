@@ -0,0 +1,95 @@
+package trie
+
+import (
+	"bytes"
+	"crypto/rand"
+	"sort"
+	"testing"
+
+	"github.com/indexsupply/x/rlp"
+	"github.com/indexsupply/x/tc"
+
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+)
+
+func randBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+func gethRoot(t *testing.T, keys, vals [][]byte) [32]byte {
+	tr := gethtrie.NewEmpty(gethtrie.NewDatabase(memorydb.New()))
+	for i := range keys {
+		tc.NoErr(t, tr.TryUpdate(keys[i], vals[i]))
+	}
+	return tr.Hash()
+}
+
+func TestStackTrie(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 16, 17, 100, 1000} {
+		var keys, vals [][]byte
+		for i := 0; i < n; i++ {
+			keys = append(keys, randBytes(32))
+			vals = append(vals, randBytes(1+i%40))
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return bytes.Compare(keys[i], keys[j]) < 0
+		})
+		var st StackTrie
+		for i := range keys {
+			tc.NoErr(t, st.Update(keys[i], vals[i]))
+		}
+		want := gethRoot(t, keys, vals)
+		if got := st.Hash(); got != want {
+			t.Errorf("n=%d want: %x got: %x", n, want, got)
+		}
+	}
+}
+
+func TestStackTrie_Empty(t *testing.T) {
+	var st StackTrie
+	if st.Hash() != gethRoot(t, nil, nil) {
+		t.Errorf("want: %x got: %x", gethRoot(t, nil, nil), st.Hash())
+	}
+}
+
+func TestStackTrie_Errors(t *testing.T) {
+	var st StackTrie
+	tc.NoErr(t, st.Update([]byte{0x10}, []byte{1}))
+	for _, k := range [][]byte{{0x10}, {0x01}, {0x10, 0x01}, {}} {
+		if err := st.Update(k, []byte{1}); err == nil {
+			t.Errorf("expected error for key %x", k)
+		}
+	}
+	if err := st.Update([]byte{0x20}, nil); err == nil {
+		t.Error("expected error for empty value")
+	}
+}
+
+func TestListRoot(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 127, 128, 129, 300, 70000} {
+		var keys, vals [][]byte
+		for i := 0; i < n; i++ {
+			keys = append(keys, rlp.Encode(rlp.Int(i)))
+			vals = append(vals, randBytes(1+i%100))
+		}
+		got, err := ListRoot(n, func(i int) []byte { return vals[i] })
+		tc.NoErr(t, err)
+		if want := gethRoot(t, keys, vals); got != want {
+			t.Errorf("n=%d want: %x got: %x", n, want, got)
+		}
+	}
+}
+
+func BenchmarkListRoot(b *testing.B) {
+	var vals [][]byte
+	for i := 0; i < 500; i++ {
+		vals = append(vals, randBytes(200))
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ListRoot(len(vals), func(i int) []byte { return vals[i] })
+	}
+}